@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"netlimiter/netlimiter"
+)
+
+const (
+	// Names of the allowlist's firewall rules; kept apart from the per-target
+	// GoNetBlock rules so the two never clear each other
+	allowRulePrefix = "GoNetAllow"
+	// Disabled rule whose description records each firewall profile's
+	// default outbound action from before allowlist mode, for restoring it
+	allowMarkerRule = allowRulePrefix + "_marker"
+
+	allowlistPrefKey = "allowlist"
+)
+
+// Build the script that turns on allowlist mode: outbound traffic is blocked
+// by default and allowed for paths (plus DNS, so the allowed apps can resolve
+// names). Windows firewall block rules beat allow rules, so the default is
+// switched through the profiles rather than with a catch-all block rule.
+// The profiles' original actions are saved on the first run only, so
+// reapplying the allowlist doesn't record "Block" as the original.
+func allowlistScript(paths []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `
+if (-not (Get-NetFirewallRule -DisplayName "%[1]s" -ErrorAction SilentlyContinue)) {
+  $prev = (Get-NetFirewallProfile -ErrorAction Stop | ForEach-Object { "$($_.Name)=$($_.DefaultOutboundAction)" }) -join ";"
+  New-NetFirewallRule -DisplayName "%[1]s" -Description $prev -Direction Outbound -Action Allow -Enabled False -ErrorAction Stop | Out-Null
+}
+Get-NetFirewallRule -DisplayName "%[2]s_*" -ErrorAction SilentlyContinue | Where-Object { $_.DisplayName -ne "%[1]s" } | Remove-NetFirewallRule -ErrorAction SilentlyContinue
+New-NetFirewallRule -DisplayName "%[2]s_dns_udp" -Direction Outbound -Protocol UDP -RemotePort 53 -Action Allow -ErrorAction Stop | Out-Null
+New-NetFirewallRule -DisplayName "%[2]s_dns_tcp" -Direction Outbound -Protocol TCP -RemotePort 53 -Action Allow -ErrorAction Stop | Out-Null
+`, allowMarkerRule, allowRulePrefix)
+	for _, p := range paths {
+		fmt.Fprintf(&b, "New-NetFirewallRule -DisplayName \"%s\" -Program %s -Direction Outbound -Action Allow -ErrorAction Stop | Out-Null\n",
+			netlimiter.PolicyName(allowRulePrefix, extraPathPolicyKey(p)), netlimiter.QuotePowerShell(p))
+	}
+	fmt.Fprintf(&b, `Set-NetFirewallProfile -All -DefaultOutboundAction Block -ErrorAction Stop
+Write-Output "Outbound traffic is now blocked except for %d program(s) and DNS"
+`, len(paths))
+	return b.String()
+}
+
+// Script fragment that turns allowlist mode off, restoring the profiles'
+// default outbound actions; part of every full clear
+func allowlistClearScript() string {
+	return fmt.Sprintf(`
+$marker = Get-NetFirewallRule -DisplayName "%s" -ErrorAction SilentlyContinue | Select-Object -First 1
+if ($marker) {
+  foreach ($entry in ($marker.Description -split ";")) {
+    $name, $action = $entry -split "="
+    Set-NetFirewallProfile -Name $name -DefaultOutboundAction $action -ErrorAction SilentlyContinue
+  }
+  Write-Output "Allowlist mode off: default outbound actions restored"
+}
+Get-NetFirewallRule -DisplayName "%s_*" -ErrorAction SilentlyContinue | Remove-NetFirewallRule -ErrorAction SilentlyContinue
+`, allowMarkerRule, allowRulePrefix)
+}
+
+// Block all outbound traffic except for the given executables
+func applyAllowlist(paths []string) *OpResult {
+	res := &OpResult{}
+	res.step("Allowlist mode: blocking outbound traffic for everything except %d program(s)", len(paths))
+	for _, p := range paths {
+		res.step("  allowed: %s", p)
+	}
+	if !firewallCmdletsAvailable() {
+		res.Err = fmt.Errorf("allowlist mode needs the NetSecurity firewall cmdlets: %w", errCmdletMissing)
+		return res
+	}
+	res.Script = allowlistScript(paths)
+	if res.run("Firewall output", "allowlist error").Err == nil {
+		res.succeed("ApplyAllowlist: success")
+	}
+	return res
+}
+
+// Executable paths for the allowlist entries: full .exe paths as given,
+// process names through their running instances
+func resolveAllowlist(entries []string, src ProcessSource) ([]string, error) {
+	var paths []string
+	add := func(p string) {
+		for _, have := range paths {
+			if strings.EqualFold(have, p) {
+				return
+			}
+		}
+		paths = append(paths, p)
+	}
+	for _, e := range entries {
+		if strings.ContainsAny(e, `\/`) {
+			p, err := resolveExePath(e)
+			if err != nil {
+				return nil, err
+			}
+			add(p)
+			continue
+		}
+		pids, err := src.FindPIDsByName(e)
+		if err != nil {
+			return nil, fmt.Errorf("error finding process: %w", err)
+		}
+		if len(pids) == 0 {
+			return nil, fmt.Errorf("%s is not running; start it or enter its full path: %w", e, errProcessNotFound)
+		}
+		instances := make([]processInstance, len(pids))
+		for i, pid := range pids {
+			instances[i].PID = pid
+		}
+		found, err := distinctExePaths(src, instances)
+		if err != nil {
+			return nil, fmt.Errorf("could not get executable path of %s: %w", e, err)
+		}
+		for _, p := range found {
+			add(p)
+		}
+	}
+	return paths, nil
+}
+
+// Non-empty lines of the allowlist entry
+func allowlistEntries(text string) []string {
+	var entries []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries
+}
+
+// Advanced panel for allowlist mode. Clear All turns it off again.
+func allowlistPanel(window fyne.Window, prefs fyne.Preferences, appendLog func(string)) fyne.CanvasObject {
+	entry := widget.NewMultiLineEntry()
+	entry.SetPlaceHolder("One process name or .exe path per line, e.g.\nchrome.exe\nC:\\Program Files\\Zoom\\bin\\Zoom.exe")
+	entry.SetMinRowsVisible(4)
+	entry.SetText(prefs.String(allowlistPrefKey))
+
+	warning := widget.NewLabel("WARNING: blocks ALL outbound traffic except these programs and DNS, including Windows Update, " +
+		"system services and remote-desktop sessions. Clear All turns it off and restores the firewall defaults.")
+	warning.Wrapping = fyne.TextWrapWord
+	warning.Importance = widget.DangerImportance
+
+	apply := widget.NewButton("Block Everything Else", func() {
+		entries := allowlistEntries(entry.Text)
+		if len(entries) == 0 {
+			appendLog("Error: list at least one program to keep online")
+			return
+		}
+		prefs.SetString(allowlistPrefKey, strings.Join(entries, "\n"))
+		msg := fmt.Sprintf("Block outbound internet for every program except these %d?\n\n%s\n\n"+
+			"This can cut off the machine's own connectivity, including remote access to it.",
+			len(entries), strings.Join(entries, "\n"))
+		dialog.ShowConfirm("Allowlist mode", msg, func(ok bool) {
+			if !ok {
+				return
+			}
+			go func() {
+				appendLog("----------------------------------------------------")
+				paths, err := resolveAllowlist(entries, processes)
+				if err != nil {
+					appendLog("Allowlist error: " + err.Error())
+					return
+				}
+				log, err := backend.ApplyAllowlist(paths).result()
+				appendLog(log)
+				auditOrLog(appendLog, "allowlist", "", strings.Join(paths, ";"), map[string]any{"programs": len(paths)}, err)
+				if err != nil {
+					appendLog("Allowlist error: " + err.Error())
+					if hint := remediation(err); hint != "" {
+						appendLog(hint)
+					}
+				}
+			}()
+		}, window)
+	})
+	apply.Importance = widget.DangerImportance
+
+	return container.NewVBox(warning, entry, apply)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const auditFileName = "audit.jsonl"
+
+// One line of the audit log.
+// Hash covers PrevHash plus every other field, chaining entries so edits or
+// deletions in the middle of the file are detectable.
+type auditEntry struct {
+	Time     time.Time      `json:"time"`
+	User     string         `json:"user"`
+	Action   string         `json:"action"`
+	Process  string         `json:"process,omitempty"`
+	Target   string         `json:"target,omitempty"`
+	Params   map[string]any `json:"params,omitempty"`
+	Result   string         `json:"result"`
+	Error    string         `json:"error,omitempty"`
+	PrevHash string         `json:"prevHash"`
+	Hash     string         `json:"hash"`
+}
+
+// Append-only audit log, separate from the GUI log; never truncated by the app
+type auditLog struct {
+	mu       sync.Mutex
+	path     string
+	lastHash string
+	loaded   bool
+}
+
+var audit = &auditLog{}
+
+// Path of the audit log file, creating its directory if needed
+func (a *auditLog) filePath() (string, error) {
+	if a.path != "" {
+		return a.path, nil
+	}
+	dir, err := appDataDir()
+	if err != nil {
+		return "", err
+	}
+	a.path = filepath.Join(dir, auditFileName)
+	return a.path, nil
+}
+
+// Read the hash of the last entry so new entries continue the chain
+func (a *auditLog) loadLastHash(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e auditEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil && e.Hash != "" {
+			a.lastHash = e.Hash
+		}
+	}
+	return scanner.Err()
+}
+
+// Compute the chained hash of an entry (with its Hash field empty)
+func auditEntryHash(e auditEntry) string {
+	e.Hash = ""
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Append one operation to the audit log
+func (a *auditLog) record(action, process, target string, params map[string]any, opErr error) error {
+	// A dry run changes nothing, so there is nothing to audit
+	if dryRun.Load() {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	path, err := a.filePath()
+	if err != nil {
+		return err
+	}
+	if !a.loaded {
+		if err := a.loadLastHash(path); err != nil {
+			return fmt.Errorf("audit log read error: %w", err)
+		}
+		a.loaded = true
+	}
+
+	e := auditEntry{
+		Time:     time.Now().UTC(),
+		User:     currentUserName(),
+		Action:   action,
+		Process:  process,
+		Target:   target,
+		Params:   params,
+		Result:   "success",
+		PrevHash: a.lastHash,
+	}
+	if opErr != nil {
+		e.Result = "error"
+		e.Error = opErr.Error()
+	}
+	e.Hash = auditEntryHash(e)
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("audit log open error: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("audit log write error: %w", err)
+	}
+	a.lastHash = e.Hash
+	return nil
+}
+
+// Record an operation, reporting audit log failures through log, and fire
+// any configured hooks for it
+func auditOrLog(log func(string), action, process, target string, params map[string]any, opErr error) {
+	if err := audit.record(action, process, target, params, opErr); err != nil {
+		log("Audit log error: " + err.Error())
+	}
+	hooks.fire(log, action, process, target, params, opErr)
+}
+
+// Copy the audit log to w (used by the export button)
+func (a *auditLog) export(w io.Writer) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	path, err := a.filePath()
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Name of the user running the app, as DOMAIN\user on Windows
+func currentUserName() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USERNAME")
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"netlimiter/netlimiter"
+)
+
+// Script clearing the given policy keys, or everything this tool created
+func clearKeysScript(keys []string, clearAll bool) string {
+	netsh := !firewallCmdletsAvailable()
+	switch {
+	case clearAll && netsh:
+		return netshClearScript()
+	case clearAll:
+		return clearScript()
+	}
+	var b strings.Builder
+	for _, key := range keys {
+		if netsh {
+			b.WriteString(netshClearTargetScript(key))
+		} else {
+			b.WriteString(netlimiter.ClearTargetScript(key))
+		}
+	}
+	return b.String()
+}
+
+// Clear the given policy keys, or everything, one PowerShell run per step
+func clearKeys(keys []string, clearAll bool) *OpResult {
+	if clearAll {
+		return clearAllLimits()
+	}
+	res := &OpResult{}
+	var errs []error
+	for _, key := range keys {
+		cleared := clearLimitsForTarget(key)
+		res.merge(cleared)
+		if cleared.Err != nil {
+			errs = append(errs, cleared.Err)
+		}
+	}
+	res.Err = errors.Join(errs...)
+	return res
+}
+
+// Clear the given policy keys (or everything when clearAll) and apply r in
+// one PowerShell run, which saves starting PowerShell twice on every Apply.
+// The clear steps ignore missing policies, so a failed run is an apply error.
+// Package rules resolve their executable and multi-path rules report each
+// path, so those still clear and apply in separate runs. res.Err is the
+// apply error.
+func applyReplacing(keys []string, clearAll bool, r LimitRule) (res *OpResult, clearErr error) {
+	targets := r.exeTargets()
+	if r.Package != "" || len(targets) > 1 {
+		cleared := clearKeys(keys, clearAll)
+		applied := applyRule(r)
+		res = (&OpResult{}).merge(cleared).merge(applied)
+		res.Err = applied.Err
+		return res, cleared.Err
+	}
+
+	t := targets[0]
+	var plan *OpResult
+	switch {
+	case r.Blocked:
+		plan = blockPlan(t.Key, t.Path, r.scope())
+	case r.Priority.active():
+		plan = priorityPlan(t.Key, t.Path, r.Priority, r.InKbps, r.OutKbps)
+	default:
+		plan = qosLimitPlan(t.Key, t.Path, r.InKbps, r.OutKbps)
+	}
+	if plan.Err != nil {
+		// Still clear what the rule replaces, as a separate apply would have
+		cleared := clearKeys(keys, clearAll)
+		res = (&OpResult{}).merge(cleared)
+		if note := nameOnlyNote(r); note != "" {
+			res.warn("%s", note)
+		}
+		res.merge(plan).Err = plan.Err
+		return res, cleared.Err
+	}
+
+	scope := fmt.Sprintf("%d target(s)", len(keys))
+	if clearAll {
+		scope = "all rules"
+	}
+	res = &OpResult{Script: clearKeysScript(keys, clearAll) + plan.Script}
+	if note := nameOnlyNote(r); note != "" {
+		res.warn("%s", note)
+	}
+	res.step("Clearing %s and applying in one PowerShell run", scope)
+	res.Steps = append(res.Steps, plan.Steps...)
+	if res.run("Output", "apply error").Err == nil {
+		res.succeed("ApplyReplacing: success")
+	}
+	return res, nil
+}
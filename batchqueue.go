@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Executables waiting to get the Limit tab's settings in one go, e.g. the
+// extra files of a multi-file drop. Only use it from the UI goroutine (fyne.Do).
+type batchQueue struct {
+	paths       []string
+	list        *fyne.Container
+	applyButton *widget.Button
+}
+
+// paths added to queue, skipping any already in it (compared case-insensitively)
+func queueExePaths(queue, paths []string) (updated, added []string) {
+	for _, p := range paths {
+		same := func(q string) bool { return strings.EqualFold(q, p) }
+		if !slices.ContainsFunc(queue, same) && !slices.ContainsFunc(added, same) {
+			added = append(added, p)
+		}
+	}
+	return append(queue, added...), added
+}
+
+// queue without the given paths
+func unqueueExePaths(queue, paths []string) []string {
+	return slices.DeleteFunc(slices.Clone(queue), func(q string) bool {
+		return slices.ContainsFunc(paths, func(p string) bool { return strings.EqualFold(p, q) })
+	})
+}
+
+// Queue paths, returning the ones that weren't queued yet
+func (q *batchQueue) add(paths []string) []string {
+	var added []string
+	q.paths, added = queueExePaths(q.paths, paths)
+	q.refresh()
+	return added
+}
+
+func (q *batchQueue) remove(paths []string) {
+	q.paths = unqueueExePaths(q.paths, paths)
+	q.refresh()
+}
+
+func (q *batchQueue) refresh() {
+	q.list.RemoveAll()
+	if len(q.paths) == 0 {
+		q.list.Add(widget.NewLabel("No queued executables. Drop several .exe files on the window to queue all but the first."))
+	}
+	for _, p := range q.paths {
+		q.list.Add(container.NewBorder(nil, nil, nil, widget.NewButton("Remove", func() { q.remove([]string{p}) }), widget.NewLabel(p)))
+	}
+	q.applyButton.SetText(fmt.Sprintf("Apply Limit / Block to %d Queued", len(q.paths)))
+}
+
+// Queue panel; apply gets a copy of the queued paths off the UI thread and
+// returns the ones it handled, which leave the queue
+func newBatchQueue(apply func(paths []string) []string) (*batchQueue, fyne.CanvasObject) {
+	q := &batchQueue{list: container.NewVBox()}
+	q.applyButton = widget.NewButton("", func() {
+		if len(q.paths) == 0 {
+			return
+		}
+		paths := slices.Clone(q.paths)
+		go func() {
+			handled := apply(paths)
+			fyne.Do(func() { q.remove(handled) })
+		}()
+	})
+	clearButton := widget.NewButton("Clear Queue", func() { q.remove(q.paths) })
+	q.refresh()
+	top := container.NewHBox(q.applyButton, clearButton)
+	return q, container.NewBorder(top, nil, nil, nil, container.NewVScroll(q.list))
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestQueueExePaths(t *testing.T) {
+	queue := []string{`C:\Games\game.exe`}
+	queue, added := queueExePaths(queue, []string{`C:\Tools\a.exe`, `c:\games\GAME.exe`, `C:\Tools\b.exe`, `C:\TOOLS\A.EXE`})
+	if want := []string{`C:\Tools\a.exe`, `C:\Tools\b.exe`}; !slices.Equal(added, want) {
+		t.Errorf("added = %q, want %q", added, want)
+	}
+	if want := []string{`C:\Games\game.exe`, `C:\Tools\a.exe`, `C:\Tools\b.exe`}; !slices.Equal(queue, want) {
+		t.Errorf("queue = %q, want %q", queue, want)
+	}
+
+	rest := unqueueExePaths(queue, []string{`c:\tools\A.exe`, `C:\Other\x.exe`})
+	if want := []string{`C:\Games\game.exe`, `C:\Tools\b.exe`}; !slices.Equal(rest, want) {
+		t.Errorf("after removing = %q, want %q", rest, want)
+	}
+	if len(queue) != 3 {
+		t.Errorf("unqueueExePaths changed its input: %q", queue)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"netlimiter/netlimiter"
+)
+
+// Protocols offered for scoped blocks; "Any" leaves the rule's protocol unset
+var blockProtocols = []string{"Any", "TCP", "UDP", "ICMPv4", "ICMPv6"}
+
+// Shorthands for every address of one IP version, as the firewall ranges they stand for
+var remoteAddressFamilies = map[string]string{
+	"ipv4": "0.0.0.0-255.255.255.255",
+	"ipv6": "::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff",
+}
+
+// Narrows a block to some remote addresses and/or one protocol; the zero
+// value blocks all of an executable's traffic
+type blockScope struct {
+	RemoteAddress string // comma-separated IPs, CIDR subnets, or ipv4/ipv6
+	Protocol      string // one of blockProtocols; "" or "Any" for all
+}
+
+// Whether the scope narrows the block at all
+func (s blockScope) scoped() bool {
+	return s.RemoteAddress != "" || (s.Protocol != "" && s.Protocol != "Any")
+}
+
+// Remote addresses as the firewall takes them, validated
+func (s blockScope) remoteAddresses() ([]string, error) {
+	if s.RemoteAddress == "" {
+		return nil, nil
+	}
+	var addrs []string
+	for _, a := range strings.Split(s.RemoteAddress, ",") {
+		a = strings.TrimSpace(a)
+		if family, ok := remoteAddressFamilies[strings.ToLower(a)]; ok {
+			addrs = append(addrs, family)
+			continue
+		}
+		if _, err := netip.ParseAddr(a); err == nil {
+			addrs = append(addrs, a)
+			continue
+		}
+		p, err := netip.ParsePrefix(a)
+		if err != nil {
+			return nil, fmt.Errorf("remote address %q is not an IP address, CIDR subnet, ipv4 or ipv6", a)
+		}
+		addrs = append(addrs, p.Masked().String())
+	}
+	return addrs, nil
+}
+
+// Check the scope before any script is built from it
+func (s blockScope) validate() error {
+	if s.Protocol != "" {
+		known := false
+		for _, p := range blockProtocols {
+			known = known || strings.EqualFold(p, s.Protocol)
+		}
+		if !known {
+			return fmt.Errorf("protocol %q is not one of %s", s.Protocol, strings.Join(blockProtocols, ", "))
+		}
+	}
+	_, err := s.remoteAddresses()
+	return err
+}
+
+// Extra New-NetFirewallRule parameters for the scope
+func (s blockScope) firewallArgs() string {
+	var args string
+	if addrs, _ := s.remoteAddresses(); len(addrs) > 0 {
+		quoted := make([]string, len(addrs))
+		for i, a := range addrs {
+			quoted[i] = netlimiter.QuotePowerShell(a)
+		}
+		args += " -RemoteAddress " + strings.Join(quoted, ",")
+	}
+	if s.Protocol != "" && s.Protocol != "Any" {
+		args += " -Protocol " + s.Protocol
+	}
+	return args
+}
+
+// Extra "netsh advfirewall firewall add rule" arguments for the scope
+func (s blockScope) netshArgs() string {
+	var args string
+	if addrs, _ := s.remoteAddresses(); len(addrs) > 0 {
+		args += " remoteip=" + strings.Join(addrs, ",")
+	}
+	if s.Protocol != "" && s.Protocol != "Any" {
+		args += " protocol=" + strings.ToLower(s.Protocol)
+	}
+	return args
+}
+
+// Suffix for rule descriptions, e.g. " to 10.0.0.0/8 (TCP)"
+func (s blockScope) describe() string {
+	var desc string
+	if s.RemoteAddress != "" {
+		desc += " to " + s.RemoteAddress
+	}
+	if s.Protocol != "" && s.Protocol != "Any" {
+		desc += " (" + s.Protocol + ")"
+	}
+	return desc
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// How often boosts are checked for expiry
+const boostCheckInterval = 5 * time.Second
+
+// Boost lengths offered for a rule
+var boostDurations = []time.Duration{5 * time.Minute, 15 * time.Minute, 30 * time.Minute, time.Hour}
+
+// Lift a rule's limit until d from now. The rule stays tracked with its
+// original values and is reapplied when the boost ends.
+func startBoost(r LimitRule, d time.Duration, log func(string)) error {
+	clearLog, err := clearRule(r).result()
+	log(fmt.Sprintf("Boosting %s for %v", r.describe(), d))
+	log(clearLog)
+	auditOrLog(log, "boost", r.Process, r.ExePath, map[string]any{"minutes": int(d / time.Minute)}, err)
+	if err != nil {
+		return err
+	}
+	r.Status, r.LastError = statusBoosted, ""
+	r.BoostUntil = time.Now().Add(d)
+	return tracked.put(r)
+}
+
+// Reapply a boosted rule's original limit
+func endBoost(r LimitRule, reason string, log func(string)) {
+	applyLog, err := applyRule(r).result()
+	log("Boost " + reason + ": restoring " + r.describe())
+	log(applyLog)
+	if err != nil {
+		log("Apply error: " + err.Error())
+	}
+	if saveErr := tracked.recordResult(r, err); saveErr != nil {
+		log("Could not save tracked rules: " + saveErr.Error())
+	}
+	auditOrLog(log, "limit", r.Process, r.ExePath, map[string]any{"trigger": "boost " + reason}, err)
+}
+
+// Countdown shown in the rules table, e.g. "until 15:04 (12m left)"
+func boostRemaining(r LimitRule, now time.Time) string {
+	left := r.BoostUntil.Sub(now).Round(time.Minute)
+	if left < time.Minute {
+		return "until " + r.BoostUntil.Format("15:04") + " (<1m left)"
+	}
+	return fmt.Sprintf("until %s (%dm left)", r.BoostUntil.Format("15:04"), int(left/time.Minute))
+}
+
+// Restore boosts left over from a previous run. Nothing lifted the boost
+// while the app was closed, so they end now rather than running on.
+func restoreBoostsAtLaunch(log func(string)) {
+	for _, r := range tracked.list() {
+		if r.status() == statusBoosted {
+			endBoost(r, "interrupted by restart", log)
+		}
+	}
+}
+
+// End boosts as they expire, calling onTick after each check for the countdown
+func watchBoosts(log func(string), onTick func()) {
+	ticker := time.NewTicker(boostCheckInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		boosting := false
+		for _, r := range tracked.list() {
+			if r.status() != statusBoosted {
+				continue
+			}
+			boosting = true
+			if !now.Before(r.BoostUntil) {
+				endBoost(r, "expired", log)
+			}
+		}
+		if boosting {
+			onTick()
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+
+	"netlimiter/netlimiter"
+)
+
+const (
+	// How often the group's usage is measured and rates are redistributed.
+	// QoS changes take a moment to bite, so shorter intervals only add churn.
+	budgetInterval = 10 * time.Second
+
+	// Floor per member so an idle app can still start talking
+	minBudgetShareKbps = 64
+
+	// A member using this fraction of its current rate is treated as wanting more
+	budgetSaturation = 0.9
+)
+
+// Total rate shared by a group of processes, e.g. three apps together at most 2000 kbps.
+//
+// QoS can only cap processes individually, so the budget is approximated:
+// every budgetInterval each member's throughput is measured from its I/O
+// counters and the total is redistributed max-min fairly (light users keep
+// what they use plus headroom, the rest is split among the busy ones). The
+// group can briefly exceed the budget while a member ramps up, until the next
+// redistribution. I/O counters include disk traffic, which makes members
+// look busier than they are.
+type SharedBudget struct {
+	Members   []string // process names
+	TotalKbps int
+}
+
+// Running shared budget; Stop removes its policies
+type budgetRunner struct {
+	budget SharedBudget
+	stop   chan struct{}
+	done   sync.WaitGroup
+	// Policies applied so far, by key, with the member and path each covers;
+	// only touched by run, and by Stop once run has returned
+	policies map[string]budgetPolicy
+}
+
+// Member policy a budget applied
+type budgetPolicy struct {
+	Process, ExePath string
+}
+
+// Policy key for a budget member. Budgets have their own keys, so they never
+// overwrite a tracked rule's policy or clear it on Stop.
+func budgetPolicyKey(exePath string) string {
+	return "budget:" + netlimiter.ExePolicyKey(exePath)
+}
+
+// Check a budget before starting it
+func (b SharedBudget) validate() error {
+	if len(b.Members) < 2 {
+		return errors.New("a shared budget needs at least two processes")
+	}
+	if b.TotalKbps < len(b.Members)*minBudgetShareKbps {
+		return fmt.Errorf("budget must be at least %d kbps for %d processes", len(b.Members)*minBudgetShareKbps, len(b.Members))
+	}
+	return nil
+}
+
+// Split budgetKbps over members with the given demands (kbps), max-min fairly:
+// members are served smallest demand first, each getting its demand plus 25%
+// headroom but never more than an equal share of what's left. Allocations
+// never sum past the budget.
+func allocateBudget(budgetKbps int, demands []int) []int {
+	n := len(demands)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return demands[order[a]] < demands[order[b]] })
+
+	alloc := make([]int, n)
+	remaining := budgetKbps
+	for k, i := range order {
+		share := remaining / (n - k)
+		a := min(demands[i]+demands[i]/4, share)
+		a = max(a, min(minBudgetShareKbps, share))
+		alloc[i] = a
+		remaining -= a
+	}
+	return alloc
+}
+
+// Start measuring the group and adjusting its members' QoS rates. Members
+// with a tracked rule are refused: two policies on one executable would
+// leave it unclear which rate applies.
+func startSharedBudget(b SharedBudget, log func(string)) (*budgetRunner, error) {
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+	for _, name := range b.Members {
+		if rule, ok := tracked.get(strings.ToLower(name)); ok {
+			return nil, fmt.Errorf("%s already has a tracked rule (%s); clear it before adding it to a shared budget", name, rule.describe())
+		}
+	}
+	r := &budgetRunner{budget: b, stop: make(chan struct{}), policies: make(map[string]budgetPolicy)}
+	r.done.Add(1)
+	go r.run(log)
+	return r, nil
+}
+
+// Stop adjusting and remove exactly the policies the budget applied
+func (r *budgetRunner) Stop(log func(string)) {
+	close(r.stop)
+	r.done.Wait()
+	for key, p := range r.policies {
+		clearLog, err := backend.ClearTarget(key).result()
+		log(clearLog)
+		if err != nil {
+			log("Clear error: " + err.Error())
+		}
+		auditOrLog(log, "clear", p.Process, p.ExePath, map[string]any{"trigger": "shared budget"}, err)
+	}
+}
+
+func (r *budgetRunner) run(log func(string)) {
+	defer r.done.Done()
+
+	n := len(r.budget.Members)
+	applied := make([]int, n) // kbps currently applied per member, 0 if none
+	initial := make([]int, n)
+	lastIO := make([]map[int32]uint64, n)
+	for i := range initial {
+		initial[i] = r.budget.TotalKbps / n
+		lastIO[i] = make(map[int32]uint64)
+	}
+	r.apply(initial, applied, log)
+
+	ticker := time.NewTicker(budgetInterval)
+	defer ticker.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case now := <-ticker.C:
+			elapsed := now.Sub(last).Seconds()
+			last = now
+
+			procCache.invalidate()
+			demands := make([]int, n)
+			for i, name := range r.budget.Members {
+				used := r.measure(name, lastIO[i])
+				kbps := int(float64(used) * 8 / 1000 / elapsed)
+				demands[i] = kbps
+				if applied[i] > 0 && float64(kbps) >= budgetSaturation*float64(applied[i]) {
+					demands[i] = r.budget.TotalKbps
+				}
+			}
+			r.apply(allocateBudget(r.budget.TotalKbps, demands), applied, log)
+		}
+	}
+}
+
+// Bytes a member moved since the previous sample; updates last in place
+func (r *budgetRunner) measure(name string, last map[int32]uint64) uint64 {
+	pids, err := processes.FindPIDsByName(name)
+	if err != nil {
+		return 0
+	}
+	var used uint64
+	seen := make(map[int32]bool, len(pids))
+	for _, pid := range pids {
+		total, err := processes.IOBytes(pid)
+		if err != nil {
+			continue
+		}
+		seen[pid] = true
+		if prev, ok := last[pid]; ok && total >= prev {
+			used += total - prev
+		}
+		last[pid] = total
+	}
+	for pid := range last {
+		if !seen[pid] {
+			delete(last, pid)
+		}
+	}
+	return used
+}
+
+// Apply rates that moved by more than 10% (or were never applied), recording
+// them in applied; members that aren't running are skipped until they start
+func (r *budgetRunner) apply(rates, applied []int, log func(string)) {
+	var changes []string
+	for i, name := range r.budget.Members {
+		if applied[i] > 0 && abs(rates[i]-applied[i])*10 <= applied[i] {
+			continue
+		}
+		pids, err := processes.FindPIDsByName(name)
+		if err != nil || len(pids) == 0 {
+			continue
+		}
+		exePath, err := processes.ExePath(pids[0])
+		if err != nil {
+			continue
+		}
+		key := budgetPolicyKey(exePath)
+		res := backend.ApplyLimit(key, exePath, 0, rates[i]) // QoS only throttles outbound
+		// Recorded even on failure, as the backend may have left part of it behind
+		r.policies[key] = budgetPolicy{Process: name, ExePath: exePath}
+		auditOrLog(log, "limit", name, exePath, map[string]any{"trigger": "shared budget", "outKbps": rates[i], "totalKbps": r.budget.TotalKbps}, res.Err)
+		if res.Err != nil {
+			log(fmt.Sprintf("Shared budget: could not set %s to %d kbps: %v", name, rates[i], res.Err))
+			continue
+		}
+		applied[i] = rates[i]
+		changes = append(changes, fmt.Sprintf("%s %d", name, rates[i]))
+	}
+	if len(changes) > 0 {
+		log("Shared budget: " + strings.Join(changes, ", ") + " kbps")
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Advanced panel to run a shared budget over several processes
+func budgetPanel(appendLog func(string)) fyne.CanvasObject {
+	membersEntry := widget.NewEntry()
+	membersEntry.SetPlaceHolder("chrome.exe, steam.exe, onedrive.exe")
+	totalEntry := widget.NewEntry()
+	totalEntry.SetPlaceHolder("2000")
+
+	var running *budgetRunner
+	var toggle *widget.Button
+	toggle = widget.NewButton("Start Budget", func() {
+		if running != nil {
+			r := running
+			running = nil
+			toggle.SetText("Start Budget")
+			go func() {
+				r.Stop(appendLog)
+				appendLog("Shared budget stopped")
+			}()
+			return
+		}
+
+		var members []string
+		for _, m := range strings.Split(membersEntry.Text, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				members = append(members, m)
+			}
+		}
+		total, err := parseLimit(totalEntry.Text, unitKbps)
+		if err != nil {
+			appendLog("Error: budget total: " + err.Error())
+			return
+		}
+		b := SharedBudget{Members: members, TotalKbps: total}
+		r, err := startSharedBudget(b, appendLog)
+		if err != nil {
+			appendLog("Shared budget error: " + err.Error())
+			return
+		}
+		running = r
+		toggle.SetText("Stop Budget")
+		appendLog(fmt.Sprintf("Shared budget: %s share %d kbps, rebalanced every %v", strings.Join(members, ", "), total, budgetInterval))
+	})
+
+	return widget.NewForm(
+		widget.NewFormItem("Processes", membersEntry),
+		widget.NewFormItem("Total (kbps)", totalEntry),
+		widget.NewFormItem("", toggle),
+	)
+}
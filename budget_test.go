@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Backend recording the policy keys it applies and clears
+type keyRecordingLimiter struct {
+	Limiter
+	applied, cleared []string
+}
+
+func (l *keyRecordingLimiter) ApplyLimit(key, exePath string, inKbps, outKbps int) *OpResult {
+	l.applied = append(l.applied, key)
+	return &OpResult{}
+}
+
+func (l *keyRecordingLimiter) ClearTarget(key string) *OpResult {
+	l.cleared = append(l.cleared, key)
+	return &OpResult{}
+}
+
+// Processes with one PID each, by lower-case name
+type fixedProcesses struct {
+	ProcessSource
+	exes map[string]string
+}
+
+func (p fixedProcesses) FindPIDsByName(name string) ([]int32, error) {
+	if _, ok := p.exes[strings.ToLower(name)]; ok {
+		return []int32{int32(len(name))}, nil
+	}
+	return nil, nil
+}
+
+func (p fixedProcesses) ExePath(pid int32) (string, error) {
+	for name, exe := range p.exes {
+		if int32(len(name)) == pid {
+			return exe, nil
+		}
+	}
+	return "", errProcessNotFound
+}
+
+// Swap the backend, process source, tracked rules and audit log for test doubles
+func recordingTestEnv(t *testing.T) (*keyRecordingLimiter, string) {
+	t.Helper()
+	l := &keyRecordingLimiter{}
+	auditPath := filepath.Join(t.TempDir(), auditFileName)
+	savedBackend, savedProcs, savedTracked, savedAudit := backend, processes, tracked, audit
+	backend = l
+	processes = fixedProcesses{exes: map[string]string{
+		"chrome.exe": `C:\Program Files\Google\Chrome\Application\chrome.exe`,
+		"steam.exe":  `C:\Program Files (x86)\Steam\steam.exe`,
+	}}
+	tracked = &ruleStore{}
+	audit = &auditLog{path: auditPath}
+	t.Cleanup(func() { backend, processes, tracked, audit = savedBackend, savedProcs, savedTracked, savedAudit })
+	return l, auditPath
+}
+
+func TestBudgetStopClearsExactlyAppliedKeys(t *testing.T) {
+	l, auditPath := recordingTestEnv(t)
+	r := &budgetRunner{
+		budget:   SharedBudget{Members: []string{"Chrome.exe", "steam.exe", "absent.exe"}, TotalKbps: 3000},
+		stop:     make(chan struct{}),
+		policies: make(map[string]budgetPolicy),
+	}
+	applied := make([]int, 3)
+	r.apply([]int{1000, 1000, 1000}, applied, func(string) {})
+	r.apply([]int{2000, 500, 500}, applied, func(string) {}) // changes both running members again
+
+	want := []string{"budget:chrome.exe", "budget:steam.exe"}
+	if got := slices.Compact(slices.Sorted(slices.Values(l.applied))); !slices.Equal(got, want) {
+		t.Fatalf("applied keys %q, want %q", got, want)
+	}
+	r.Stop(func(string) {})
+	slices.Sort(l.cleared)
+	if !slices.Equal(l.cleared, want) {
+		t.Errorf("Stop cleared %q, want %q", l.cleared, want)
+	}
+
+	// Every rate change and every clear is audited
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e auditEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Params["trigger"] != "shared budget" {
+			t.Errorf("audit entry %+v lacks the shared budget trigger", e)
+		}
+		actions = append(actions, e.Action)
+	}
+	if want := []string{"limit", "limit", "limit", "limit", "clear", "clear"}; !slices.Equal(actions, want) {
+		t.Errorf("audited %q, want %q", actions, want)
+	}
+}
+
+func TestBudgetKeysDontTouchTrackedRules(t *testing.T) {
+	recordingTestEnv(t)
+	exe := `C:\Program Files\Google\Chrome\Application\chrome.exe`
+	if key := budgetPolicyKey(exe); key == (LimitRule{Process: "chrome.exe", ExePath: exe}).policyKey() {
+		t.Errorf("budget policy key %q is the tracked rule's", key)
+	}
+	if err := tracked.put(LimitRule{Process: "chrome.exe", ExePath: exe, OutKbps: 500}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := startSharedBudget(SharedBudget{Members: []string{"CHROME.EXE", "steam.exe"}, TotalKbps: 2000}, func(string) {}); err == nil {
+		t.Error("started a budget over a process with a tracked rule")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+
+	"netlimiter/netlimiter"
+)
+
+const (
+	busyAlertPrefKey         = "busyAlert" // off by default to avoid nagging
+	busyThresholdPrefKey     = "busyThresholdKbps"
+	defaultBusyThresholdKbps = 5000
+
+	// How often all processes' counters are sampled while alerts are on
+	busySampleInterval = 5 * time.Second
+
+	// Consecutive samples over the threshold before a process counts as busy
+	busySustainSamples = 3
+
+	// Don't alert about the same process again within this time
+	busyAlertCooldown = 10 * time.Minute
+)
+
+// Process whose throughput stayed over the threshold
+type busyProcess struct {
+	Name string
+	Kbps int
+}
+
+// Tracks per-process throughput between samples.
+// Like the usage column, this counts all process I/O, so disk-heavy apps can trigger it.
+type busyMonitor struct {
+	last    map[int32]uint64
+	over    map[string]int // consecutive samples over the threshold, by lower-cased name
+	alerted map[string]time.Time
+}
+
+func newBusyMonitor() *busyMonitor {
+	return &busyMonitor{last: make(map[int32]uint64), over: make(map[string]int), alerted: make(map[string]time.Time)}
+}
+
+// Alert threshold from preferences, falling back to the default
+func busyThresholdKbps(prefs fyne.Preferences) int {
+	if kbps := prefs.Int(busyThresholdPrefKey); kbps > 0 {
+		return kbps
+	}
+	return defaultBusyThresholdKbps
+}
+
+// Take one sample and return processes that just became busy.
+// Processes this tool already limits and this app itself are ignored.
+func (m *busyMonitor) sample(procs []netlimiter.Process, src ProcessSource, rules []LimitRule, thresholdKbps int, elapsed time.Duration, now time.Time) []busyProcess {
+	limited := make(map[string]bool)
+	for _, r := range rules {
+		if r.Package == "" && r.status() == statusActive {
+			limited[strings.ToLower(r.Process)] = true
+		}
+	}
+	self := int32(os.Getpid())
+
+	rates := make(map[string]uint64) // bytes since the previous sample, by lower-cased name
+	names := make(map[string]string)
+	cur := make(map[int32]uint64, len(procs))
+	for _, p := range procs {
+		key := strings.ToLower(p.Name)
+		if p.PID == self || limited[key] {
+			continue
+		}
+		total, err := src.IOBytes(p.PID)
+		if err != nil {
+			continue
+		}
+		cur[p.PID] = total
+		if prev, ok := m.last[p.PID]; ok && total >= prev {
+			rates[key] += total - prev
+			names[key] = p.Name
+		}
+	}
+	m.last = cur
+
+	var busy []busyProcess
+	for key := range m.over {
+		if _, ok := rates[key]; !ok {
+			delete(m.over, key)
+		}
+	}
+	for key, bytes := range rates {
+		kbps := int(float64(bytes) * 8 / 1000 / elapsed.Seconds())
+		if kbps < thresholdKbps {
+			delete(m.over, key)
+			continue
+		}
+		m.over[key]++
+		if m.over[key] < busySustainSamples || now.Sub(m.alerted[key]) < busyAlertCooldown {
+			continue
+		}
+		m.alerted[key] = now
+		busy = append(busy, busyProcess{Name: names[key], Kbps: kbps})
+	}
+	return busy
+}
+
+// Sample while alerts are enabled, calling onBusy for each newly busy process
+func watchBusyProcesses(prefs fyne.Preferences, onBusy func(busyProcess)) {
+	ticker := time.NewTicker(busySampleInterval)
+	defer ticker.Stop()
+
+	m := newBusyMonitor()
+	lastSample := time.Now()
+	for now := range ticker.C {
+		elapsed := now.Sub(lastSample)
+		lastSample = now
+		if !prefs.Bool(busyAlertPrefKey) {
+			m = newBusyMonitor()
+			continue
+		}
+		procs, err := procCache.snapshot()
+		if err != nil {
+			continue
+		}
+		for _, b := range m.sample(procs, processes, tracked.list(), busyThresholdKbps(prefs), elapsed, now) {
+			onBusy(b)
+		}
+	}
+}
+
+// Notification text for a busy process
+func (b busyProcess) message() string {
+	return fmt.Sprintf("%s is using about %s/s. Use \"Throttle %s\" in the tray menu to limit it.", b.Name, formatBytes(uint64(b.Kbps)*1000/8), b.Name)
+}
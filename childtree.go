@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"netlimiter/netlimiter"
+)
+
+// Limits on the child process walk, so a fork bomb or a PID cycle can't
+// stall an apply
+const (
+	childTreeMaxDepth = 8
+	childTreeMaxProcs = 256
+)
+
+// A process found below one of the matched processes
+type childProcess struct {
+	PID     int32
+	Parent  int32
+	Depth   int    // 1 for a direct child
+	ExePath string // empty when it can't be read
+}
+
+// Every descendant of roots, breadth first. Each PID is visited once, so a
+// cycle (Windows reuses the PIDs of exited parents) ends the walk, and a
+// child that started before its parent is skipped as such a reused PID.
+// truncated reports hitting childTreeMaxDepth or childTreeMaxProcs.
+func descendantProcesses(src ProcessSource, roots []int32) (children []childProcess, truncated bool) {
+	seen := make(map[int32]bool, len(roots))
+	for _, pid := range roots {
+		seen[pid] = true
+	}
+	level := roots
+	for depth := 1; len(level) > 0; depth++ {
+		var next []int32
+		for _, parent := range level {
+			pids, err := src.Children(parent)
+			if err != nil {
+				continue
+			}
+			parentStart, parentErr := src.StartTime(parent)
+			for _, pid := range pids {
+				if seen[pid] {
+					continue
+				}
+				seen[pid] = true
+				if start, err := src.StartTime(pid); parentErr == nil && err == nil && start.Before(parentStart) {
+					continue
+				}
+				if depth > childTreeMaxDepth || len(children) >= childTreeMaxProcs {
+					return children, true
+				}
+				exe, _ := src.ExePath(pid)
+				children = append(children, childProcess{PID: pid, Parent: parent, Depth: depth, ExePath: exe})
+				next = append(next, pid)
+			}
+		}
+		level = next
+	}
+	return children, false
+}
+
+// PIDs of the running processes started from exePath
+func pidsRunning(src ProcessSource, exePath string) ([]int32, error) {
+	procs, err := src.ListProcesses()
+	if err != nil {
+		return nil, err
+	}
+	var pids []int32
+	for _, p := range procs {
+		if strings.EqualFold(p.ExePath, exePath) {
+			pids = append(pids, p.PID)
+		}
+	}
+	return pids, nil
+}
+
+// Executable paths of children not already in known, each once. Windows
+// programs a child tree often contains (conhost.exe, WerFault.exe, svchost.exe)
+// are returned as excluded instead: limiting them would throttle every app
+// that uses them, not just this one.
+func childExePaths(children []childProcess, known []string) (paths, excluded []string) {
+	for _, c := range children {
+		if c.ExePath == "" {
+			continue
+		}
+		same := func(p string) bool { return strings.EqualFold(p, c.ExePath) }
+		if slices.ContainsFunc(known, same) || slices.ContainsFunc(paths, same) || slices.ContainsFunc(excluded, same) {
+			continue
+		}
+		if isSystemPath(c.ExePath) || isCriticalProcess(netlimiter.ExeFileName(c.ExePath)) {
+			excluded = append(excluded, c.ExePath)
+		} else {
+			paths = append(paths, c.ExePath)
+		}
+	}
+	return paths, excluded
+}
+
+// Indented tree of the children for the log
+func formatChildTree(children []childProcess, truncated bool) string {
+	var b strings.Builder
+	for _, c := range children {
+		exe := c.ExePath
+		if exe == "" {
+			exe = "(path not readable, not covered)"
+		}
+		fmt.Fprintf(&b, "  %s%d  %s  (parent %d)\n", strings.Repeat("  ", c.Depth-1), c.PID, exe, c.Parent)
+	}
+	if truncated {
+		fmt.Fprintf(&b, "  ... stopped after %d processes or %d levels\n", childTreeMaxProcs, childTreeMaxDepth)
+	}
+	return b.String()
+}
+
+// Walk the process tree below roots and return the extra executable paths
+// to cover, logging every process found
+func includeChildProcesses(src ProcessSource, roots []int32, known []string, log func(string)) []string {
+	children, truncated := descendantProcesses(src, roots)
+	if len(children) == 0 {
+		log(fmt.Sprintf("No child processes of %s", formatPIDs(roots)))
+		return nil
+	}
+	log(fmt.Sprintf("%d child process(es) of %s:\n%s", len(children), formatPIDs(roots), strings.TrimSuffix(formatChildTree(children, truncated), "\n")))
+	paths, excluded := childExePaths(children, known)
+	for _, p := range excluded {
+		log("Excluding system child process: " + p)
+	}
+	for _, p := range paths {
+		log("Including child executable: " + p)
+	}
+	return paths
+}
+
+func formatPIDs(pids []int32) string {
+	s := make([]string, len(pids))
+	for i, pid := range pids {
+		s[i] = fmt.Sprint(pid)
+	}
+	return "PID " + strings.Join(s, ", ")
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestChildExePathsExcludesSystemChildren(t *testing.T) {
+	t.Setenv("SystemRoot", `C:\Windows`)
+	children := []childProcess{
+		{PID: 2, Depth: 1, ExePath: `C:\Games\Demo\game.exe`},
+		{PID: 3, Depth: 1, ExePath: `C:\Windows\System32\conhost.exe`},
+		{PID: 4, Depth: 2, ExePath: `C:\Games\Demo\crashpad.exe`},
+		{PID: 5, Depth: 2, ExePath: `C:\WINDOWS\system32\WerFault.exe`},
+		{PID: 6, Depth: 2, ExePath: `C:\Windows\SysWOW64\cmd.exe`},
+		{PID: 7, Depth: 3, ExePath: `D:\Tools\svchost.exe`}, // critical by name wherever it runs
+		{PID: 8, Depth: 3, ExePath: `C:\Windows\System32\conhost.exe`},
+		{PID: 9, Depth: 3, ExePath: `c:\games\demo\GAME.EXE`},
+		{PID: 10, Depth: 3},
+		{PID: 11, Depth: 1, ExePath: `C:\Games\Demo\launcher.exe`},
+	}
+	paths, excluded := childExePaths(children, []string{`C:\Games\Demo\launcher.exe`})
+	if want := []string{`C:\Games\Demo\game.exe`, `C:\Games\Demo\crashpad.exe`}; !slices.Equal(paths, want) {
+		t.Errorf("paths = %q, want %q", paths, want)
+	}
+	wantExcluded := []string{
+		`C:\Windows\System32\conhost.exe`,
+		`C:\WINDOWS\system32\WerFault.exe`,
+		`C:\Windows\SysWOW64\cmd.exe`,
+		`D:\Tools\svchost.exe`,
+	}
+	if !slices.Equal(excluded, wantExcluded) {
+		t.Errorf("excluded = %q, want %q", excluded, wantExcluded)
+	}
+}
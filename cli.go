@@ -0,0 +1,214 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"netlimiter/netlimiter"
+)
+
+// Headless command-line options; any of them skips the GUI
+type cliOptions struct {
+	process string
+	inKbps  int
+	outKbps int
+	outPct  int
+	adapter string
+	block   bool
+	clear   bool
+	list    bool
+	dryRun  bool
+}
+
+// Register the CLI flags on the default flag set
+func registerCLIFlags() *cliOptions {
+	o := &cliOptions{}
+	flag.StringVar(&o.process, "process", "", "process name (e.g. chrome.exe), PID or full .exe path to limit, block or clear")
+	flag.IntVar(&o.inKbps, "in", 0, "IN limit in kbps; reported only, since Windows QoS only throttles outbound traffic, and refused without -out")
+	flag.IntVar(&o.outKbps, "out", 0, "OUT limit in kbps")
+	flag.IntVar(&o.outPct, "out-percent", 0, "OUT limit as a percentage (1-100) of the adapter's link speed, instead of -out")
+	flag.StringVar(&o.adapter, "adapter", "", "network adapter -out-percent is taken of (default: the one carrying the default route)")
+	flag.BoolVar(&o.block, "block", false, "block all internet for -process")
+	flag.BoolVar(&o.clear, "clear", false, "clear the rule for -process, or every rule without -process")
+	flag.BoolVar(&o.list, "list", false, "list tracked rules and the live QoS/firewall state")
+	flag.BoolVar(&o.dryRun, "dry-run", false, "print the PowerShell scripts instead of running them; also starts the GUI in dry-run mode")
+	return o
+}
+
+// Whether any CLI flag was given on the command line
+func cliRequested() bool {
+	requested := false
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "process", "in", "out", "out-percent", "block", "clear", "list":
+			requested = true
+		}
+	})
+	return requested
+}
+
+// Resolve a process name or .exe path to the executable to target
+func resolveCLITarget(target string) (LimitRule, error) {
+	if strings.ContainsAny(target, `\/`) {
+		exePath, err := resolveExePath(target)
+		if err != nil {
+			return LimitRule{}, err
+		}
+		return LimitRule{Process: filepath.Base(exePath), ExePath: exePath}, nil
+	}
+	if pid, ok := parsePID(target); ok {
+		exePath, err := resolveExeByPID(processes, pid)
+		if err != nil {
+			return LimitRule{}, err
+		}
+		return LimitRule{Process: filepath.Base(exePath), ExePath: exePath}, nil
+	}
+	name, err := sanitizeTarget(target, matchModeExact)
+	if err != nil {
+		return LimitRule{}, err
+	}
+	pids, err := processes.FindPIDsByName(name)
+	if err != nil {
+		return LimitRule{}, fmt.Errorf("error finding process: %w", err)
+	}
+	if len(pids) == 0 {
+		return LimitRule{}, fmt.Errorf("no process found with name %s: %w", name, errProcessNotFound)
+	}
+	instances := make([]processInstance, len(pids))
+	for i, pid := range pids {
+		instances[i].PID = pid
+	}
+	paths, err := distinctExePaths(processes, instances)
+	if err != nil {
+		return LimitRule{}, fmt.Errorf("could not get executable path: %w", err)
+	}
+	fmt.Printf("%d distinct executable path(s) for %s\n", len(paths), name)
+	return LimitRule{Process: name, ExePath: paths[0], ExtraPaths: paths[1:]}, nil
+}
+
+// Headless mode: run one operation through the same backend and rule
+// tracking as the GUI, print the log and return the process exit code
+func runCLI(o *cliOptions) int {
+	if err := tracked.load(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load tracked rules:", err)
+	}
+	fail := func(err error) int {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		if hint := remediation(err); hint != "" {
+			fmt.Fprintln(os.Stderr, hint)
+		}
+		return 1
+	}
+
+	switch {
+	case o.list:
+		return listCLI()
+
+	case o.clear && o.process == "":
+		log, err := backend.ClearAll().result()
+		fmt.Print(log)
+		auditOrLog(printLog, "clear", "", "", map[string]any{"scope": "all", "mode": "cli"}, err)
+		if err != nil {
+			return fail(err)
+		}
+		if err := tracked.clear(); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not save tracked rules:", err)
+		}
+		return 0
+
+	case o.process == "":
+		return fail(fmt.Errorf("-process is required unless -clear or -list is used alone"))
+
+	case o.clear:
+		r, ok := tracked.get(strings.ToLower(filepath.Base(o.process)))
+		if !ok {
+			r = LimitRule{Process: filepath.Base(o.process)}
+		}
+		log, err := clearRule(r).result()
+		fmt.Print(log)
+		auditOrLog(printLog, "clear", r.Process, r.ExePath, map[string]any{"scope": "target", "mode": "cli"}, err)
+		if err != nil {
+			return fail(err)
+		}
+		if err := tracked.remove(r.key()); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not save tracked rules:", err)
+		}
+		return 0
+	}
+
+	if err := netlimiter.ValidateLimitKbps(o.inKbps); err != nil {
+		return fail(fmt.Errorf("-in: %w", err))
+	}
+	if o.outPct != 0 {
+		kbps, note, err := percentOfAdapterKbps(o.outPct, o.adapter)
+		if err != nil {
+			return fail(fmt.Errorf("-out-percent: %w", err))
+		}
+		fmt.Println("OUT limit:", note)
+		o.outKbps = kbps
+	}
+	if err := netlimiter.ValidateLimitKbps(o.outKbps); err != nil {
+		return fail(fmt.Errorf("-out: %w", err))
+	}
+	if !o.block {
+		if err := checkInboundLimit(o.inKbps, o.outKbps); err != nil {
+			return fail(fmt.Errorf("-in: %w", err))
+		}
+	}
+	r, err := resolveCLITarget(o.process)
+	if err != nil {
+		return fail(err)
+	}
+	r.InKbps, r.OutKbps = o.inKbps, o.outKbps
+	r.Blocked = o.block || (o.inKbps == 0 && o.outKbps == 0)
+
+	// Replace this target's previous rule, as the GUI does
+	if prev, ok := tracked.get(r.key()); ok {
+		if log, err := clearRule(prev).result(); err != nil {
+			fmt.Print(log)
+			fmt.Fprintln(os.Stderr, "Clear error:", err)
+		}
+	}
+	fmt.Println("Applying", r.describe())
+	log, err := applyRule(r).result()
+	fmt.Print(log)
+	if saveErr := tracked.recordResult(r, err); saveErr != nil {
+		fmt.Fprintln(os.Stderr, "Could not save tracked rules:", saveErr)
+	}
+	action := "limit"
+	if r.Blocked {
+		action = "block"
+	}
+	auditOrLog(printLog, action, r.Process, r.ExePath, map[string]any{"inKbps": r.InKbps, "outKbps": r.OutKbps, "mode": "cli"}, err)
+	if err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+// Print tracked rules and what is actually live in Windows
+func listCLI() int {
+	rules := tracked.list()
+	fmt.Printf("Tracked rules (%d):\n", len(rules))
+	for _, r := range rules {
+		fmt.Printf("  %-8s %s\n", r.status(), r.describe())
+	}
+	live, err := backend.LiveRules()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Live state error:", err)
+		return 1
+	}
+	fmt.Printf("Live policies (%d):\n", len(live))
+	for _, p := range live {
+		fmt.Printf("  %-8s %s %s\n", p.Kind, p.Name, p.AppPath)
+	}
+	return 0
+}
+
+// Log function for headless modes
+func printLog(s string) {
+	fmt.Println(s)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"netlimiter/netlimiter"
+)
+
+// Version of the config file format; files from a newer version are refused
+const configSchemaVersion = 1
+
+const (
+	configMerge   = "Merge with current"
+	configReplace = "Replace current"
+)
+
+// Portable copy of the rules and profiles, for setting up other machines
+type configFile struct {
+	Version  int            `json:"version"`
+	Exported time.Time      `json:"exported"`
+	Rules    []LimitRule    `json:"rules"`
+	Profiles []limitProfile `json:"profiles"`
+}
+
+// Config holding rules and profiles, without this machine's rule state
+func buildConfig(rules []LimitRule, profiles []limitProfile) configFile {
+	c := configFile{Version: configSchemaVersion, Exported: time.Now(), Profiles: profiles}
+	for _, r := range rules {
+		r.AppliedAt, r.Status, r.BoostUntil, r.LastError = time.Time{}, "", time.Time{}, ""
+		c.Rules = append(c.Rules, r)
+	}
+	return c
+}
+
+func writeConfig(w io.Writer, c configFile) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// Read and validate a config file
+func readConfig(r io.Reader) (configFile, error) {
+	var c configFile
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return c, fmt.Errorf("not a valid config file: %w", err)
+	}
+	switch {
+	case c.Version == 0:
+		return c, errors.New("not a net-limiter config file (no version)")
+	case c.Version > configSchemaVersion:
+		return c, fmt.Errorf("config file version %d is newer than this app supports (%d); update the app", c.Version, configSchemaVersion)
+	}
+	for i, r := range c.Rules {
+		if strings.TrimSpace(r.Process) == "" {
+			return c, fmt.Errorf("rule %d: no process", i+1)
+		}
+		if err := errors.Join(netlimiter.ValidateLimitKbps(r.InKbps), netlimiter.ValidateLimitKbps(r.OutKbps), r.scope().validate()); err != nil {
+			return c, fmt.Errorf("rule %s: %w", r.Process, err)
+		}
+	}
+	for _, p := range c.Profiles {
+		if strings.TrimSpace(p.Name) == "" {
+			return c, errors.New("profile with no name")
+		}
+		if err := p.validateSharedCap(); err != nil {
+			return c, fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+		for _, e := range p.Entries {
+			if err := errors.Join(netlimiter.ValidateLimitKbps(e.InKbps), netlimiter.ValidateLimitKbps(e.OutKbps)); err != nil {
+				return c, fmt.Errorf("profile %s: %s: %w", p.Name, e.Name, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+// Store the config's rules and profiles. Nothing is applied: rules are
+// queued as pending, to be applied from the Limit tab. Replacing drops the
+// current rules and profiles from the lists first.
+func importConfig(prefs fyne.Preferences, c configFile, replace bool, log func(string)) {
+	if replace {
+		saveProfiles(prefs, nil)
+		if err := tracked.clear(); err != nil {
+			log("Could not save tracked rules: " + err.Error())
+		}
+	}
+	for _, p := range c.Profiles {
+		setProfile(prefs, p)
+	}
+	for _, r := range c.Rules {
+		if have, ok := tracked.get(r.key()); ok && sameRuleSettings(have, r) {
+			continue
+		}
+		if err := tracked.markPending(r); err != nil {
+			log("Could not save tracked rules: " + err.Error())
+		}
+	}
+	log(fmt.Sprintf("Imported %d rule(s) as pending and %d profile(s); nothing was applied", len(c.Rules), len(c.Profiles)))
+}
+
+// Save the tracked rules and profiles to a user-chosen JSON file
+func showExportConfig(window fyne.Window, prefs fyne.Preferences, appendLog func(string)) {
+	save := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
+		if err != nil || w == nil {
+			return
+		}
+		defer w.Close()
+		c := buildConfig(tracked.list(), loadProfiles(prefs))
+		if err := writeConfig(w, c); err != nil {
+			appendLog("Export config error: " + err.Error())
+			return
+		}
+		appendLog(fmt.Sprintf("Exported %d rule(s) and %d profile(s) to: %s", len(c.Rules), len(c.Profiles), w.URI().Path()))
+	}, window)
+	save.SetFileName("net-limiter-config.json")
+	save.Show()
+}
+
+// Pick a config file, show what it holds and merge or replace on confirmation
+func showImportConfig(window fyne.Window, prefs fyne.Preferences, appendLog func(string)) {
+	dialog.ShowFileOpen(func(rc fyne.URIReadCloser, err error) {
+		if err != nil || rc == nil {
+			return
+		}
+		defer rc.Close()
+
+		c, err := readConfig(rc)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "%d rule(s), queued as pending (nothing is applied):\n", len(c.Rules))
+		for _, r := range c.Rules {
+			fmt.Fprintf(&b, "- %s\n", r.describe())
+		}
+		fmt.Fprintf(&b, "\n%d profile(s):\n", len(c.Profiles))
+		for _, p := range c.Profiles {
+			fmt.Fprintf(&b, "- %s (%d process(es))", p.Name, len(p.Entries))
+			if p.SharedCapKbps > 0 {
+				fmt.Fprintf(&b, ", sharing %d kbps", p.SharedCapKbps)
+			}
+			b.WriteString("\n")
+		}
+		summary := widget.NewLabel(b.String())
+		mode := widget.NewRadioGroup([]string{configMerge, configReplace}, nil)
+		mode.SetSelected(configMerge)
+		note := widget.NewLabel("Replacing drops the current rules from the list; their policies stay in Windows until cleared (Manage Rules tab).")
+		note.Wrapping = fyne.TextWrapWord
+
+		content := container.NewBorder(nil, container.NewVBox(mode, note), nil, nil, container.NewVScroll(summary))
+		d := dialog.NewCustomConfirm("Import config", "Import", "Cancel", content, func(ok bool) {
+			if !ok {
+				return
+			}
+			appendLog("----------------------------------------------------")
+			appendLog(fmt.Sprintf("Importing config %s (%s)", rc.URI().Name(), strings.ToLower(mode.Selected)))
+			importConfig(prefs, c, mode.Selected == configReplace, appendLog)
+		}, window)
+		d.Resize(fyne.NewSize(520, 420))
+		d.Show()
+	}, window)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Existing tracked rule that interacts with a rule about to be applied
+type ruleConflict struct {
+	Existing LimitRule
+	Reason   string
+}
+
+// Whether two rules act on the same target: same key or same resolved executable
+func sameTarget(a, b LimitRule) bool {
+	if a.key() == b.key() {
+		return true
+	}
+	return a.ExePath != "" && b.ExePath != "" && strings.EqualFold(a.ExePath, b.ExePath)
+}
+
+// Whether two rules' scopes overlap. Rules currently cover every port and both
+// directions, so any two rules on the same target overlap.
+func scopesOverlap(a, b LimitRule) bool {
+	return sameTarget(a, b)
+}
+
+// Find active tracked rules that would interact with newRule.
+// Re-applying an identical rule is not a conflict.
+func findConflicts(newRule LimitRule, existing []LimitRule) []ruleConflict {
+	var conflicts []ruleConflict
+	for _, r := range existing {
+		// Failed, paused or pending rules aren't in effect
+		if r.status() != statusActive || !scopesOverlap(newRule, r) {
+			continue
+		}
+		if r.key() == newRule.key() && r.Blocked == newRule.Blocked &&
+			r.InKbps == newRule.InKbps && r.OutKbps == newRule.OutKbps {
+			continue
+		}
+
+		var reason string
+		switch {
+		case r.Blocked && !newRule.Blocked:
+			reason = "is blocked, which would make the new throttle meaningless"
+		case !r.Blocked && newRule.Blocked:
+			reason = "is throttled; the new block overrides the throttle entirely"
+		case r.Blocked && newRule.Blocked:
+			reason = "is already blocked"
+		default:
+			reason = fmt.Sprintf("is already throttled (in %d / out %d kbps); only one throttle takes effect", r.InKbps, r.OutKbps)
+		}
+		conflicts = append(conflicts, ruleConflict{Existing: r, Reason: reason})
+	}
+	return conflicts
+}
+
+// Describe conflicts for a confirmation dialog
+func formatConflicts(conflicts []ruleConflict) string {
+	var b strings.Builder
+	b.WriteString("The new rule overlaps existing rules:\n\n")
+	for _, c := range conflicts {
+		target := c.Existing.Process
+		if c.Existing.ExePath != "" {
+			target = c.Existing.ExePath
+		}
+		fmt.Fprintf(&b, "- %s %s\n", target, c.Reason)
+	}
+	b.WriteString("\nReplace the existing rules with the new one?")
+	return b.String()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	verifyAfterClearPrefKey = "verifyAfterClear"
+
+	// Lightweight reachability target (Windows' own connectivity test host)
+	connectivityProbeAddr    = "www.msftconnecttest.com:80"
+	connectivityProbeTimeout = 5 * time.Second
+
+	// How long a connectivity test measures the app's traffic
+	connectivitySampleTime = 5 * time.Second
+
+	// Measured rates this far above a limit suggest the policy isn't biting
+	// (disk I/O also counts, so small overshoots are normal)
+	limitOvershoot = 1.2
+)
+
+// Open and close a TCP connection to check general internet reachability
+func probeReachability(addr string) error {
+	conn, err := net.DialTimeout("tcp", addr, connectivityProbeTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// Live policies that still match any of the cleared rules
+func leftoverRules(cleared []LimitRule, live []livePolicy) []livePolicy {
+	var left []livePolicy
+	for _, p := range live {
+		for _, r := range cleared {
+			if liveMatchesRule(p, r) {
+				left = append(left, p)
+				break
+			}
+		}
+	}
+	return left
+}
+
+// After a clear, confirm no QoS/firewall rule still targets the previously
+// limited processes, then check that the internet is reachable
+func verifyClear(cleared []LimitRule, log func(string)) {
+	live, err := backend.LiveRules()
+	if err != nil {
+		log("Connectivity check: could not query live rules: " + err.Error())
+		return
+	}
+
+	left := leftoverRules(cleared, live)
+	for _, p := range left {
+		log(fmt.Sprintf("Connectivity check: leftover %s rule %s still targets %s", p.Kind, p.Name, p.AppPath))
+	}
+
+	if err := probeReachability(connectivityProbeAddr); err != nil {
+		log("Connectivity check: internet not reachable: " + err.Error())
+		return
+	}
+	if len(left) == 0 {
+		log("Connectivity restored: no leftover rules and the internet is reachable")
+	}
+}
+
+// Check a rule is in effect and the app isn't cut off by accident: list the
+// live policies matching it (a firewall block on a limited app means no
+// connectivity at all), probe the internet from this machine, and measure
+// the app's traffic next to its limit. The probe runs from this app, not
+// through the rule; only the measurement sees the throttle.
+func testRuleConnectivity(r LimitRule, log func(string)) {
+	log("Connectivity test: " + r.describe())
+
+	live, err := backend.LiveRules()
+	if err != nil {
+		log("Connectivity test: could not query live rules: " + err.Error())
+	}
+	// Match both kinds of policy, whichever the rule asked for
+	limit, block := r, r
+	limit.Blocked, block.Blocked = false, true
+	var throttled, blocked int
+	for _, p := range live {
+		switch {
+		case r.Package == "" && liveMatchesRule(p, limit): // package QoS can't be told apart
+			throttled++
+			log(fmt.Sprintf("  QoS policy %s throttles %s to %d kbps", p.Name, p.AppPath, p.BitsPerSecond/1000))
+		case liveMatchesRule(p, block):
+			blocked++
+			log(fmt.Sprintf("  Firewall rule %s blocks %s", p.Name, p.AppPath))
+		}
+	}
+	switch {
+	case err != nil:
+	case r.Blocked && blocked == 0:
+		log("Warning: no firewall rule blocks this app; the block is not in effect")
+	case !r.Blocked && blocked > 0:
+		log("Warning: a firewall rule blocks this app, so it has no connectivity despite being only limited")
+	case !r.Blocked && r.Package == "" && throttled == 0:
+		log("Warning: no QoS policy matches this app; the limit is not in effect")
+	}
+
+	if err := probeReachability(connectivityProbeAddr); err != nil {
+		log("Connectivity test: internet not reachable from this machine: " + err.Error())
+	} else {
+		log("Connectivity test: internet is reachable from this machine")
+	}
+
+	if r.Package != "" {
+		log("Connectivity test: package traffic can't be measured per process; check the app itself")
+		return
+	}
+	var s rateSampler
+	start := time.Now()
+	s.sample(processes, r.Process, start)
+	time.Sleep(connectivitySampleTime)
+	rate, ok := s.sample(processes, r.Process, time.Now())
+	switch {
+	case rate.PIDs == 0:
+		log("Connectivity test: " + r.Process + " is not running, nothing to measure")
+	case !ok:
+		log("Connectivity test: could not measure " + r.Process)
+	case r.Blocked:
+		log(fmt.Sprintf("Connectivity test: %s measured %s; a blocked app still shows disk I/O", r.Process, rate))
+	default:
+		log(fmt.Sprintf("Connectivity test: %s measured out %d kbps against a limit of %d kbps (in %d kbps, not enforceable)", r.Process, rate.OutKbps, r.OutKbps, rate.InKbps))
+		if r.OutKbps > 0 && float64(rate.OutKbps) > float64(r.OutKbps)*limitOvershoot {
+			log("Warning: the app sends faster than its limit; check the policy matches its executable")
+		}
+	}
+}
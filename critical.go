@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Windows processes whose traffic keeps the machine itself online (DNS,
+// DHCP, name resolution, logon, services); blocking one can cut off all
+// networking until the rule is removed
+var criticalProcessNames = map[string]bool{
+	"system":       true,
+	"registry":     true,
+	"smss.exe":     true,
+	"csrss.exe":    true,
+	"wininit.exe":  true,
+	"winlogon.exe": true,
+	"services.exe": true,
+	"lsass.exe":    true,
+	"svchost.exe":  true,
+	"lsaiso.exe":   true,
+	"dwm.exe":      true,
+	"spoolsv.exe":  true,
+}
+
+// Set by the "Allow blocking Windows system programs" option; without it
+// blocks of executables in the system folders are refused
+var allowSystemBlocks atomic.Bool
+
+// Whether name is one of criticalProcessNames
+func isCriticalProcess(name string) bool {
+	return criticalProcessNames[strings.ToLower(filepath.Base(strings.TrimSpace(name)))]
+}
+
+// Whether exePath is in System32 or its 32-bit twin SysWOW64
+func isSystemPath(exePath string) bool {
+	root := os.Getenv("SystemRoot")
+	if root == "" {
+		root = `C:\Windows`
+	}
+	path := strings.ToLower(strings.ReplaceAll(exePath, "/", `\`))
+	for _, dir := range []string{`System32\`, `SysWOW64\`} {
+		if strings.HasPrefix(path, strings.ToLower(strings.TrimRight(root, `\`)+`\`+dir)) {
+			return true
+		}
+	}
+	return false
+}
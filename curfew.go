@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	curfewsPrefKey = "curfews"
+
+	// How often curfews are evaluated; also bounds how late a boundary is enforced
+	curfewCheckInterval = 30 * time.Second
+
+	curfewNote = "curfew"
+)
+
+var weekdayNames = []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// Daily window during which a process is blocked, or limited when a rate is
+// set (e.g. homework hours), e.g. 22:00-06:00. Days are the weekdays a window
+// starts on; a window past midnight ends the next day.
+type curfew struct {
+	Process  string         `json:"process"`
+	ExePath  string         `json:"exePath,omitempty"` // resolved once the process is seen running
+	Start    string         `json:"start"`             // "HH:MM"
+	End      string         `json:"end"`
+	Days     []time.Weekday `json:"days"`
+	InKbps   int            `json:"inKbps,omitempty"` // both 0 blocks, as on the Limit tab
+	OutKbps  int            `json:"outKbps,omitempty"`
+	Disabled bool           `json:"disabled,omitempty"` // kept but not enforced
+	Enforced bool           `json:"enforced,omitempty"` // we applied it for the current window
+
+	// Set when the user lifted the block during a window; respected until then
+	OverrideUntil time.Time `json:"overrideUntil,omitempty"`
+}
+
+// Curfews are changed by both the dialog and the watcher
+var curfewMu sync.Mutex
+
+func loadCurfews(prefs fyne.Preferences) []curfew {
+	var curfews []curfew
+	if raw := prefs.String(curfewsPrefKey); raw != "" {
+		if json.Unmarshal([]byte(raw), &curfews) != nil {
+			return nil
+		}
+	}
+	return curfews
+}
+
+func saveCurfews(prefs fyne.Preferences, curfews []curfew) {
+	data, _ := json.Marshal(curfews)
+	prefs.SetString(curfewsPrefKey, string(data))
+}
+
+// Parse "HH:MM" into minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Whether now is inside a curfew window, and the next boundary: the end of
+// the current window, or the start of the next one (zero if there is none)
+func (c curfew) window(now time.Time) (bool, time.Time) {
+	start, err1 := parseClock(c.Start)
+	end, err2 := parseClock(c.End)
+	if err1 != nil || err2 != nil || start == end {
+		return false, time.Time{}
+	}
+	length := time.Duration((end-start+24*60)%(24*60)) * time.Minute
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	// Yesterday's window may run past midnight; check it first
+	for offset := -1; offset <= 7; offset++ {
+		day := midnight.AddDate(0, 0, offset)
+		if !c.onDay(day.Weekday()) {
+			continue
+		}
+		from := day.Add(time.Duration(start) * time.Minute)
+		to := from.Add(length)
+		if now.Before(from) {
+			return false, from
+		}
+		if now.Before(to) {
+			return true, to
+		}
+	}
+	return false, time.Time{}
+}
+
+func (c curfew) onDay(d time.Weekday) bool {
+	for _, day := range c.Days {
+		if day == d {
+			return true
+		}
+	}
+	return false
+}
+
+// One-line description for logs and the dialog
+func (c curfew) describe() string {
+	var days []string
+	for i, name := range weekdayNames {
+		if c.onDay(time.Weekday((i + 1) % 7)) {
+			days = append(days, name)
+		}
+	}
+	action := "blocked"
+	if !c.blocks() {
+		action = "limited to " + formatLimit(c.InKbps, c.OutKbps)
+	}
+	desc := fmt.Sprintf("%s %s %s-%s on %s", c.Process, action, c.Start, c.End, strings.Join(days, ", "))
+	if c.Disabled {
+		desc += " (disabled)"
+	}
+	return desc
+}
+
+// Whether the window blocks rather than limits
+func (c curfew) blocks() bool {
+	return c.InKbps <= 0 && c.OutKbps <= 0
+}
+
+// Rule put in place during the window
+func (c curfew) rule() LimitRule {
+	return LimitRule{Process: c.Process, ExePath: c.ExePath, InKbps: c.InKbps, OutKbps: c.OutKbps, Blocked: c.blocks(), Note: curfewNote}
+}
+
+// Whether tracked rule r is what the window puts in place and still active
+func (c curfew) inForce(r LimitRule) bool {
+	if r.status() != statusActive || r.Blocked != c.blocks() {
+		return false
+	}
+	return c.blocks() || (r.InKbps == c.InKbps && r.OutKbps == c.OutKbps)
+}
+
+// Bring one curfew's block or limit in line with the clock. Evaluated as a
+// state rather than at the boundary itself, so a missed boundary (the app
+// wasn't running, or the process wasn't, or the machine slept) is corrected
+// on the next check and the schedule can't drift. A disabled curfew counts
+// as outside its window.
+func enforceCurfew(c *curfew, now time.Time, log func(string)) {
+	key := strings.ToLower(c.Process)
+	if c.ExePath == "" {
+		if pids, err := processes.FindPIDsByName(c.Process); err == nil && len(pids) > 0 {
+			c.ExePath, _ = processes.ExePath(pids[0])
+		}
+	}
+
+	in, boundary := c.window(now)
+	in = in && !c.Disabled
+	r, ok := tracked.get(key)
+	applied := ok && c.inForce(r)
+
+	if !in {
+		c.OverrideUntil = time.Time{}
+		if !c.Enforced {
+			return
+		}
+		c.Enforced = false
+		if !applied || r.Note != curfewNote {
+			return
+		}
+		clearLog, err := clearRule(r).result()
+		log("Curfew over: lifting the curfew rule on " + c.Process)
+		log(clearLog)
+		if err != nil {
+			log("Clear error: " + err.Error())
+		} else if err := tracked.remove(key); err != nil {
+			log("Could not save tracked rules: " + err.Error())
+		}
+		auditOrLog(log, "clear", c.Process, c.ExePath, map[string]any{"trigger": "curfew"}, err)
+		return
+	}
+
+	if now.Before(c.OverrideUntil) {
+		return
+	}
+	if c.Enforced && !applied {
+		c.OverrideUntil = boundary
+		log(fmt.Sprintf("Curfew rule on %s was changed manually; leaving it until %s", c.Process, boundary.Format("Mon 15:04")))
+		return
+	}
+	if applied || c.ExePath == "" {
+		return
+	}
+
+	if ok {
+		if clearLog, err := clearRule(r).result(); err != nil {
+			log(clearLog)
+			log("Clear error: " + err.Error())
+		}
+	}
+	rule := c.rule()
+	applyLog, err := applyRule(rule).result()
+	log(fmt.Sprintf("Curfew started: %s until %s", rule.describe(), boundary.Format("Mon 15:04")))
+	log(applyLog)
+	if err != nil {
+		log("Apply error: " + err.Error())
+	}
+	if saveErr := tracked.recordResult(rule, err); saveErr != nil {
+		log("Could not save tracked rules: " + saveErr.Error())
+	}
+	action := "limit"
+	if rule.Blocked {
+		action = "block"
+	}
+	auditOrLog(log, action, c.Process, c.ExePath, map[string]any{"trigger": "curfew", "inKbps": c.InKbps, "outKbps": c.OutKbps}, err)
+	c.Enforced = err == nil
+}
+
+// Evaluate every curfew now and then every curfewCheckInterval
+func watchCurfews(prefs fyne.Preferences, log func(string)) {
+	ticker := time.NewTicker(curfewCheckInterval)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		curfewMu.Lock()
+		curfews := loadCurfews(prefs)
+		curfewMu.Unlock()
+		if len(curfews) == 0 {
+			continue
+		}
+		for i := range curfews {
+			enforceCurfew(&curfews[i], time.Now(), log)
+		}
+
+		// Save the enforcement state without undoing edits made meanwhile
+		curfewMu.Lock()
+		latest := loadCurfews(prefs)
+		for i := range latest {
+			for _, c := range curfews {
+				if strings.EqualFold(c.Process, latest[i].Process) && c.Start == latest[i].Start && c.End == latest[i].End {
+					latest[i].ExePath, latest[i].Enforced, latest[i].OverrideUntil = c.ExePath, c.Enforced, c.OverrideUntil
+				}
+			}
+		}
+		saveCurfews(prefs, latest)
+		curfewMu.Unlock()
+	}
+}
+
+// Dialog listing curfews with a form to add one
+func showCurfews(window fyne.Window, prefs fyne.Preferences, appendLog func(string)) {
+	processEntry := widget.NewEntry()
+	processEntry.SetPlaceHolder("Process name, e.g. game.exe")
+	startEntry := widget.NewEntry()
+	startEntry.SetText("22:00")
+	endEntry := widget.NewEntry()
+	endEntry.SetText("06:00")
+	days := widget.NewCheckGroup(weekdayNames, nil)
+	days.Horizontal = true
+	days.SetSelected(weekdayNames)
+	inEntry := widget.NewEntry()
+	inEntry.SetPlaceHolder("0")
+	outEntry := widget.NewEntry()
+	outEntry.SetPlaceHolder("0")
+
+	// Change one saved curfew under the lock
+	update := func(process string, change func(*curfew)) {
+		curfewMu.Lock()
+		defer curfewMu.Unlock()
+		curfews := loadCurfews(prefs)
+		for i := range curfews {
+			if strings.EqualFold(curfews[i].Process, process) {
+				change(&curfews[i])
+			}
+		}
+		saveCurfews(prefs, curfews)
+	}
+
+	list := container.NewVBox()
+	var refresh func()
+	refresh = func() {
+		list.RemoveAll()
+		curfewMu.Lock()
+		curfews := loadCurfews(prefs)
+		curfewMu.Unlock()
+		if len(curfews) == 0 {
+			list.Add(widget.NewLabel("No curfews."))
+		}
+		for _, c := range curfews {
+			remove := widget.NewButton("Remove", func() {
+				curfewMu.Lock()
+				var kept []curfew
+				for _, other := range loadCurfews(prefs) {
+					if !strings.EqualFold(other.Process, c.Process) {
+						kept = append(kept, other)
+					}
+				}
+				saveCurfews(prefs, kept)
+				curfewMu.Unlock()
+				appendLog("Removed curfew: " + c.describe() + " (an active curfew rule stays until cleared)")
+				refresh()
+			})
+			// The watcher lifts or applies the rule on its next check
+			enabled := widget.NewCheck("Enabled", nil)
+			enabled.SetChecked(!c.Disabled)
+			enabled.OnChanged = func(on bool) {
+				update(c.Process, func(saved *curfew) { saved.Disabled = !on })
+				if on {
+					appendLog("Enabled curfew for " + c.Process)
+				} else {
+					appendLog("Disabled curfew for " + c.Process + "; a curfew rule in place is lifted on the next check")
+				}
+			}
+			list.Add(container.NewBorder(nil, nil, nil, container.NewHBox(enabled, remove), widget.NewLabel(c.describe())))
+		}
+	}
+	refresh()
+
+	add := widget.NewButton("Add Curfew", func() {
+		c := curfew{Process: strings.TrimSpace(processEntry.Text), Start: strings.TrimSpace(startEntry.Text), End: strings.TrimSpace(endEntry.Text)}
+		for _, name := range days.Selected {
+			for i, n := range weekdayNames {
+				if n == name {
+					c.Days = append(c.Days, time.Weekday((i+1)%7))
+				}
+			}
+		}
+		inKbps, errIn := parseLimit(inEntry.Text, unitKbps)
+		outKbps, errOut := parseLimit(outEntry.Text, unitKbps)
+		c.InKbps, c.OutKbps = inKbps, outKbps
+		_, errStart := parseClock(c.Start)
+		_, errEnd := parseClock(c.End)
+		process, errName := sanitizeTarget(c.Process, matchModeExact)
+		c.Process = process
+		switch {
+		case errName != nil:
+			dialog.ShowInformation("Curfew", "Enter a valid process name: "+errName.Error()+".", window)
+			return
+		case errStart != nil || errEnd != nil || c.Start == c.End:
+			dialog.ShowInformation("Curfew", "Enter different start and end times as HH:MM.", window)
+			return
+		case len(c.Days) == 0:
+			dialog.ShowInformation("Curfew", "Select at least one day.", window)
+			return
+		case errIn != nil || errOut != nil || inKbps < 0 || outKbps < 0:
+			dialog.ShowInformation("Curfew", "Enter limits as whole kbps, or leave both empty to block.", window)
+			return
+		}
+
+		curfewMu.Lock()
+		var kept []curfew
+		for _, other := range loadCurfews(prefs) {
+			if !strings.EqualFold(other.Process, c.Process) {
+				kept = append(kept, other)
+			}
+		}
+		saveCurfews(prefs, append(kept, c))
+		curfewMu.Unlock()
+		appendLog("Added curfew: " + c.describe())
+		refresh()
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem("Process", processEntry),
+		widget.NewFormItem("From", startEntry),
+		widget.NewFormItem("Until", endEntry),
+		widget.NewFormItem("Days", days),
+		widget.NewFormItem("Limit IN (kbps)", inEntry),
+		widget.NewFormItem("Limit OUT (kbps)", outEntry),
+		widget.NewFormItem("", widget.NewLabel("Leave both limits empty to block")),
+		widget.NewFormItem("", add),
+	)
+	d := dialog.NewCustom("Curfews", "Close", container.NewBorder(form, nil, nil, nil, container.NewVScroll(list)), window)
+	d.Resize(fyne.NewSize(560, 460))
+	d.Show()
+}
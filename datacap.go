@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	dataCapsPrefKey = "dataCaps"
+
+	// How often capped processes' counters are sampled and totals saved
+	dataCapCheckInterval = 30 * time.Second
+
+	dataCapNote = "daily data cap"
+)
+
+// Daily byte budget for a process: once Used reaches the cap the process is
+// blocked until midnight, then its previous rule (if any) is restored.
+// Like the usage column this counts all process I/O, so it errs towards blocking early.
+type dataCap struct {
+	Process  string     `json:"process"`
+	ExePath  string     `json:"exePath,omitempty"` // resolved once the process is seen running
+	CapMB    int        `json:"capMB"`
+	Day      string     `json:"day,omitempty"` // local date Used belongs to, "2006-01-02"
+	Used     uint64     `json:"used"`
+	Enforced bool       `json:"enforced,omitempty"` // cap was hit and handled for Day
+	Previous *LimitRule `json:"previous,omitempty"` // rule replaced by the block, restored at reset
+}
+
+// Data caps are changed by both the dialog and the watcher
+var dataCapMu sync.Mutex
+
+func loadDataCaps(prefs fyne.Preferences) []dataCap {
+	var caps []dataCap
+	if raw := prefs.String(dataCapsPrefKey); raw != "" {
+		if json.Unmarshal([]byte(raw), &caps) != nil {
+			return nil
+		}
+	}
+	return caps
+}
+
+func saveDataCaps(prefs fyne.Preferences, caps []dataCap) {
+	data, _ := json.Marshal(caps)
+	prefs.SetString(dataCapsPrefKey, string(data))
+}
+
+func (c dataCap) capBytes() uint64 {
+	return uint64(c.CapMB) * 1000 * 1000
+}
+
+// One-line description for logs and the dialog
+func (c dataCap) describe() string {
+	desc := fmt.Sprintf("%s: %s of %d MB used today", c.Process, formatBytes(c.Used), c.CapMB)
+	if c.Enforced {
+		desc += " (cap reached)"
+	}
+	return desc
+}
+
+// Start a new day: lift our block and restore the rule it replaced
+func resetDataCap(c *dataCap, day string, log func(string)) {
+	if c.Enforced {
+		key := strings.ToLower(c.Process)
+		if r, ok := tracked.get(key); ok && r.Blocked && r.Note == dataCapNote {
+			clearLog, err := clearRule(r).result()
+			log("Data cap reset: unblocking " + c.Process)
+			log(clearLog)
+			if err != nil {
+				log("Clear error: " + err.Error())
+			} else if err := tracked.remove(key); err != nil {
+				log("Could not save tracked rules: " + err.Error())
+			}
+			auditOrLog(log, "clear", c.Process, c.ExePath, map[string]any{"trigger": "data cap reset"}, err)
+		}
+		if c.Previous != nil {
+			prev := *c.Previous
+			applyLog, err := applyRule(prev).result()
+			log("Data cap reset: restoring " + prev.describe())
+			log(applyLog)
+			if err != nil {
+				log("Apply error: " + err.Error())
+			}
+			if saveErr := tracked.recordResult(prev, err); saveErr != nil {
+				log("Could not save tracked rules: " + saveErr.Error())
+			}
+		}
+	}
+	c.Day, c.Used, c.Enforced, c.Previous = day, 0, false, nil
+}
+
+// Add the I/O since the last sample to the day's total and block once the cap
+// is reached. last holds each PID's previous counter; a PID's first sample is
+// only a baseline. Returns true when the cap was hit on this pass.
+func enforceDataCap(c *dataCap, last map[int32]uint64, now time.Time, log func(string)) bool {
+	if day := now.Format("2006-01-02"); c.Day != day {
+		resetDataCap(c, day, log)
+	}
+
+	pids, err := processes.FindPIDsByName(c.Process)
+	if err != nil {
+		return false
+	}
+	seen := make(map[int32]bool, len(pids))
+	for _, pid := range pids {
+		total, err := processes.IOBytes(pid)
+		if err != nil {
+			continue
+		}
+		seen[pid] = true
+		if prev, ok := last[pid]; ok && total >= prev {
+			c.Used += total - prev
+		}
+		last[pid] = total
+		if c.ExePath == "" {
+			c.ExePath, _ = processes.ExePath(pid)
+		}
+	}
+	for pid := range last {
+		if !seen[pid] {
+			delete(last, pid)
+		}
+	}
+
+	// Once handled, a manual unblock is left alone until the next day
+	if c.Enforced || c.Used < c.capBytes() || c.ExePath == "" {
+		return false
+	}
+
+	key := strings.ToLower(c.Process)
+	if r, ok := tracked.get(key); ok {
+		if !r.Blocked {
+			c.Previous = &r
+		}
+		if clearLog, err := clearRule(r).result(); err != nil {
+			log(clearLog)
+			log("Clear error: " + err.Error())
+		}
+	}
+	rule := LimitRule{Process: c.Process, ExePath: c.ExePath, Blocked: true, Note: dataCapNote}
+	applyLog, err := applyRule(rule).result()
+	log(fmt.Sprintf("Daily data cap reached: blocking %s until midnight (%s)", c.Process, c.describe()))
+	log(applyLog)
+	if err != nil {
+		log("Block error: " + err.Error())
+	}
+	if saveErr := tracked.recordResult(rule, err); saveErr != nil {
+		log("Could not save tracked rules: " + saveErr.Error())
+	}
+	auditOrLog(log, "block", c.Process, c.ExePath, map[string]any{"trigger": "data cap", "capMB": c.CapMB}, err)
+	c.Enforced = err == nil
+	return c.Enforced
+}
+
+// Sample capped processes every dataCapCheckInterval, saving the running
+// totals so a restart doesn't reset the count. onCapHit runs once per cap per day.
+func watchDataCaps(prefs fyne.Preferences, log func(string), onCapHit func(dataCap)) {
+	counters := make(map[string]map[int32]uint64) // last I/O totals by lower-cased process
+	ticker := time.NewTicker(dataCapCheckInterval)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		dataCapMu.Lock()
+		caps := loadDataCaps(prefs)
+		dataCapMu.Unlock()
+		if len(caps) == 0 {
+			continue
+		}
+		var hit []dataCap
+		for i := range caps {
+			key := strings.ToLower(caps[i].Process)
+			if counters[key] == nil {
+				counters[key] = make(map[int32]uint64)
+			}
+			if enforceDataCap(&caps[i], counters[key], time.Now(), log) {
+				hit = append(hit, caps[i])
+			}
+		}
+
+		// Save totals without undoing edits made meanwhile
+		dataCapMu.Lock()
+		latest := loadDataCaps(prefs)
+		for i := range latest {
+			for _, c := range caps {
+				if strings.EqualFold(c.Process, latest[i].Process) {
+					capMB := latest[i].CapMB
+					latest[i] = c
+					latest[i].CapMB = capMB
+				}
+			}
+		}
+		saveDataCaps(prefs, latest)
+		dataCapMu.Unlock()
+
+		for _, c := range hit {
+			onCapHit(c)
+		}
+	}
+}
+
+// Dialog listing data caps with today's usage and a form to add one
+func showDataCaps(window fyne.Window, prefs fyne.Preferences, appendLog func(string)) {
+	processEntry := widget.NewEntry()
+	processEntry.SetPlaceHolder("Process name, e.g. game.exe")
+	capEntry := widget.NewEntry()
+	capEntry.SetPlaceHolder("MB per day, e.g. 2000")
+
+	list := container.NewVBox()
+	var refresh func()
+	refresh = func() {
+		list.RemoveAll()
+		dataCapMu.Lock()
+		caps := loadDataCaps(prefs)
+		dataCapMu.Unlock()
+		if len(caps) == 0 {
+			list.Add(widget.NewLabel("No data caps."))
+		}
+		for _, c := range caps {
+			remove := widget.NewButton("Remove", func() {
+				dataCapMu.Lock()
+				var kept []dataCap
+				for _, other := range loadDataCaps(prefs) {
+					if !strings.EqualFold(other.Process, c.Process) {
+						kept = append(kept, other)
+					}
+				}
+				saveDataCaps(prefs, kept)
+				dataCapMu.Unlock()
+				appendLog("Removed data cap for " + c.Process + " (an active cap block stays until cleared)")
+				refresh()
+			})
+			list.Add(container.NewBorder(nil, nil, nil, remove, widget.NewLabel(c.describe())))
+		}
+	}
+	refresh()
+
+	add := widget.NewButton("Add Data Cap", func() {
+		process, err := sanitizeTarget(processEntry.Text, matchModeExact)
+		if err != nil {
+			dialog.ShowInformation("Data cap", "Enter a valid process name: "+err.Error()+".", window)
+			return
+		}
+		capMB, err := strconv.Atoi(strings.TrimSpace(capEntry.Text))
+		if err != nil || capMB <= 0 {
+			dialog.ShowInformation("Data cap", "Enter the cap as a positive number of MB.", window)
+			return
+		}
+
+		dataCapMu.Lock()
+		caps := loadDataCaps(prefs)
+		found := false
+		for i := range caps {
+			// Changing the cap keeps today's total
+			if strings.EqualFold(caps[i].Process, process) {
+				caps[i].CapMB, found = capMB, true
+			}
+		}
+		if !found {
+			caps = append(caps, dataCap{Process: process, CapMB: capMB})
+		}
+		saveDataCaps(prefs, caps)
+		dataCapMu.Unlock()
+		appendLog(fmt.Sprintf("Data cap: %s limited to %d MB per day", process, capMB))
+		refresh()
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem("Process", processEntry),
+		widget.NewFormItem("Cap (MB/day)", capEntry),
+		widget.NewFormItem("", add),
+	)
+	d := dialog.NewCustom("Daily Data Caps", "Close", container.NewBorder(form, nil, nil, nil, container.NewVScroll(list)), window)
+	d.Resize(fyne.NewSize(520, 400))
+	d.Show()
+}
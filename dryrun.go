@@ -0,0 +1,17 @@
+package main
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// Dry-run mode: scripts that would change QoS or firewall state are written
+// to the log instead of run, and rules are neither tracked nor audited.
+// Read-only queries (live state, services, file details) still run.
+// Set by -dry-run and the GUI checkbox, read from worker goroutines.
+var dryRun atomic.Bool
+
+// Output returned for a script skipped by dry-run mode
+func dryRunOutput(script string) string {
+	return "[dry run] not executed:\n" + strings.TrimSpace(script)
+}
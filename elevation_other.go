@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+// Elevation is a Windows notion; other platforms never count as elevated
+func isElevated() bool {
+	return false
+}
+
+func relaunchElevated() error {
+	return errUnsupportedPlatform
+}
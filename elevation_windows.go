@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32                = syscall.NewLazyDLL("advapi32.dll")
+	procGetTokenInformation = advapi32.NewProc("GetTokenInformation")
+	shell32                 = syscall.NewLazyDLL("shell32.dll")
+	procShellExecuteW       = shell32.NewProc("ShellExecuteW")
+)
+
+// TOKEN_INFORMATION_CLASS value for TOKEN_ELEVATION
+const tokenElevation = 20
+
+// Whether this process runs elevated (as Administrator past UAC).
+// An unreadable token counts as not elevated.
+func isElevated() bool {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return false
+	}
+	var token syscall.Token
+	if err := syscall.OpenProcessToken(process, syscall.TOKEN_QUERY, &token); err != nil {
+		return false
+	}
+	defer token.Close()
+
+	var elevated, size uint32
+	ok, _, _ := procGetTokenInformation.Call(uintptr(token), tokenElevation,
+		uintptr(unsafe.Pointer(&elevated)), unsafe.Sizeof(elevated), uintptr(unsafe.Pointer(&size)))
+	return ok != 0 && elevated != 0
+}
+
+// Start this executable again with the same arguments through a UAC prompt.
+// The caller quits once it returns nil; declining the prompt is an error.
+func relaunchElevated() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not find own executable: %w", err)
+	}
+	args := make([]string, len(os.Args)-1)
+	for i, a := range os.Args[1:] {
+		args[i] = syscall.EscapeArg(a)
+	}
+	verb, _ := syscall.UTF16PtrFromString("runas")
+	file, _ := syscall.UTF16PtrFromString(exe)
+	params, _ := syscall.UTF16PtrFromString(strings.Join(args, " "))
+
+	// ShellExecute returns a value above 32 on success
+	const swShowNormal = 1
+	r, _, callErr := procShellExecuteW.Call(0, uintptr(unsafe.Pointer(verb)), uintptr(unsafe.Pointer(file)),
+		uintptr(unsafe.Pointer(params)), 0, swShowNormal)
+	if r <= 32 {
+		return fmt.Errorf("could not restart as Administrator: %w", callErr)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Failure modes callers can tell apart with errors.Is.
+// errUnsupportedPlatform (limiter.go) wraps errors.ErrUnsupported.
+var (
+	errNotElevated       = errors.New("administrator rights required")
+	errProcessNotFound   = errors.New("process not found")
+	errPolicyExists      = errors.New("policy or rule already exists")
+	errCmdletMissing     = errors.New("required PowerShell cmdlet unavailable")
+	errServiceStopped    = errors.New("required Windows service not running")
+	errPowerShellTimeout = errors.New("PowerShell timed out")
+	errSystemProgram     = errors.New("refusing to block a Windows system program")
+	errTransientWMI      = errors.New("transient WMI failure")
+)
+
+// Output fragments that identify a failure mode. English and error-ID
+// fragments are both listed; IDs and HRESULTs survive localized Windows.
+var psErrorPatterns = []struct {
+	kind      error
+	fragments []string
+}{
+	{errNotElevated, []string{"Access is denied", "PermissionDenied", "requires elevation", "0x80070005"}},
+	{errPolicyExists, []string{"already exists", "ObjectExists", "0x800700B7"}},
+	{errCmdletMissing, []string{"is not recognized as the name of a cmdlet", "CommandNotFoundException"}},
+	{errServiceStopped, []string{"0x800706D9", "service has not been started", "0x80070426"}},
+	// WMI hiccups (generic failure, RPC server busy or restarting) that pass on a retry
+	{errTransientWMI, []string{"Generic failure", "0x80041001", "0x80041006", "0x80041033", "RPC server is unavailable", "0x800706BA", "0x800706BE"}},
+}
+
+// Failure mode named by PowerShell output, nil if unrecognised
+func classifyPowerShellOutput(out string) error {
+	lower := strings.ToLower(out)
+	for _, p := range psErrorPatterns {
+		for _, f := range p.fragments {
+			if strings.Contains(lower, strings.ToLower(f)) {
+				return p.kind
+			}
+		}
+	}
+	return nil
+}
+
+// Failed PowerShell run. Kind is the recognised failure mode, if any;
+// errors.Is matches both Kind and the underlying process error.
+type psError struct {
+	Kind    error
+	Err     error
+	Message string // first error message in the output, "" if none was found
+}
+
+func (e *psError) Error() string {
+	if e.Kind == nil {
+		return e.Err.Error()
+	}
+	return e.Kind.Error() + " (" + e.Err.Error() + ")"
+}
+
+func (e *psError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// Attach the failure mode found in out to a PowerShell error
+func classifyPowerShellError(out string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &psError{Kind: classifyPowerShellOutput(out), Err: err, Message: firstPowerShellError(out)}
+}
+
+// Error record header PowerShell prints, e.g. "New-NetQosPolicy : Access is denied."
+var psErrorLine = regexp.MustCompile(`(?m)^([A-Za-z]+-[A-Za-z]+) : (.+)$`)
+
+// First error message in PowerShell output, without the location and
+// CategoryInfo lines that follow it
+func firstPowerShellError(out string) string {
+	m := psErrorLine.FindStringSubmatch(out)
+	if m == nil {
+		return ""
+	}
+	return m[1] + ": " + strings.TrimSpace(m[2])
+}
+
+// Short, actionable description of a failed PowerShell run in place of its
+// multi-line error record; "" when err is nil. out may be empty when the
+// output was streamed, in which case what err recorded is used.
+func interpretPowerShellError(out string, err error) string {
+	if err == nil {
+		return ""
+	}
+	kind, message := classifyPowerShellOutput(out), firstPowerShellError(out)
+	var ps *psError
+	if out == "" && errors.As(err, &ps) {
+		kind, message = ps.Kind, ps.Message
+	}
+	if kind == nil {
+		// Elevation, timeouts and the like are found before or without any output
+		for _, k := range []error{errNotElevated, errPowerShellTimeout, errors.ErrUnsupported} {
+			if errors.Is(err, k) {
+				kind = k
+			}
+		}
+	}
+
+	var summary string
+	switch kind {
+	case errNotElevated:
+		summary = "Access denied: QoS and firewall changes need Administrator rights."
+	case errPolicyExists:
+		summary = "A policy or rule with this name already exists."
+	case errCmdletMissing:
+		summary = "The NetQos/NetSecurity cmdlets are missing, as on some Windows Home editions."
+	case errServiceStopped:
+		summary = "A Windows service the change needs isn't running."
+	case errTransientWMI:
+		summary = "WMI failed transiently; this usually passes on a retry."
+	case errPowerShellTimeout:
+		summary = "PowerShell stopped responding and was killed."
+	case errors.ErrUnsupported:
+		summary = "This operation only works on Windows."
+	default:
+		summary = message
+		if summary == "" {
+			summary = err.Error()
+		}
+	}
+	if hint := remediation(kind); hint != "" {
+		summary += " " + hint
+	}
+	return summary
+}
+
+// What the user can do about err, "" when there is nothing specific
+func remediation(err error) string {
+	switch {
+	case errors.Is(err, errNotElevated):
+		return "Restart the app as Administrator (Restart as Admin, or right-click > Run as administrator)."
+	case errors.Is(err, errPolicyExists):
+		return "A rule with the same name is left over; use Clear This Target and apply again."
+	case errors.Is(err, errCmdletMissing):
+		return "This Windows edition lacks the NetQos/NetSecurity modules; run the Self-Test for details."
+	case errors.Is(err, errServiceStopped):
+		return "Start the Base Filtering Engine and Windows Defender Firewall services, then retry."
+	case errors.Is(err, errProcessNotFound):
+		return "Start the app first, or target it by executable path."
+	case errors.Is(err, errPowerShellTimeout):
+		return "PowerShell stopped responding; retry, or raise the PowerShell timeout in Settings."
+	case errors.Is(err, errSystemProgram):
+		return "Blocking it can take down the machine's networking; tick \"Allow blocking Windows system programs\" under Options only if you mean it."
+	case errors.Is(err, errors.ErrUnsupported):
+		return "Limits can only be applied on Windows."
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"netlimiter/netlimiter"
+)
+
+// Version resource and Authenticode details of an executable, shown before
+// applying so an impersonating binary (a fake chrome.exe) stands out
+type exeInfo struct {
+	Description     string
+	Company         string
+	SignatureStatus string // Get-AuthenticodeSignature status: Valid, NotSigned, HashMismatch, ...
+	Signer          string // subject of the signing certificate
+}
+
+// Read an executable's file description, company and signature.
+// Get-AuthenticodeSignature also checks catalog signatures, which most Windows binaries use.
+func queryExeInfo(exePath string) (exeInfo, error) {
+	script := fmt.Sprintf(`
+$p = %s
+$v = (Get-Item -LiteralPath $p -ErrorAction Stop).VersionInfo
+$s = Get-AuthenticodeSignature -LiteralPath $p -ErrorAction SilentlyContinue
+[pscustomobject]@{
+  Description = "$($v.FileDescription)"
+  Company = "$($v.CompanyName)"
+  SignatureStatus = "$($s.Status)"
+  Signer = "$($s.SignerCertificate.Subject)"
+} | ConvertTo-Json -Compress
+`, netlimiter.QuotePowerShell(exePath))
+
+	var info exeInfo
+	if err := queryPowerShellJSON(script, &info); err != nil {
+		return exeInfo{}, fmt.Errorf("could not read file details: %w", err)
+	}
+	return info, nil
+}
+
+func (i exeInfo) signed() bool {
+	return i.SignatureStatus == "Valid"
+}
+
+// Common name of the signer, e.g. "Google LLC" from "CN=Google LLC, O=Google LLC, ..."
+func (i exeInfo) signerName() string {
+	for _, part := range strings.Split(i.Signer, ",") {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(part), "CN="); ok {
+			return strings.Trim(name, `"`)
+		}
+	}
+	return i.Signer
+}
+
+// Lines for the log and the confirmation dialog
+func (i exeInfo) describe() string {
+	orUnknown := func(s string) string {
+		if strings.TrimSpace(s) == "" {
+			return "(none)"
+		}
+		return s
+	}
+	signature := "unsigned"
+	switch {
+	case i.signed():
+		signature = "signed by " + i.signerName()
+	case i.SignatureStatus != "" && i.SignatureStatus != "NotSigned":
+		signature = "signature invalid (" + i.SignatureStatus + ")"
+	}
+	return fmt.Sprintf("Description: %s\nCompany: %s\nSignature: %s", orUnknown(i.Description), orUnknown(i.Company), signature)
+}
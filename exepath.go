@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+
+	"netlimiter/netlimiter"
+)
+
+// Receives a note whenever an executable path had to be found another way;
+// set by the GUI, nil in headless mode
+var exePathNote func(string)
+
+// Executable path of a process. Reading it directly can be denied for
+// protected processes even to an administrator, so the command line and then
+// WMI (Win32_Process.ExecutablePath) are tried before giving up; the method
+// that worked is passed to exePathNote.
+func resolveExePathWithFallback(pid int32) (string, error) {
+	exePath, err := netlimiter.ExePath(pid)
+	if err == nil || errors.Is(err, process.ErrorProcessNotRunning) {
+		return exePath, err
+	}
+
+	path, cmdErr := exeFromCommandLine(pid)
+	method := "its command line"
+	if cmdErr != nil {
+		var wmiErr error
+		path, wmiErr = exeFromWMI(pid)
+		method = "WMI"
+		if wmiErr != nil {
+			return "", fmt.Errorf("%w (command line: %v; WMI: %v)", err, cmdErr, wmiErr)
+		}
+	}
+	if exePathNote != nil {
+		exePathNote(fmt.Sprintf("PID %d: executable path not readable (%v); found from %s: %s", pid, err, method, path))
+	}
+	return path, nil
+}
+
+// Executable named at the start of a process's command line, if it is a full
+// path to an existing .exe. Unquoted paths with spaces are tried word by word.
+func exeFromCommandLine(pid int32) (string, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return "", err
+	}
+	cmdline, err := p.Cmdline()
+	if err != nil {
+		return "", err
+	}
+	if path, ok := commandLineExe(cmdline, existingFile); ok {
+		return path, nil
+	}
+	return "", fmt.Errorf("no executable path at the start of %q", cmdline)
+}
+
+func existingFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// First full .exe path of a command line for which exists is true
+func commandLineExe(cmdline string, exists func(string) bool) (string, bool) {
+	cmdline = strings.TrimSpace(cmdline)
+	if rest, ok := strings.CutPrefix(cmdline, `"`); ok {
+		path, _, _ := strings.Cut(rest, `"`)
+		return path, filepath.IsAbs(path) && strings.EqualFold(filepath.Ext(path), ".exe") && exists(path)
+	}
+	words := strings.Fields(cmdline)
+	for i := range words {
+		path := strings.Join(words[:i+1], " ")
+		if filepath.IsAbs(path) && strings.EqualFold(filepath.Ext(path), ".exe") && exists(path) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// Executable path WMI reports for a PID
+func exeFromWMI(pid int32) (string, error) {
+	script := fmt.Sprintf(`ConvertTo-Json -InputObject "$((Get-CimInstance Win32_Process -Filter 'ProcessId = %d' -ErrorAction Stop).ExecutablePath)" -Compress`, pid)
+	var path string
+	if err := queryPowerShellJSON(script, &path); err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", fmt.Errorf("no executable path for PID %d", pid)
+	}
+	return path, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+
+	"netlimiter/netlimiter"
+)
+
+// Header written at the top of exported scripts
+func scriptHeader(purpose string) string {
+	return fmt.Sprintf(`#requires -RunAsAdministrator
+# Generated by Windows NetLimiter GUI on %s
+# %s
+# Uses the Windows QoS (NetQos) and Firewall (NetSecurity) cmdlets and must be
+# run from an elevated (Administrator) PowerShell session.
+`, time.Now().Format("2006-01-02 15:04:05"), purpose)
+}
+
+// Build a standalone script recreating the given rules, using the same
+// cmdlets the app runs. Package limits are resolved to their executable now,
+// since the script can't rely on this tool to do it later.
+func buildApplyScript(rules []LimitRule) (string, error) {
+	var b strings.Builder
+	b.WriteString(scriptHeader("Recreates the network limits below. Run the companion -remove script to undo."))
+
+	for _, r := range rules {
+		fmt.Fprintf(&b, "\n# --- %s ---\n", r.describe())
+		if !r.Blocked {
+			if err := checkInboundLimit(r.InKbps, r.OutKbps); err != nil {
+				return "", fmt.Errorf("%s: %w", r.describe(), err)
+			}
+		}
+		switch {
+		case r.Package != "" && r.Blocked:
+			b.WriteString(packageBlockScript(r.Package))
+		case r.Package != "":
+			exes, err := resolvePackageExecutables(r.Package)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString("# Package executable path is version specific; re-export after app updates\n")
+			b.WriteString(netlimiter.LimitScript(r.policyKey(), exes[0], convertToBitsPerSecond(r.OutKbps, unitKbps), r.qosStore()))
+		default:
+			for _, t := range r.exeTargets() {
+				switch {
+				case r.Blocked:
+					b.WriteString(blockScript(t.Key, t.Path, r.scope()))
+				case r.Priority.active():
+					b.WriteString(netlimiter.QoSScript(t.Key, t.Path, convertToBitsPerSecond(r.OutKbps, unitKbps), dscpForPriority(r.Priority), r.qosStore()))
+				default:
+					b.WriteString(netlimiter.LimitScript(t.Key, t.Path, convertToBitsPerSecond(r.OutKbps, unitKbps), r.qosStore()))
+				}
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// Build the companion script that removes everything the apply script creates
+func buildRemoveScript() string {
+	return scriptHeader("Removes the QoS policies and firewall rules created by Windows NetLimiter GUI.") + clearScript()
+}
+
+// Ask for a file name and write the apply script plus a "-remove" companion next to it
+func exportRulesScript(window fyne.Window, rules []LimitRule, appendLog func(string)) {
+	if len(rules) == 0 {
+		dialog.ShowInformation("Export as .ps1", "There are no applied rules to export.", window)
+		return
+	}
+
+	// Package rules query PowerShell while building, keep that off the UI thread
+	go func() {
+		applyScript, err := buildApplyScript(rules)
+		fyne.Do(func() {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			showScriptSave(window, applyScript, appendLog)
+		})
+	}()
+}
+
+// Save the apply script and its removal companion to a user-chosen location
+func showScriptSave(window fyne.Window, applyScript string, appendLog func(string)) {
+	save := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
+		if err != nil || w == nil {
+			return
+		}
+		defer w.Close()
+
+		if _, err := io.WriteString(w, applyScript); err != nil {
+			appendLog("Export script error: " + err.Error())
+			return
+		}
+		appendLog("Exported rules script to: " + w.URI().Path())
+
+		removeURI, err := companionURI(w.URI(), "-remove")
+		if err != nil {
+			appendLog("Export remove script error: " + err.Error())
+			return
+		}
+		rw, err := storage.Writer(removeURI)
+		if err != nil {
+			appendLog("Export remove script error: " + err.Error())
+			return
+		}
+		defer rw.Close()
+		if _, err := io.WriteString(rw, buildRemoveScript()); err != nil {
+			appendLog("Export remove script error: " + err.Error())
+			return
+		}
+		appendLog("Exported removal script to: " + removeURI.Path())
+	}, window)
+	save.SetFileName("net-limits.ps1")
+	save.Show()
+}
+
+// URI next to u with suffix inserted before the extension (limits.ps1 -> limits-remove.ps1)
+func companionURI(u fyne.URI, suffix string) (fyne.URI, error) {
+	parent, err := storage.Parent(u)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(u.Name(), u.Extension())
+	return storage.Child(parent, name+suffix+u.Extension())
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"netlimiter/netlimiter"
+)
+
+const favoritesPrefKey = "favorites"
+
+// Pinned process name with optional default limits, a quick single-target shortcut
+type favorite struct {
+	Process   string `json:"process"`
+	HasLimits bool   `json:"hasLimits,omitempty"`
+	InKbps    int    `json:"inKbps,omitempty"`
+	OutKbps   int    `json:"outKbps,omitempty"`
+}
+
+// Label for the favorites list
+func (f favorite) describe() string {
+	if !f.HasLimits {
+		return f.Process
+	}
+	return fmt.Sprintf("%s (in %d / out %d kbps)", f.Process, f.InKbps, f.OutKbps)
+}
+
+// Saved favorites, sorted by process name
+func loadFavorites(prefs fyne.Preferences) []favorite {
+	var favs []favorite
+	if raw := prefs.String(favoritesPrefKey); raw != "" {
+		if json.Unmarshal([]byte(raw), &favs) != nil {
+			return nil
+		}
+	}
+	sort.Slice(favs, func(i, j int) bool { return strings.ToLower(favs[i].Process) < strings.ToLower(favs[j].Process) })
+	return favs
+}
+
+func saveFavorites(prefs fyne.Preferences, favs []favorite) {
+	data, _ := json.Marshal(favs)
+	prefs.SetString(favoritesPrefKey, string(data))
+}
+
+// Pin f, replacing a favorite for the same process
+func addFavorite(prefs fyne.Preferences, f favorite) {
+	saveFavorites(prefs, append(withoutFavorite(loadFavorites(prefs), f.Process), f))
+}
+
+// Unpin the favorite for process
+func removeFavorite(prefs fyne.Preferences, process string) {
+	saveFavorites(prefs, withoutFavorite(loadFavorites(prefs), process))
+}
+
+func withoutFavorite(favs []favorite, process string) []favorite {
+	var kept []favorite
+	for _, f := range favs {
+		if !strings.EqualFold(f.Process, process) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// Whether a favorite's process is running and whether this tool limits it
+func favoriteState(f favorite, procs []netlimiter.Process, rules []LimitRule) (running, limited bool) {
+	for _, p := range procs {
+		if strings.EqualFold(p.Name, f.Process) {
+			running = true
+			break
+		}
+	}
+	for _, r := range rules {
+		if r.Package == "" && strings.EqualFold(r.Process, f.Process) && r.status() == statusActive {
+			limited = true
+			break
+		}
+	}
+	return running, limited
+}
+
+// Favorites tab: pin the current target, then load or apply it with one click.
+// current reads the form; load fills it; apply fills it and applies.
+func favoritesPanel(prefs fyne.Preferences, current func() (favorite, error), load, apply func(favorite), appendLog func(string)) (fyne.CanvasObject, func()) {
+	list := container.NewVBox()
+	status := widget.NewLabel("")
+
+	var refresh func()
+	refresh = func() {
+		favs := loadFavorites(prefs)
+		// The process list can take a moment; build the rows off the UI thread
+		go func() {
+			procs, err := procCache.snapshot()
+			rules := tracked.list()
+			fyne.Do(func() {
+				list.RemoveAll()
+				status.SetText("")
+				if err != nil {
+					status.SetText("Could not list processes: " + err.Error())
+				}
+				if len(favs) == 0 {
+					list.Add(widget.NewLabel("No favorites. Fill in the Limit tab and pin it here."))
+				}
+				for _, f := range favs {
+					running, limited := favoriteState(f, procs, rules)
+					state := "not running"
+					if running {
+						state = "running"
+					}
+					if limited {
+						state += ", limited"
+					}
+					buttons := container.NewHBox(
+						widget.NewButton("Load", func() { load(f) }),
+						widget.NewButton("Apply", func() { apply(f) }),
+						widget.NewButton("Unpin", func() {
+							removeFavorite(prefs, f.Process)
+							appendLog("Unpinned favorite: " + f.Process)
+							refresh()
+						}),
+					)
+					list.Add(container.NewBorder(nil, nil, nil, buttons, widget.NewLabel(f.describe()+"  ["+state+"]")))
+				}
+			})
+		}()
+	}
+
+	withLimits := widget.NewCheck("Include the current limits", nil)
+	withLimits.SetChecked(true)
+	pin := widget.NewButton("Pin Current Process", func() {
+		f, err := current()
+		if err != nil {
+			appendLog("Error: " + err.Error())
+			return
+		}
+		if !withLimits.Checked {
+			f.HasLimits, f.InKbps, f.OutKbps = false, 0, 0
+		}
+		addFavorite(prefs, f)
+		appendLog("Pinned favorite: " + f.describe())
+		refresh()
+	})
+	refreshButton := widget.NewButton("Refresh", refresh)
+
+	refresh()
+	top := container.NewVBox(container.NewHBox(pin, withLimits, refreshButton), status)
+	return container.NewBorder(top, nil, nil, nil, container.NewVScroll(list)), refresh
+}
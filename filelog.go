@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+const (
+	// Folder for the log files; empty means logs under the app data folder
+	logDirPrefKey = "logDir"
+
+	// A new file is started once the current one reaches logFileMaxSize,
+	// and only the newest logFilesKept files are kept
+	logFileMaxSize = 1 << 20
+	logFilesKept   = 5
+
+	logFilePrefix = "net-limiter-"
+)
+
+// One line of a log file
+type fileLogEntry struct {
+	Time  time.Time `json:"time"`
+	Level string    `json:"level"` // quiet, normal or verbose, as in the GUI; "script" for PowerShell scripts
+	Text  string    `json:"text"`
+}
+
+// Persistent copy of the log as JSON lines, in size-rotated files named after
+// the time they were started. Writes never fail the caller; the first error
+// is kept for reporting and logging stops until the folder changes.
+type fileLogger struct {
+	mu   sync.Mutex
+	dir  string
+	f    *os.File
+	size int64
+	err  error
+}
+
+var fileLog = &fileLogger{}
+
+// Log folder from preferences, or the default under the app data folder
+func logDir(prefs fyne.Preferences) (string, error) {
+	if dir := strings.TrimSpace(prefs.String(logDirPrefKey)); dir != "" {
+		return dir, nil
+	}
+	base, err := appDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "logs"), nil
+}
+
+// Point fileLog at the folder set in preferences
+func configureFileLog(prefs fyne.Preferences) error {
+	dir, err := logDir(prefs)
+	if err != nil {
+		return err
+	}
+	return fileLog.configure(dir)
+}
+
+// Log to dir from now on, creating it if needed; safe to call again with the same folder
+func (l *fileLogger) configure(dir string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if dir == l.dir && l.err == nil {
+		return nil
+	}
+	l.closeLocked()
+	l.dir, l.err = dir, os.MkdirAll(dir, 0o700)
+	return l.err
+}
+
+// Append a line; does nothing until configured
+func (l *fileLogger) write(level, text string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.dir == "" || l.err != nil {
+		return
+	}
+	line, err := json.Marshal(fileLogEntry{Time: time.Now(), Level: level, Text: strings.TrimRight(text, "\n")})
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	if l.f == nil || l.size+int64(len(line)) > logFileMaxSize {
+		if l.err = l.rotateLocked(); l.err != nil {
+			return
+		}
+	}
+	n, err := l.f.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		l.err = err
+		l.closeLocked()
+	}
+}
+
+// Start a new file and delete the oldest beyond logFilesKept
+func (l *fileLogger) rotateLocked() error {
+	l.closeLocked()
+	name := logFilePrefix + time.Now().Format("20060102-150405.000") + ".log"
+	f, err := os.OpenFile(filepath.Join(l.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	l.f, l.size = f, 0
+
+	// Timestamped names sort oldest first
+	old, _ := filepath.Glob(filepath.Join(l.dir, logFilePrefix+"*.log"))
+	sort.Strings(old)
+	for len(old) > logFilesKept {
+		os.Remove(old[0])
+		old = old[1:]
+	}
+	return nil
+}
+
+func (l *fileLogger) closeLocked() {
+	if l.f != nil {
+		l.f.Close()
+		l.f = nil
+	}
+}
+
+// First write error since the folder was configured, if any
+func (l *fileLogger) lastError() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// Foreground window detection needs the Win32 API
+func foregroundProcessID() (int32, error) {
+	return 0, errors.New("foreground window detection is only supported on Windows")
+}
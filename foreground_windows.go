@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32                       = syscall.NewLazyDLL("user32.dll")
+	procGetForegroundWindow      = user32.NewProc("GetForegroundWindow")
+	procGetWindowThreadProcessID = user32.NewProc("GetWindowThreadProcessId")
+	procGetClassNameW            = user32.NewProc("GetClassNameW")
+)
+
+// Window classes of the taskbar and tray overflow; focused when the tray menu opens
+var shellTrayClasses = map[string]bool{
+	"Shell_TrayWnd":            true,
+	"Shell_SecondaryTrayWnd":   true,
+	"NotifyIconOverflowWindow": true,
+}
+
+// PID of the process owning the foreground window.
+// The taskbar doesn't count as an app the user is "using".
+func foregroundProcessID() (int32, error) {
+	hwnd, _, _ := procGetForegroundWindow.Call()
+	if hwnd == 0 {
+		return 0, errors.New("no foreground window")
+	}
+
+	class := make([]uint16, 256)
+	n, _, _ := procGetClassNameW.Call(hwnd, uintptr(unsafe.Pointer(&class[0])), uintptr(len(class)))
+	if n > 0 && shellTrayClasses[syscall.UTF16ToString(class[:n])] {
+		return 0, errors.New("foreground window is the taskbar")
+	}
+
+	var pid uint32
+	procGetWindowThreadProcessID.Call(hwnd, uintptr(unsafe.Pointer(&pid)))
+	if pid == 0 {
+		return 0, errors.New("could not get the foreground window's process")
+	}
+	return int32(pid), nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+
+	"fyne.io/fyne/v2"
+)
+
+const formStatePrefKey = "formState"
+
+// Default window size, used until a size has been saved
+var defaultWindowSize = fyne.NewSize(600, 480)
+
+// Window size and Limit tab values saved on exit and restored at launch
+type formState struct {
+	Width   float32 `json:"width,omitempty"`
+	Height  float32 `json:"height,omitempty"`
+	Process string  `json:"process,omitempty"`
+	In      string  `json:"in,omitempty"` // as typed, so "50%" survives
+	InUnit  string  `json:"inUnit,omitempty"`
+	Out     string  `json:"out,omitempty"`
+	OutUnit string  `json:"outUnit,omitempty"`
+}
+
+// Saved state; the zero value when none was saved or it can't be read
+func loadFormState(prefs fyne.Preferences) formState {
+	var s formState
+	if raw := prefs.String(formStatePrefKey); raw != "" {
+		if json.Unmarshal([]byte(raw), &s) != nil {
+			return formState{}
+		}
+	}
+	return s
+}
+
+func saveFormState(prefs fyne.Preferences, s formState) {
+	data, _ := json.Marshal(s)
+	prefs.SetString(formStatePrefKey, string(data))
+}
+
+// Saved window size, or the default when none was saved
+func (s formState) windowSize() fyne.Size {
+	if s.Width <= 0 || s.Height <= 0 {
+		return defaultWindowSize
+	}
+	return fyne.NewSize(s.Width, s.Height)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+const (
+	hookURLPrefKey     = "hookURL"
+	hookCommandPrefKey = "hookCommand"
+	hookEventsPrefKey  = "hookEvents" // comma-separated; empty means all
+
+	// A slow webhook or script is abandoned after this long
+	hookTimeout = 10 * time.Second
+)
+
+// Events hooks can fire on
+const (
+	hookRuleApplied = "rule.applied"
+	hookRuleFailed  = "rule.failed"
+	hookRuleCleared = "rule.cleared"
+	hookRuleBoosted = "rule.boosted"
+	hookDataCapHit  = "datacap.hit"
+)
+
+var hookEventNames = []string{hookRuleApplied, hookRuleFailed, hookRuleCleared, hookRuleBoosted, hookDataCapHit}
+
+// JSON body POSTed to the webhook and piped to the command's stdin
+type hookEvent struct {
+	Event   string         `json:"event"`
+	Time    time.Time      `json:"time"`
+	Action  string         `json:"action"`
+	Process string         `json:"process,omitempty"`
+	Target  string         `json:"target,omitempty"`
+	Params  map[string]any `json:"params,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// Hook settings, read from preferences at startup and when Settings is saved.
+// Package-level like the audit log, which is where events come from.
+type hookConfig struct {
+	mu      sync.Mutex
+	url     string
+	command string
+	events  []string
+}
+
+var hooks = &hookConfig{}
+
+// Load hook settings from preferences
+func (h *hookConfig) configure(prefs fyne.Preferences) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.url = strings.TrimSpace(prefs.String(hookURLPrefKey))
+	h.command = strings.TrimSpace(prefs.String(hookCommandPrefKey))
+	h.events = parseHookEvents(prefs.String(hookEventsPrefKey))
+}
+
+// Enabled events from the stored list; empty means all
+func parseHookEvents(raw string) []string {
+	var events []string
+	for _, e := range strings.Split(raw, ",") {
+		if e = strings.TrimSpace(e); slices.Contains(hookEventNames, e) {
+			events = append(events, e)
+		}
+	}
+	if len(events) == 0 {
+		return hookEventNames
+	}
+	return events
+}
+
+// Event name for an audited operation
+func hookEventFor(action string, params map[string]any, opErr error) string {
+	switch {
+	case opErr != nil:
+		return hookRuleFailed
+	case action == "block" && params["trigger"] == "data cap":
+		return hookDataCapHit
+	case action == "clear":
+		return hookRuleCleared
+	case action == "boost":
+		return hookRuleBoosted
+	}
+	return hookRuleApplied
+}
+
+// Fire the configured hooks for an audited operation without blocking the caller.
+// Outcomes are reported through log.
+func (h *hookConfig) fire(log func(string), action, process, target string, params map[string]any, opErr error) {
+	h.mu.Lock()
+	url, command, events := h.url, h.command, h.events
+	h.mu.Unlock()
+	if (url == "" && command == "") || dryRun.Load() {
+		return
+	}
+
+	e := hookEvent{Event: hookEventFor(action, params, opErr), Time: time.Now().UTC(), Action: action, Process: process, Target: target, Params: params}
+	if !slices.Contains(events, e.Event) {
+		return
+	}
+	if opErr != nil {
+		e.Error = opErr.Error()
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	if url != "" {
+		go func() {
+			if err := postHook(url, body); err != nil {
+				log("Webhook error (" + e.Event + "): " + err.Error())
+			}
+		}()
+	}
+	if command != "" {
+		go func() {
+			if err := runHookCommand(command, e, body); err != nil {
+				log("Hook command error (" + e.Event + "): " + err.Error())
+			}
+		}()
+	}
+}
+
+// POST body to url, failing on timeout or a non-2xx status
+func postHook(url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Run command through the shell with the event as JSON on stdin and in
+// NETLIMITER_* environment variables
+func runHookCommand(command string, e hookEvent, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"NETLIMITER_EVENT="+e.Event,
+		"NETLIMITER_ACTION="+e.Action,
+		"NETLIMITER_PROCESS="+e.Process,
+		"NETLIMITER_TARGET="+e.Target,
+		"NETLIMITER_ERROR="+e.Error,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("timed out after %v", hookTimeout)
+		}
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
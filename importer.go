@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+
+	"netlimiter/netlimiter"
+)
+
+// Field names accepted from other tools' process -> limits exports
+var (
+	importInKeys    = []string{"in", "download", "downloadkbps", "inkbps"}
+	importOutKeys   = []string{"out", "upload", "uploadkbps", "outkbps"}
+	importBlockKeys = []string{"block", "blocked"}
+)
+
+// Rules read from an import file, plus what couldn't be represented
+type importResult struct {
+	Rules   []LimitRule
+	Skipped []string
+}
+
+// Parse a JSON mapping of process name to limits in kbps, e.g.
+//
+//	{"chrome.exe": {"download": 500, "upload": 200}, "game.exe": {"block": true}}
+//
+// Field names are case-insensitive. Unknown fields (schedules, priorities,
+// remote addresses...) are skipped and reported rather than failing the import.
+func parseImport(r io.Reader) (importResult, error) {
+	var raw map[string]map[string]any
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return importResult{}, fmt.Errorf("not a process -> limits JSON mapping: %w", err)
+	}
+
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var res importResult
+	for _, name := range names {
+		process, err := sanitizeTarget(name, matchModeExact)
+		if err != nil {
+			res.Skipped = append(res.Skipped, fmt.Sprintf("%q: %v", name, err))
+			continue
+		}
+		r := LimitRule{Process: process}
+		valid := true
+		for field, value := range raw[name] {
+			key := strings.ToLower(field)
+			switch {
+			case slices.Contains(importInKeys, key), slices.Contains(importOutKeys, key):
+				kbps, ok := value.(float64)
+				if !ok || kbps < 0 || kbps > netlimiter.MaxLimitKbps || kbps != float64(int(kbps)) {
+					res.Skipped = append(res.Skipped, fmt.Sprintf("%s: %s is not a whole number of kbps up to 100 Gbps", process, field))
+					valid = false
+					continue
+				}
+				if slices.Contains(importInKeys, key) {
+					r.InKbps = int(kbps)
+				} else {
+					r.OutKbps = int(kbps)
+				}
+			case slices.Contains(importBlockKeys, key):
+				b, ok := value.(bool)
+				if !ok {
+					res.Skipped = append(res.Skipped, fmt.Sprintf("%s: %s is not true/false", process, field))
+					continue
+				}
+				r.Blocked = b
+			default:
+				res.Skipped = append(res.Skipped, fmt.Sprintf("%s: unsupported field %q ignored", process, field))
+			}
+		}
+		if !valid {
+			continue
+		}
+		if !r.Blocked && r.InKbps == 0 && r.OutKbps == 0 {
+			res.Skipped = append(res.Skipped, process+": no limit or block given")
+			continue
+		}
+		if r.Blocked {
+			r.InKbps, r.OutKbps = 0, 0
+		}
+		res.Rules = append(res.Rules, r)
+	}
+	return res, nil
+}
+
+// Apply imported rules one by one. Processes that aren't running are queued
+// as pending, to be applied from the Limit tab once they are.
+func applyImportedRules(rules []LimitRule, log func(string)) {
+	for _, r := range rules {
+		r.Note = "imported"
+		pids, err := processes.FindPIDsByName(r.Process)
+		if err == nil && len(pids) > 0 {
+			r.ExePath, err = processes.ExePath(pids[0])
+		}
+		if err != nil || len(pids) == 0 {
+			log("Queued (not running): " + r.describe())
+			if err := tracked.markPending(r); err != nil {
+				log("Could not save tracked rules: " + err.Error())
+			}
+			continue
+		}
+
+		if prev, ok := tracked.get(r.key()); ok {
+			if clearLog, err := clearRule(prev).result(); err != nil {
+				log(clearLog)
+				log("Clear error: " + err.Error())
+			}
+		}
+		applyLog, err := applyRule(r).result()
+		log("Applying " + r.describe())
+		log(applyLog)
+		if err != nil {
+			log("Apply error: " + err.Error())
+		}
+		if saveErr := tracked.recordResult(r, err); saveErr != nil {
+			log("Could not save tracked rules: " + saveErr.Error())
+		}
+		auditOrLog(log, "import", r.Process, r.ExePath, map[string]any{"inKbps": r.InKbps, "outKbps": r.OutKbps, "blocked": r.Blocked}, err)
+	}
+}
+
+// Pick an import file, show what will be imported and skipped, then apply
+func showImportRules(window fyne.Window, log func(string)) {
+	dialog.ShowFileOpen(func(rc fyne.URIReadCloser, err error) {
+		if err != nil || rc == nil {
+			return
+		}
+		defer rc.Close()
+
+		res, err := parseImport(rc)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if len(res.Rules) == 0 {
+			dialog.ShowInformation("Import rules", "Nothing to import.\n\n"+strings.Join(res.Skipped, "\n"), window)
+			return
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "%d rule(s) will be applied or queued:\n", len(res.Rules))
+		for _, r := range res.Rules {
+			fmt.Fprintf(&b, "- %s\n", r.describe())
+		}
+		if len(res.Skipped) > 0 {
+			fmt.Fprintf(&b, "\nSkipped:\n- %s\n", strings.Join(res.Skipped, "\n- "))
+		}
+		dialog.ShowConfirm("Import rules", b.String(), func(ok bool) {
+			if !ok {
+				return
+			}
+			go func() {
+				log("----------------------------------------------------")
+				log(fmt.Sprintf("Importing %d rule(s) from %s", len(res.Rules), rc.URI().Name()))
+				for _, s := range res.Skipped {
+					log("Import skipped: " + s)
+				}
+				applyImportedRules(res.Rules, log)
+			}()
+		}, window)
+	}, window)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Backend that creates and removes the QoS policies / firewall rules
+type Limiter interface {
+	Name() string
+	ApplyLimit(key, exePath string, inKbps, outKbps int) *OpResult
+	BlockInternet(key, exePath string, scope blockScope) *OpResult
+	ApplyPriority(key, exePath string, priority qosPriority, inKbps, outKbps int) *OpResult
+	ApplyLimitForPackage(pfn string, inKbps, outKbps int) *OpResult
+	BlockInternetForPackage(pfn string) *OpResult
+	ClearTarget(key string) *OpResult
+	// Clear keys (or everything) and apply r, in one step where the backend
+	// can; res.Err is the apply error
+	ApplyReplacing(keys []string, clearAll bool, r LimitRule) (res *OpResult, clearErr error)
+	ClearAll() *OpResult
+	// Block all outbound traffic except for the given executables; ClearAll undoes it
+	ApplyAllowlist(paths []string) *OpResult
+	LiveRules() ([]livePolicy, error)
+	// QoS policies and blocking firewall rules of other tools (not carrying
+	// this tool's names) that target any of the executables
+	ForeignRules(exePaths []string) ([]livePolicy, error)
+	// Remove one live QoS policy or firewall rule, e.g. one left behind
+	RemoveLive(p livePolicy) *OpResult
+	CheckServices() (serviceReport, error)
+	StartService(name string) (string, error)
+	SelfTest() (selfTestResult, error)
+}
+
+// Source of running processes used to resolve a target
+type ProcessSource interface {
+	FindPIDsByName(name string) ([]int32, error)
+	FindByRegex(re *regexp.Regexp) ([]processMatch, error)
+	ExePath(pid int32) (string, error)
+	IOBytes(pid int32) (uint64, error)
+	IOCounters(pid int32) (read, write uint64, err error)
+	CommandLine(pid int32) (cmdline, cwd string, err error)
+	StartTime(pid int32) (time.Time, error)
+	// Direct children of the process; none is not an error
+	Children(pid int32) ([]int32, error)
+	ListProcesses() ([]runningProcess, error)
+}
+
+// Active backends; the mock build tag swaps these for in-memory fakes
+var (
+	backend   Limiter       = newPlatformLimiter()
+	processes ProcessSource = gopsutilSource{}
+)
+
+// Whether the active backend needs administrator rights; the mock doesn't
+var backendNeedsElevation = runtime.GOOS == "windows"
+
+// Whether QoS/firewall changes would fail for lack of elevation.
+// A dry run changes nothing, so it never needs it.
+func elevationMissing() bool {
+	return backendNeedsElevation && !dryRun.Load() && !isElevated()
+}
+
+// Returned by Windows-only operations on other platforms (development builds)
+var errUnsupportedPlatform = fmt.Errorf("%w on this platform (%s)", errors.ErrUnsupported, runtime.GOOS)
+
+// Process source backed by gopsutil
+type gopsutilSource struct{}
+
+func (gopsutilSource) FindPIDsByName(name string) ([]int32, error) {
+	return findPIDsByName(name)
+}
+
+func (gopsutilSource) FindByRegex(re *regexp.Regexp) ([]processMatch, error) {
+	return findProcessesByRegex(re)
+}
+
+func (gopsutilSource) ExePath(pid int32) (string, error) {
+	return resolveExePathWithFallback(pid)
+}
+
+// Total bytes read and written by the process so far
+func (gopsutilSource) IOBytes(pid int32) (uint64, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return 0, fmt.Errorf("error reading process info: %w", err)
+	}
+	io, err := p.IOCounters()
+	if err != nil {
+		return 0, err
+	}
+	return io.ReadBytes + io.WriteBytes, nil
+}
+
+// Bytes read and written by the process so far, separately
+func (gopsutilSource) IOCounters(pid int32) (uint64, uint64, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error reading process info: %w", err)
+	}
+	io, err := p.IOCounters()
+	if err != nil {
+		return 0, 0, err
+	}
+	return io.ReadBytes, io.WriteBytes, nil
+}
+
+// Command line and working directory of the process; a missing working
+// directory (access denied for other users' processes) is not an error
+func (gopsutilSource) CommandLine(pid int32) (string, string, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return "", "", fmt.Errorf("error reading process info: %w", err)
+	}
+	cmdline, err := p.Cmdline()
+	if err != nil {
+		return "", "", err
+	}
+	cwd, _ := p.Cwd()
+	return cmdline, cwd, nil
+}
+
+// When the process was started
+func (gopsutilSource) StartTime(pid int32) (time.Time, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error reading process info: %w", err)
+	}
+	ms, err := p.CreateTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
+
+func (gopsutilSource) Children(pid int32) ([]int32, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return nil, fmt.Errorf("error reading process info: %w", err)
+	}
+	children, err := p.Children()
+	if errors.Is(err, process.ErrorNoChildren) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	pids := make([]int32, len(children))
+	for i, c := range children {
+		pids[i] = c.Pid
+	}
+	return pids, nil
+}
+
+func (gopsutilSource) ListProcesses() ([]runningProcess, error) {
+	return listRunningProcesses()
+}
@@ -0,0 +1,340 @@
+//go:build mock
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"netlimiter/netlimiter"
+)
+
+// Build with -tags mock to exercise the GUI without admin rights:
+// policies are only kept in memory, processes are canned, and tracked
+// rules, the audit log and the file log go to a temporary directory
+// instead of the real app data.
+var mockStart = time.Now()
+
+func init() {
+	backend = newMockLimiter()
+	backendNeedsElevation = false
+	processes = mockProcessSource{}
+	appDataBase = mockAppDataBase
+}
+
+// Temporary app data base, created on first use and shared for the run
+var mockAppDataBase = sync.OnceValues(func() (string, error) {
+	return os.MkdirTemp("", "net-limiter-mock-")
+})
+
+// Rule recorded by the mock backend
+type mockRule struct {
+	target  string // exe path or "package:<pfn>"
+	blocked bool
+	inKbps  int
+	outKbps int
+}
+
+// In-memory Limiter that never touches QoS or the firewall
+type mockLimiter struct {
+	mu        sync.Mutex
+	rules     map[string]mockRule // by policy key
+	allowlist []string            // allowed paths while allowlist mode is on
+}
+
+func newMockLimiter() *mockLimiter {
+	return &mockLimiter{rules: make(map[string]mockRule)}
+}
+
+func (m *mockLimiter) Name() string { return "Mock (in-memory)" }
+
+func (m *mockLimiter) set(res *OpResult, key string, r mockRule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules[key] = r
+	res.step("[mock] %d rule(s) tracked", len(m.rules))
+}
+
+func (m *mockLimiter) ApplyLimit(key, exePath string, inKbps, outKbps int) *OpResult {
+	res := &OpResult{}
+	if inKbps <= 0 && outKbps <= 0 {
+		res.Err = fmt.Errorf("limit must be > 0 to use QoS")
+		return res
+	}
+	if err := checkInboundLimit(inKbps, outKbps); err != nil {
+		res.Err = err
+		return res
+	}
+	res.step("[mock] Applying speed limit for: %s (in %d / out %d kbps)", exePath, inKbps, outKbps)
+	m.set(res, key, mockRule{target: exePath, inKbps: inKbps, outKbps: outKbps})
+	res.succeed("ApplyLimit: success")
+	return res
+}
+
+func (m *mockLimiter) BlockInternet(key, exePath string, scope blockScope) *OpResult {
+	res := &OpResult{}
+	if res.Err = scope.validate(); res.Err != nil {
+		return res
+	}
+	res.step("[mock] Blocking internet for: %s%s", exePath, scope.describe())
+	m.set(res, key, mockRule{target: exePath, blocked: true})
+	res.succeed("BlockInternet: success")
+	return res
+}
+
+func (m *mockLimiter) ApplyPriority(key, exePath string, priority qosPriority, inKbps, outKbps int) *OpResult {
+	res := &OpResult{}
+	if err := checkInboundLimit(inKbps, outKbps); err != nil {
+		res.Err = err
+		return res
+	}
+	res.step("[mock] Applying %s priority for: %s (in %d / out %d kbps)", priority, exePath, inKbps, outKbps)
+	m.set(res, key, mockRule{target: exePath, inKbps: inKbps, outKbps: outKbps})
+	res.succeed("ApplyPriority: success")
+	return res
+}
+
+func (m *mockLimiter) ApplyLimitForPackage(pfn string, inKbps, outKbps int) *OpResult {
+	if err := validatePackageFamilyName(pfn); err != nil {
+		return &OpResult{Err: err}
+	}
+	return m.ApplyLimit(packagePolicyKey(pfn), "package:"+pfn, inKbps, outKbps)
+}
+
+func (m *mockLimiter) BlockInternetForPackage(pfn string) *OpResult {
+	if err := validatePackageFamilyName(pfn); err != nil {
+		return &OpResult{Err: err}
+	}
+	return m.BlockInternet(packagePolicyKey(pfn), "package:"+pfn, blockScope{})
+}
+
+func (m *mockLimiter) ClearTarget(key string) *OpResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := &OpResult{}
+	res.step("[mock] Clearing rules for: %s", key)
+	if _, ok := m.rules[key]; !ok {
+		res.step("No existing policy/rules found")
+	}
+	delete(m.rules, key)
+	res.succeed("ClearTarget: success")
+	return res
+}
+
+// The mock has no process start to save, so it clears and applies in turn
+func (m *mockLimiter) ApplyReplacing(keys []string, clearAll bool, r LimitRule) (*OpResult, error) {
+	res := &OpResult{}
+	var clearErr error
+	if clearAll {
+		cleared := m.ClearAll()
+		res.merge(cleared)
+		clearErr = cleared.Err
+	}
+	for _, key := range keys {
+		res.merge(m.ClearTarget(key))
+	}
+	applied := applyRule(r)
+	res.merge(applied).Err = applied.Err
+	return res, clearErr
+}
+
+func (m *mockLimiter) ClearAll() *OpResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := &OpResult{}
+	res.step("[mock] Clearing %d rule(s)", len(m.rules))
+	if len(m.rules) == 0 {
+		res.step("No existing policy/rules found")
+	}
+	m.rules = make(map[string]mockRule)
+	if m.allowlist != nil {
+		res.step("Allowlist mode off: default outbound actions restored")
+		m.allowlist = nil
+	}
+	res.succeed("ClearAllLimits: success")
+	return res
+}
+
+func (m *mockLimiter) ApplyAllowlist(paths []string) *OpResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.allowlist = append([]string{}, paths...)
+	res := &OpResult{}
+	res.step("[mock] Outbound traffic is now blocked except for %d program(s) and DNS", len(paths))
+	res.succeed("ApplyAllowlist: success")
+	return res
+}
+
+// Live state is the in-memory rule map
+func (m *mockLimiter) LiveRules() ([]livePolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var live []livePolicy
+	for key, r := range m.rules {
+		p := livePolicy{Kind: "qos", Name: netlimiter.PolicyName(netlimiter.QoSPolicyOut, key), AppPath: r.target}
+		if r.blocked {
+			p = livePolicy{Kind: "firewall", Name: netlimiter.PolicyName(netlimiter.FirewallRuleOut, key), AppPath: r.target}
+		}
+		if pfn, ok := strings.CutPrefix(r.target, "package:"); ok {
+			p.AppPath, p.Package = "", pfn
+		} else {
+			p.BitsPerSecond = convertToBitsPerSecond(r.outKbps, unitKbps)
+		}
+		live = append(live, p)
+	}
+	return live, nil
+}
+
+// No other tools run against the mock
+func (m *mockLimiter) ForeignRules(exePaths []string) ([]livePolicy, error) {
+	return nil, nil
+}
+
+// Remove the rule whose policy name p carries
+func (m *mockLimiter) RemoveLive(p livePolicy) *OpResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := &OpResult{}
+	res.step("[mock] Removing %s %s", p.Kind, p.Name)
+	for key, r := range m.rules {
+		name := netlimiter.PolicyName(netlimiter.QoSPolicyOut, key)
+		if r.blocked {
+			name = netlimiter.PolicyName(netlimiter.FirewallRuleOut, key)
+		}
+		if name == p.Name {
+			delete(m.rules, key)
+			res.succeed("RemoveLive: success")
+			return res
+		}
+	}
+	res.Err = fmt.Errorf("no mock policy named %s", p.Name)
+	return res
+}
+
+// The mock backend needs no services
+func (m *mockLimiter) CheckServices() (serviceReport, error) {
+	return serviceReport{
+		Services: []serviceState{
+			{Name: serviceBFE, DisplayName: "Base Filtering Engine", Status: "Running"},
+			{Name: serviceFirewall, DisplayName: "Windows Defender Firewall", Status: "Running"},
+		},
+		PacketScheduler: true,
+		QosCmdlets:      true,
+	}, nil
+}
+
+func (m *mockLimiter) StartService(name string) (string, error) {
+	return "[mock] Starting service: " + name + "\n", nil
+}
+
+// The in-memory backend can always create and remove rules
+func (m *mockLimiter) SelfTest() (selfTestResult, error) {
+	return selfTestResult{Elevated: true}, nil
+}
+
+// Canned processes: lower-case name -> PID -> executable path
+var mockProcessTable = map[string]map[int32]string{
+	"chrome.exe": {
+		1001: `C:\Program Files\Google\Chrome\Application\chrome.exe`,
+		1002: `C:\Program Files\Google\Chrome\Application\chrome.exe`,
+		1003: `C:\Program Files\Google\Chrome Beta\Application\chrome.exe`,
+	},
+	"firefox.exe":  {2001: `C:\Program Files\Mozilla Firefox\firefox.exe`},
+	"spotify.exe":  {3001: `C:\Users\demo\AppData\Roaming\Spotify\Spotify.exe`},
+	"node.exe":     {4001: `C:\Program Files\nodejs\node.exe`},
+	"steam.exe":    {5001: `C:\Program Files (x86)\Steam\steam.exe`},
+	"game.exe":     {5002: `C:\Games\Demo\game.exe`},
+	"crashpad.exe": {5003: `C:\Games\Demo\crashpad.exe`},
+}
+
+// Parent PID to child PIDs: a launcher starting a game, which starts a
+// crash reporter, and Chrome's helper processes
+var mockProcessChildren = map[int32][]int32{
+	1001: {1002},
+	5001: {5002},
+	5002: {5003},
+}
+
+// Process source returning the canned processes above
+type mockProcessSource struct{}
+
+func (mockProcessSource) FindPIDsByName(name string) ([]int32, error) {
+	var pids []int32
+	for procName, byPID := range mockProcessTable {
+		if !netlimiter.MatchProcessName(name, procName) {
+			continue
+		}
+		for pid := range byPID {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+	return pids, nil
+}
+
+func (mockProcessSource) FindByRegex(re *regexp.Regexp) ([]processMatch, error) {
+	var matches []processMatch
+	for name, byPID := range mockProcessTable {
+		for pid, exe := range byPID {
+			if re.MatchString(name) || re.MatchString(exe) {
+				matches = append(matches, processMatch{PID: pid, Name: name, ExePath: exe})
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].PID < matches[j].PID })
+	return matches, nil
+}
+
+func (mockProcessSource) ExePath(pid int32) (string, error) {
+	for _, byPID := range mockProcessTable {
+		if exe, ok := byPID[pid]; ok {
+			return exe, nil
+		}
+	}
+	return "", fmt.Errorf("no mock process with PID %d", pid)
+}
+
+// Grows steadily with time, faster for higher PIDs
+func (mockProcessSource) IOBytes(pid int32) (uint64, error) {
+	return uint64(time.Since(mockStart).Seconds()) * uint64(pid) * 64, nil
+}
+
+// Three quarters of the mock I/O is reads
+func (m mockProcessSource) IOCounters(pid int32) (uint64, uint64, error) {
+	total, _ := m.IOBytes(pid)
+	return total / 4 * 3, total / 4, nil
+}
+
+// The executable path quoted, started from its own folder
+func (m mockProcessSource) CommandLine(pid int32) (string, string, error) {
+	exe, err := m.ExePath(pid)
+	if err != nil {
+		return "", "", err
+	}
+	return `"` + exe + `"`, exe[:strings.LastIndex(exe, `\`)], nil
+}
+
+// Every canned process, using 40 MB of memory per thousand of its PID
+func (mockProcessSource) ListProcesses() ([]runningProcess, error) {
+	var procs []runningProcess
+	for name, byPID := range mockProcessTable {
+		for pid, exe := range byPID {
+			procs = append(procs, runningProcess{PID: pid, Name: name, ExePath: exe, Memory: uint64(pid/1000) * 40_000_000})
+		}
+	}
+	return procs, nil
+}
+
+func (mockProcessSource) Children(pid int32) ([]int32, error) {
+	return mockProcessChildren[pid], nil
+}
+
+// Higher PIDs started later
+func (mockProcessSource) StartTime(pid int32) (time.Time, error) {
+	return mockStart.Add(-time.Hour + time.Duration(pid)*time.Second), nil
+}
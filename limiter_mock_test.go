@@ -0,0 +1,61 @@
+//go:build mock
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A mock apply keeps its rules, audit entries and log out of the real app data
+func TestMockApplyLeavesRealAppDataAlone(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	t.Setenv("APPDATA", config)
+	t.Setenv("HOME", config)
+	realBase, err := os.UserConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	realDir := filepath.Join(realBase, "net-limiter")
+	if err := os.MkdirAll(realDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	realRules := filepath.Join(realDir, rulesFileName)
+	const saved = `[{"process":"saved.exe","inKbps":0,"outKbps":100,"appliedAt":"2026-01-02T03:04:05Z"}]`
+	if err := os.WriteFile(realRules, []byte(saved), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	savedTracked, savedAudit := tracked, audit
+	tracked, audit = &ruleStore{}, &auditLog{}
+	t.Cleanup(func() { tracked, audit = savedTracked, savedAudit })
+
+	if err := tracked.load(); err != nil {
+		t.Fatal(err)
+	}
+	r := LimitRule{Process: "chrome.exe", ExePath: `C:\Program Files\Google\Chrome\Application\chrome.exe`, OutKbps: 500}
+	_, applyErr := applyRule(r).result()
+	if applyErr != nil {
+		t.Fatalf("mock apply: %v", applyErr)
+	}
+	if err := tracked.recordResult(r, applyErr); err != nil {
+		t.Fatal(err)
+	}
+	auditOrLog(func(string) {}, "limit", r.Process, r.ExePath, nil, applyErr)
+
+	if data, err := os.ReadFile(realRules); err != nil || string(data) != saved {
+		t.Errorf("real %s = %q, %v; want it untouched", rulesFileName, data, err)
+	}
+	entries, err := os.ReadDir(realDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("real app data holds %d entries, want only %s", len(entries), rulesFileName)
+	}
+	if len(tracked.list()) != 1 {
+		t.Errorf("mock store tracks %d rules, want the applied one only", len(tracked.list()))
+	}
+}
@@ -0,0 +1,75 @@
+//go:build !windows
+
+package main
+
+import "runtime"
+
+// QoS and the firewall are Windows-only; elsewhere the GUI still launches for UI work
+func newPlatformLimiter() Limiter {
+	return unsupportedLimiter{}
+}
+
+// Limiter for non-Windows builds: every operation fails with errUnsupportedPlatform
+type unsupportedLimiter struct{}
+
+func (unsupportedLimiter) Name() string { return "Unsupported (" + runtime.GOOS + ")" }
+
+func (unsupportedLimiter) ApplyLimit(key, exePath string, inKbps, outKbps int) *OpResult {
+	return &OpResult{Err: errUnsupportedPlatform}
+}
+
+func (unsupportedLimiter) BlockInternet(key, exePath string, scope blockScope) *OpResult {
+	return &OpResult{Err: errUnsupportedPlatform}
+}
+
+func (unsupportedLimiter) ApplyPriority(key, exePath string, priority qosPriority, inKbps, outKbps int) *OpResult {
+	return &OpResult{Err: errUnsupportedPlatform}
+}
+
+func (unsupportedLimiter) ApplyLimitForPackage(pfn string, inKbps, outKbps int) *OpResult {
+	return &OpResult{Err: errUnsupportedPlatform}
+}
+
+func (unsupportedLimiter) BlockInternetForPackage(pfn string) *OpResult {
+	return &OpResult{Err: errUnsupportedPlatform}
+}
+
+func (unsupportedLimiter) ClearTarget(key string) *OpResult {
+	return &OpResult{Err: errUnsupportedPlatform}
+}
+
+func (unsupportedLimiter) ApplyReplacing(keys []string, clearAll bool, r LimitRule) (*OpResult, error) {
+	return &OpResult{Err: errUnsupportedPlatform}, errUnsupportedPlatform
+}
+
+func (unsupportedLimiter) ClearAll() *OpResult {
+	return &OpResult{Err: errUnsupportedPlatform}
+}
+
+func (unsupportedLimiter) ApplyAllowlist(paths []string) *OpResult {
+	return &OpResult{Err: errUnsupportedPlatform}
+}
+
+func (unsupportedLimiter) LiveRules() ([]livePolicy, error) {
+	return nil, errUnsupportedPlatform
+}
+
+func (unsupportedLimiter) ForeignRules(exePaths []string) ([]livePolicy, error) {
+	return nil, errUnsupportedPlatform
+}
+
+func (unsupportedLimiter) RemoveLive(p livePolicy) *OpResult {
+	return &OpResult{Err: errUnsupportedPlatform}
+}
+
+func (unsupportedLimiter) CheckServices() (serviceReport, error) {
+	return serviceReport{}, errUnsupportedPlatform
+}
+
+func (unsupportedLimiter) StartService(name string) (string, error) {
+	return "", errUnsupportedPlatform
+}
+
+func (unsupportedLimiter) SelfTest() (selfTestResult, error) {
+	return selfTestResult{}, errUnsupportedPlatform
+}
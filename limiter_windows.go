@@ -0,0 +1,71 @@
+package main
+
+// Windows backend for the running platform
+func newPlatformLimiter() Limiter {
+	return psLimiter{}
+}
+
+// Limiter that drives Windows QoS and Firewall through PowerShell
+type psLimiter struct{}
+
+func (psLimiter) Name() string { return "PowerShell" }
+
+func (psLimiter) ApplyLimit(key, exePath string, inKbps, outKbps int) *OpResult {
+	return applyQosLimit(key, exePath, inKbps, outKbps)
+}
+
+func (psLimiter) BlockInternet(key, exePath string, scope blockScope) *OpResult {
+	return blockInternetForProcess(key, exePath, scope)
+}
+
+func (psLimiter) ApplyPriority(key, exePath string, priority qosPriority, inKbps, outKbps int) *OpResult {
+	return applyPriorityForExe(key, exePath, priority, inKbps, outKbps)
+}
+
+func (psLimiter) ApplyLimitForPackage(pfn string, inKbps, outKbps int) *OpResult {
+	return applyLimitForPackage(pfn, inKbps, outKbps)
+}
+
+func (psLimiter) BlockInternetForPackage(pfn string) *OpResult {
+	return blockInternetForPackage(pfn)
+}
+
+func (psLimiter) ClearTarget(key string) *OpResult {
+	return clearLimitsForTarget(key)
+}
+
+func (psLimiter) ApplyReplacing(keys []string, clearAll bool, r LimitRule) (*OpResult, error) {
+	return applyReplacing(keys, clearAll, r)
+}
+
+func (psLimiter) ClearAll() *OpResult {
+	return clearAllLimits()
+}
+
+func (psLimiter) ApplyAllowlist(paths []string) *OpResult {
+	return applyAllowlist(paths)
+}
+
+func (psLimiter) LiveRules() ([]livePolicy, error) {
+	return queryLiveRules()
+}
+
+func (psLimiter) ForeignRules(exePaths []string) ([]livePolicy, error) {
+	return queryForeignRules(exePaths)
+}
+
+func (psLimiter) RemoveLive(p livePolicy) *OpResult {
+	return removeLivePolicy(p)
+}
+
+func (psLimiter) CheckServices() (serviceReport, error) {
+	return checkServices()
+}
+
+func (psLimiter) StartService(name string) (string, error) {
+	return startWindowsService(name)
+}
+
+func (psLimiter) SelfTest() (selfTestResult, error) {
+	return runSelfTest()
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"netlimiter/netlimiter"
+)
+
+// Find all PIDs for a given process name (e.g. "chrome.exe") or wildcard
+// pattern (e.g. "chrome*"). Uses the short-lived process cache, so repeated
+// lookups are cheap.
+func findPIDsByName(target string) ([]int32, error) {
+	procs, err := procCache.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return netlimiter.MatchingPIDs(procs, target), nil
+}
+
+// Build the script that blocks internet for an executable path under target
+// key's rules: all of it, or only what scope narrows it to
+func blockScript(key, exePath string, scope blockScope) string {
+	return netlimiter.BlockScript(key, exePath, scope.firewallArgs())
+}
+
+// Plan blocking an executable, using netsh when the cmdlets are missing
+func blockPlan(key, exePath string, scope blockScope) *OpResult {
+	res := &OpResult{}
+	res.step("Blocking internet for: %s%s", exePath, scope.describe())
+	if err := scope.validate(); err != nil {
+		res.Err = err
+		return res
+	}
+	if isSystemPath(exePath) && !allowSystemBlocks.Load() {
+		res.Err = fmt.Errorf("%s is in the Windows system folder: %w", exePath, errSystemProgram)
+		return res
+	}
+	if !firewallCmdletsAvailable() {
+		res.warn("Firewall cmdlets unavailable, using netsh")
+		res.Script = netshBlockScript(key, exePath, scope)
+		return res
+	}
+	res.Script = blockScript(key, exePath, scope)
+	return res
+}
+
+// Block internet (inbound + outbound) for a given executable path, all of it or within scope
+func blockInternetForProcess(key, exePath string, scope blockScope) *OpResult {
+	res := blockPlan(key, exePath, scope)
+	if res.Err != nil {
+		return res
+	}
+	if !firewallCmdletsAvailable() {
+		res.run("Firewall output", "firewall error") // netsh
+	} else {
+		out, err := qosLimiter().Block(context.Background(), key, exePath, scope.firewallArgs())
+		res.record("Firewall output", "firewall error", out, err)
+	}
+	if res.Err == nil {
+		res.succeed("BlockInternet: success")
+	}
+	return res
+}
+
+// Build the script that removes every QoS policy and firewall rule used by this
+// tool and turns allowlist mode off
+func clearScript() string {
+	return scriptLimiter.ClearAllPrefix + netlimiter.ClearScript()
+}
+
+// Clear the QoS policy and firewall rules of one target, leaving other rules alone
+func clearLimitsForTarget(key string) *OpResult {
+	res := &OpResult{Script: netlimiter.ClearTargetScript(key)}
+	res.step("Clearing QoS policy and firewall rules for: %s", key)
+	if !firewallCmdletsAvailable() {
+		res.Script = netshClearTargetScript(key)
+		res.run("Output", "clear error")
+	} else {
+		out, err := qosLimiter().Clear(context.Background(), key)
+		res.record("Output", "clear error", out, err)
+	}
+	if res.Err == nil {
+		res.succeed("ClearTarget: success")
+	}
+	return res
+}
+
+// Clear QoS policy and firewall rules used by this tool
+func clearAllLimits() *OpResult {
+	res := &OpResult{Script: clearScript()}
+	res.step("Clearing QoS policy and firewall rules...")
+	if !firewallCmdletsAvailable() {
+		res.Script = netshClearScript()
+		res.run("Output", "clearAllLimits error")
+	} else {
+		out, err := qosLimiter().ClearAll(context.Background())
+		res.record("Output", "clearAllLimits error", out, err)
+	}
+	if res.Err == nil {
+		res.succeed("ClearAllLimits: success")
+	}
+	return res
+}
+
+// Windows QoS throttle actions only act on traffic the machine sends, so
+// only the OUT limit can be enforced. An IN limit on its own is refused
+// rather than enforced as an outbound one.
+func checkInboundLimit(inKbps, outKbps int) error {
+	if inKbps > 0 && outKbps <= 0 {
+		return fmt.Errorf("IN limit of %d kbps can't be enforced: Windows QoS only throttles outbound (upload) traffic; set an OUT limit instead", inKbps)
+	}
+	return nil
+}
+
+// Note in res that an IN limit set alongside the OUT one isn't enforced
+func noteInboundLimit(res *OpResult, inKbps int) {
+	if inKbps > 0 {
+		res.warn("Note: IN limit of %d kbps is not enforced; Windows QoS policies only throttle outbound traffic", inKbps)
+	}
+}
+
+// Check a QoS limit and plan its script under target key's policy
+func qosLimitPlan(key, exePath string, inKbps, outKbps int) *OpResult {
+	res := &OpResult{}
+	res.step("Applying speed limit for: %s", exePath)
+
+	if err := errors.Join(netlimiter.ValidateLimitKbps(inKbps), netlimiter.ValidateLimitKbps(outKbps), checkInboundLimit(inKbps, outKbps)); err != nil {
+		res.Err = err
+		return res
+	}
+	if !qosCmdletsAvailable() {
+		res.Err = errNoQosCmdlets()
+		return res
+	}
+	if outKbps <= 0 && inKbps <= 0 {
+		res.Err = fmt.Errorf("limit must be > 0 to use QoS")
+		return res
+	}
+
+	noteInboundLimit(res, inKbps)
+	bitsPerSecond := convertToBitsPerSecond(outKbps, unitKbps)
+	res.step("Requested OUT limit: %d kbps (~%d bits per second)", outKbps, bitsPerSecond)
+	res.Script = netlimiter.LimitScript(key, exePath, bitsPerSecond, qosStore())
+	return res
+}
+
+// Apply QoS throttling for an executable path under target key's policy
+func applyQosLimit(key, exePath string, inKbps, outKbps int) *OpResult {
+	res := qosLimitPlan(key, exePath, inKbps, outKbps)
+	if res.Err != nil {
+		return res
+	}
+	out, err := qosLimiter().Limit(context.Background(), key, exePath, outKbps)
+	if res.record("QoS output", "QoS error", out, err).Err == nil {
+		res.succeed("ApplyLimit: success")
+	}
+	return res
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+
+	"netlimiter/netlimiter"
+)
+
+// Adapter whose link speed percentage limits use; empty for the one carrying
+// the default route
+const percentAdapterPrefKey = "percentAdapter"
+
+// Connected network adapter and its link speed
+type netAdapter struct {
+	Name         string
+	Speed        int64 // bits per second
+	DefaultRoute bool  // carries the lowest-metric default route
+}
+
+// Connected adapters, the default route's first
+func listNetAdapters() ([]netAdapter, error) {
+	script := `
+$route = Get-NetRoute -DestinationPrefix 0.0.0.0/0, ::/0 -ErrorAction SilentlyContinue | Sort-Object RouteMetric | Select-Object -First 1
+$adapters = @(Get-NetAdapter -ErrorAction SilentlyContinue | Where-Object { $_.Status -eq "Up" } | ForEach-Object {
+  [pscustomobject]@{ Name = $_.Name; Speed = [int64]$_.Speed; DefaultRoute = [bool]($route -and $route.ifIndex -eq $_.ifIndex) }
+} | Sort-Object -Property @{ Expression = "DefaultRoute"; Descending = $true }, Name)
+ConvertTo-Json -InputObject $adapters -Compress
+`
+	var adapters []netAdapter
+	if err := queryPowerShellJSON(script, &adapters); err != nil {
+		return nil, fmt.Errorf("network adapter query error: %w", err)
+	}
+	return adapters, nil
+}
+
+// Connected adapter named name, or the default route's when name is empty
+func findNetAdapter(name string) (netAdapter, error) {
+	adapters, err := listNetAdapters()
+	if err != nil {
+		return netAdapter{}, err
+	}
+	for _, a := range adapters {
+		if (name == "" && a.DefaultRoute) || (name != "" && strings.EqualFold(a.Name, name)) {
+			if a.Speed <= 0 {
+				return netAdapter{}, fmt.Errorf("adapter %s reports no link speed", a.Name)
+			}
+			return a, nil
+		}
+	}
+	if name != "" {
+		return netAdapter{}, fmt.Errorf("network adapter %q is not connected", name)
+	}
+	return netAdapter{}, errors.New("no active network adapter found to take a percentage of")
+}
+
+// Link speed of the adapter carrying the default route, in bits per second
+func getActiveLinkSpeedBps() (int64, error) {
+	a, err := findNetAdapter("")
+	if err != nil {
+		return 0, err
+	}
+	return a.Speed, nil
+}
+
+// Limit exePath's outbound traffic to percent of the active adapter's link
+// speed, through the same throttle path as an absolute limit
+func applyPercentLimit(exePath string, percent int) *OpResult {
+	if percent < 1 || percent > 100 {
+		return &OpResult{Err: fmt.Errorf("percentage must be from 1 to 100, got %d", percent)}
+	}
+	speedBps, err := getActiveLinkSpeedBps()
+	if err != nil {
+		return &OpResult{Err: err}
+	}
+	return applyPercentOfSpeed(exePath, percent, speedBps)
+}
+
+// Limit exePath to percent of speedBps, logging both the percentage and
+// the absolute rate it comes to
+func applyPercentOfSpeed(exePath string, percent int, speedBps int64) *OpResult {
+	capacity := int(speedBps / 1000)
+	kbps := percentOfKbps(percent, capacity)
+	res := &OpResult{}
+	res.step("OUT limit: %d%% of %d kbps (active link speed) = %d kbps", percent, capacity, kbps)
+	limited := backend.ApplyLimit(netlimiter.ExePolicyKey(exePath), exePath, 0, kbps)
+	res.merge(limited).Err = limited.Err
+	return res
+}
+
+// pct percent of an adapter's link speed (the default route's when adapter
+// is empty), with a note giving both the percentage and the absolute rate
+func percentOfAdapterKbps(pct int, adapter string) (kbps int, note string, err error) {
+	if pct < 1 || pct > 100 {
+		return 0, "", fmt.Errorf("percentage must be from 1 to 100, got %d", pct)
+	}
+	a, err := findNetAdapter(adapter)
+	if err != nil {
+		return 0, "", err
+	}
+	capacity := int(a.Speed / 1000)
+	kbps = percentOfKbps(pct, capacity)
+	return kbps, fmt.Sprintf("%d%% of %d kbps (link speed of %s) = %d kbps", pct, capacity, a.Name, kbps), nil
+}
+
+// What a percentage limit is a percentage of: the link speed of the adapter
+// picked in the speed test panel, else the speed test's measurement in that
+// direction when there is one, else the link speed of the adapter carrying
+// the default route. up selects upload (OUT); the description says which was used.
+func linkCapacityKbps(prefs fyne.Preferences, up bool) (kbps int, source string, err error) {
+	adapter := prefs.String(percentAdapterPrefKey)
+	if adapter == "" {
+		if c, ok := loadLinkCapacity(prefs); ok {
+			kbps = c.DownKbps
+			if up {
+				kbps = c.UpKbps
+			}
+			if kbps > 0 {
+				return kbps, "measured link capacity (" + c.describe() + ")", nil
+			}
+		}
+	}
+	a, err := findNetAdapter(adapter)
+	if err != nil {
+		return 0, "", err
+	}
+	return int(a.Speed / 1000), "link speed of " + a.Name, nil
+}
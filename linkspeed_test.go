@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Backend applying limits through the PowerShell throttle path on any OS,
+// so captureScripts sees the script
+type throttleOnlyLimiter struct{ Limiter }
+
+func (throttleOnlyLimiter) ApplyLimit(key, exePath string, inKbps, outKbps int) *OpResult {
+	return applyQosLimit(key, exePath, inKbps, outKbps)
+}
+
+func useThrottleOnlyBackend(t *testing.T) {
+	saved := backend
+	backend = throttleOnlyLimiter{}
+	t.Cleanup(func() { backend = saved })
+}
+
+func TestApplyPercentLimitRejectsOutOfRange(t *testing.T) {
+	scripts := captureScripts(t)
+	for _, pct := range []int{-1, 0, 101} {
+		if res := applyPercentLimit(`C:\app.exe`, pct); res.Err == nil {
+			t.Errorf("applyPercentLimit(%d) accepted", pct)
+		}
+	}
+	if len(*scripts) != 0 {
+		t.Errorf("ran %d script(s) for invalid percentages", len(*scripts))
+	}
+}
+
+func TestApplyPercentOfSpeed(t *testing.T) {
+	tests := []struct {
+		percent  int
+		speedBps int64
+		wantBps  string
+		wantLog  string
+	}{
+		{50, 100_000_000, "-ThrottleRateActionBitsPerSecond 50000000 ", "50% of 100000 kbps (active link speed) = 50000 kbps"},
+		{100, 1_000_000_000, "-ThrottleRateActionBitsPerSecond 1000000000 ", "100% of 1000000 kbps (active link speed) = 1000000 kbps"},
+		{1, 54_000_000, "-ThrottleRateActionBitsPerSecond 540000 ", "1% of 54000 kbps (active link speed) = 540 kbps"},
+		{1, 10_000, "-ThrottleRateActionBitsPerSecond 1000 ", "1% of 10 kbps (active link speed) = 1 kbps"}, // never rounded down to 0
+	}
+	useThrottleOnlyBackend(t)
+	for _, tt := range tests {
+		scripts := captureScripts(t)
+		res := applyPercentOfSpeed(`C:\Games\game.exe`, tt.percent, tt.speedBps)
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		if script := onlyScript(t, *scripts); !strings.Contains(script, tt.wantBps) {
+			t.Errorf("%d%% of %d bps: script lacks %q:\n%s", tt.percent, tt.speedBps, tt.wantBps, script)
+		}
+		if log := res.Log(); !strings.Contains(log, tt.wantLog) {
+			t.Errorf("%d%% of %d bps: log lacks %q:\n%s", tt.percent, tt.speedBps, tt.wantLog, log)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How often the live rate of the process in the form is sampled
+const liveRateInterval = time.Second
+
+// Current I/O rate of every instance of a process. Windows counts all
+// process I/O (disk included), so this is an upper bound on network traffic.
+type processRate struct {
+	PIDs    int
+	InKbps  int // reads
+	OutKbps int // writes
+}
+
+func (r processRate) String() string {
+	if r.PIDs == 0 {
+		return "not running"
+	}
+	return fmt.Sprintf("in %d / out %d kbps (%d process(es))", r.InKbps, r.OutKbps, r.PIDs)
+}
+
+// I/O totals of one PID at the previous sample
+type ioTotals struct {
+	read, write uint64
+}
+
+// Turns successive I/O counter samples of a process name into rates
+type rateSampler struct {
+	name string
+	at   time.Time
+	last map[int32]ioTotals
+}
+
+// Sample the named process; false while there is no earlier sample to compare with.
+// A new name only sets a baseline, as does a PID seen for the first time, and
+// PIDs that exited are dropped so nothing is kept for them.
+func (s *rateSampler) sample(src ProcessSource, name string, now time.Time) (processRate, bool) {
+	if !strings.EqualFold(name, s.name) {
+		s.name, s.last = name, nil
+	}
+	var pids []int32
+	if pid, ok := parsePID(name); ok {
+		pids = []int32{pid}
+	} else if found, err := src.FindPIDsByName(name); err == nil {
+		pids = found
+	}
+
+	cur := make(map[int32]ioTotals, len(pids))
+	var read, write uint64
+	for _, pid := range pids {
+		r, w, err := src.IOCounters(pid)
+		if err != nil {
+			continue
+		}
+		cur[pid] = ioTotals{read: r, write: w}
+		if prev, ok := s.last[pid]; ok && r >= prev.read && w >= prev.write {
+			read += r - prev.read
+			write += w - prev.write
+		}
+	}
+
+	hadBaseline, elapsed := s.last != nil, now.Sub(s.at)
+	s.last, s.at = cur, now
+	rate := processRate{PIDs: len(cur)}
+	if !hadBaseline {
+		return rate, false
+	}
+	rate.InKbps = throughputKbps(int64(read), elapsed)
+	rate.OutKbps = throughputKbps(int64(write), elapsed)
+	return rate, true
+}
+
+// Process name the live rate is shown for: the exact name or the file name
+// of a path; regex and package targets have no single process to sample
+func liveRateTarget(text string, packageMode bool, matchMode string) string {
+	text = strings.TrimSpace(text)
+	switch {
+	case packageMode || matchMode == matchModeRegex:
+		return ""
+	case matchMode == matchModePath:
+		return filepath.Base(text)
+	}
+	return namePattern(matchMode, text)
+}
+
+// Samples the process named in the form until its context is cancelled.
+// One goroutine serves every target, so changing or exiting the process
+// doesn't start or strand any.
+type liveRateMonitor struct {
+	mu   sync.Mutex
+	name string
+}
+
+func (m *liveRateMonitor) setTarget(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.name = name
+}
+
+func (m *liveRateMonitor) target() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.name
+}
+
+// Sample every liveRateInterval and report the label text through onUpdate
+func (m *liveRateMonitor) run(ctx context.Context, src ProcessSource, onUpdate func(string)) {
+	ticker := time.NewTicker(liveRateInterval)
+	defer ticker.Stop()
+	var s rateSampler
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			name := m.target()
+			if name == "" {
+				onUpdate("Enter a process name to see its current rate")
+				continue
+			}
+			rate, ok := s.sample(src, name, now)
+			if !ok && rate.PIDs > 0 {
+				onUpdate(name + ": measuring...")
+				continue
+			}
+			onUpdate(name + ": " + rate.String())
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"netlimiter/netlimiter"
+)
+
+// QoS policy or firewall rule created by this tool, as currently present in Windows
+type livePolicy struct {
+	Kind          string // "qos" or "firewall"
+	Name          string
+	AppPath       string
+	BitsPerSecond int64
+	Package       string // AppContainer SID for package-scoped firewall rules
+}
+
+// Differences between the tracked rule list and the live state
+type ruleDrift struct {
+	TrackedNotLive []LimitRule  // active in our list, missing from Windows
+	LiveNotTracked []livePolicy // present in Windows, unknown to our list
+}
+
+// Whether anything disagrees
+func (d ruleDrift) empty() bool {
+	return len(d.TrackedNotLive) == 0 && len(d.LiveNotTracked) == 0
+}
+
+// Query the QoS policies and firewall rules carrying this tool's names
+func queryLiveRules() ([]livePolicy, error) {
+	script := fmt.Sprintf(`
+$qos = @(Get-NetQosPolicy -PolicyStore ActiveStore -ErrorAction SilentlyContinue | Where-Object { $_.Name -like "%s*" } | ForEach-Object {
+  [pscustomobject]@{ Kind = "qos"; Name = $_.Name; AppPath = $_.AppPathNameMatchCondition; BitsPerSecond = [int64]$_.ThrottleRateActionBitsPerSecond; Package = "" }
+})
+$fw = @(Get-NetFirewallRule -DisplayName "%s*" -ErrorAction SilentlyContinue | ForEach-Object {
+  $app = $_ | Get-NetFirewallApplicationFilter
+  [pscustomobject]@{ Kind = "firewall"; Name = $_.DisplayName; AppPath = "$($app.Program)"; BitsPerSecond = 0; Package = "$($app.Package)" }
+})
+ConvertTo-Json -InputObject @($qos + $fw) -Compress
+`, netlimiter.QoSPolicyName, strings.TrimSuffix(netlimiter.FirewallRuleIn, "_IN"))
+
+	var live []livePolicy
+	if err := queryPowerShellJSON(script, &live); err != nil {
+		return nil, fmt.Errorf("live rule query error: %w", err)
+	}
+	return live, nil
+}
+
+// Query QoS policies and enabled blocking firewall rules targeting any of
+// the executables that this tool didn't create, e.g. another limiter's or a
+// manual policy. A QoS condition matches the full path or the bare file
+// name; firewall rules are found by program path.
+func queryForeignRules(exePaths []string) ([]livePolicy, error) {
+	quoted := make([]string, len(exePaths))
+	for i, p := range exePaths {
+		quoted[i] = netlimiter.QuotePowerShell(p)
+	}
+	script := fmt.Sprintf(`
+$paths = @(%[1]s)
+$names = @($paths | ForEach-Object { Split-Path $_ -Leaf })
+$qos = @(Get-NetQosPolicy -PolicyStore ActiveStore -ErrorAction SilentlyContinue |
+  Where-Object { $_.Name -notlike "%[2]s*" -and ($paths -contains $_.AppPathNameMatchCondition -or $names -contains $_.AppPathNameMatchCondition) } | ForEach-Object {
+  [pscustomobject]@{ Kind = "qos"; Name = $_.Name; AppPath = $_.AppPathNameMatchCondition; BitsPerSecond = [int64]$_.ThrottleRateActionBitsPerSecond; Package = "" }
+})
+$fw = @(foreach ($p in $paths) {
+  Get-NetFirewallApplicationFilter -Program $p -ErrorAction SilentlyContinue | Get-NetFirewallRule -ErrorAction SilentlyContinue |
+    Where-Object { $_.Enabled -eq "True" -and $_.Action -eq "Block" -and $_.DisplayName -notlike "%[3]s*" } | ForEach-Object {
+    [pscustomobject]@{ Kind = "firewall"; Name = $_.DisplayName; AppPath = $p; BitsPerSecond = 0; Package = "" }
+  }
+})
+ConvertTo-Json -InputObject @($qos + $fw) -Compress
+`, strings.Join(quoted, ", "), netlimiter.QoSPolicyName, strings.TrimSuffix(netlimiter.FirewallRuleIn, "_IN"))
+
+	var foreign []livePolicy
+	if err := queryPowerShellJSON(script, &foreign); err != nil {
+		return nil, fmt.Errorf("other policies query error: %w", err)
+	}
+	return foreign, nil
+}
+
+// One line for a policy of another tool, e.g. "QoS policy Foo throttles chrome.exe to 500 kbps"
+func describeForeign(p livePolicy) string {
+	if p.Kind == "firewall" {
+		return fmt.Sprintf("Firewall rule %q blocks %s", p.Name, p.AppPath)
+	}
+	if p.BitsPerSecond > 0 {
+		return fmt.Sprintf("QoS policy %q throttles %s to %d kbps", p.Name, p.AppPath, p.BitsPerSecond/1000)
+	}
+	return fmt.Sprintf("QoS policy %q matches %s", p.Name, p.AppPath)
+}
+
+// Whether a live policy or rule carries one of this tool's names
+func ownedLivePolicy(p livePolicy) bool {
+	switch p.Kind {
+	case "qos":
+		return strings.HasPrefix(p.Name, netlimiter.QoSPolicyName)
+	case "firewall":
+		return strings.HasPrefix(p.Name, netlimiter.FirewallRuleIn) || strings.HasPrefix(p.Name, netlimiter.FirewallRuleOut)
+	}
+	return false
+}
+
+// Build the script that removes exactly one live QoS policy or firewall rule
+func removeLiveScript(p livePolicy) string {
+	if p.Kind == "qos" {
+		return fmt.Sprintf(`
+$store = if (Get-NetQosPolicy -Name %[1]s -PolicyStore %[2]s -ErrorAction SilentlyContinue) { "%[2]s" } else { "%[3]s" }
+Remove-NetQosPolicy -Name %[1]s -PolicyStore $store -Confirm:$false -ErrorAction Stop
+`, netlimiter.QuotePowerShell(p.Name), netlimiter.PersistentStore, netlimiter.ActiveStore)
+	}
+	return fmt.Sprintf(`
+Get-NetFirewallRule -DisplayName %s -ErrorAction Stop | Remove-NetFirewallRule -ErrorAction Stop
+`, netlimiter.QuotePowerShell(p.Name))
+}
+
+// Remove one live policy or rule, refusing names this tool doesn't own
+func removeLivePolicy(p livePolicy) *OpResult {
+	res := &OpResult{}
+	res.step("Removing %s %s", p.Kind, p.Name)
+	if !ownedLivePolicy(p) {
+		res.Err = fmt.Errorf("%s is not a %s policy or rule", p.Name, netlimiter.QoSPolicyName)
+		return res
+	}
+	res.Script = removeLiveScript(p)
+	if res.run("Output", "remove error").Err == nil {
+		res.succeed("RemoveLive: success")
+	}
+	return res
+}
+
+// Whether a live policy implements a tracked rule.
+// Package firewall rules are keyed by SID, so any package-scoped rule counts.
+func liveMatchesRule(p livePolicy, r LimitRule) bool {
+	if r.Blocked {
+		if p.Kind != "firewall" {
+			return false
+		}
+		if r.Package != "" {
+			return p.Package != "" && p.Package != "Any"
+		}
+		return coversPath(r, p.AppPath)
+	}
+	if p.Kind != "qos" {
+		return false
+	}
+	if r.Package != "" {
+		// QoS for a package matches its resolved executable, which isn't tracked
+		return true
+	}
+	return coversPath(r, p.AppPath)
+}
+
+// Whether path is one of the executables a process rule covers
+func coversPath(r LimitRule, path string) bool {
+	for _, t := range r.exeTargets() {
+		if strings.EqualFold(path, t.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare active tracked rules with the live state
+func reconcileRules(rules []LimitRule, live []livePolicy) ruleDrift {
+	var drift ruleDrift
+	used := make([]bool, len(live))
+
+	for _, r := range rules {
+		if r.status() != statusActive {
+			continue
+		}
+		found := false
+		for i, p := range live {
+			if liveMatchesRule(p, r) {
+				used[i] = true
+				found = true
+			}
+		}
+		if !found {
+			drift.TrackedNotLive = append(drift.TrackedNotLive, r)
+		}
+	}
+	for i, p := range live {
+		if !used[i] {
+			drift.LiveNotTracked = append(drift.LiveNotTracked, p)
+		}
+	}
+	return drift
+}
+
+// Describe drift for the log
+func formatDrift(d ruleDrift) string {
+	if d.empty() {
+		return "Tracked rules match the live QoS/firewall state"
+	}
+	var b strings.Builder
+	for _, r := range d.TrackedNotLive {
+		fmt.Fprintf(&b, "Drift: tracked but not live: %s\n", r.describe())
+	}
+	for _, p := range d.LiveNotTracked {
+		fmt.Fprintf(&b, "Drift: live but not tracked: %s %s %s\n", p.Kind, p.Name, p.AppPath)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Whether a process is currently limited, according to both the tracked rule
+// list and the live QoS/firewall state. The returned rule is the effective
+// one. If the live state can't be queried, the tracked list is trusted.
+func isProcessLimited(processName string) (bool, *LimitRule) {
+	r, ok := tracked.get(strings.ToLower(processName))
+	if !ok || r.status() != statusActive {
+		return false, nil
+	}
+
+	live, err := backend.LiveRules()
+	if err != nil {
+		return true, &r
+	}
+	for _, p := range live {
+		if liveMatchesRule(p, r) {
+			return true, &r
+		}
+	}
+	return false, nil
+}
+
+// Query the live state, flag drift on tracked rules and report it.
+// Active rules missing from Windows are marked failed.
+func verifyLiveState(log func(string)) {
+	live, err := backend.LiveRules()
+	if err != nil {
+		log("Verify error: " + err.Error())
+		return
+	}
+	drift := reconcileRules(tracked.list(), live)
+	log(formatDrift(drift))
+	for _, r := range drift.TrackedNotLive {
+		r.Status, r.LastError = statusFailed, "missing from live QoS/firewall state"
+		if err := tracked.put(r); err != nil {
+			log("Could not save tracked rules: " + err.Error())
+		}
+	}
+}
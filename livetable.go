@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+var liveTableHeaders = []string{"Kind", "Name", "Matches", "Rate", ""}
+
+// Text shown in a live policy table cell (the last column holds the delete button)
+func liveTableCell(p livePolicy, col int) string {
+	switch col {
+	case 0:
+		return p.Kind
+	case 1:
+		return p.Name
+	case 2:
+		if p.Package != "" && p.Package != "Any" {
+			return "package " + p.Package
+		}
+		return p.AppPath
+	case 3:
+		if p.Kind != "qos" || p.BitsPerSecond <= 0 {
+			return ""
+		}
+		return fmt.Sprintf("%d kbps", p.BitsPerSecond/1000)
+	}
+	return ""
+}
+
+// Manage Rules tab: every QoS policy and firewall rule carrying this tool's
+// names as Windows has them, tracked or not, each removable on its own
+func liveRulesPanel(window fyne.Window, appendLog func(string)) fyne.CanvasObject {
+	var rows []livePolicy
+	status := widget.NewLabel("Refresh to list the policies and rules in place")
+
+	var refresh func()
+	remove := func(p livePolicy) {
+		dialog.ShowConfirm("Remove "+p.Kind, "Remove "+p.Name+"?\nA tracked rule using it will show as drift in Verify Live State.", func(ok bool) {
+			if !ok {
+				return
+			}
+			go func() {
+				appendLog("----------------------------------------------------")
+				log, err := backend.RemoveLive(p).result()
+				appendLog(log)
+				auditOrLog(appendLog, "clear", "", p.Name, map[string]any{"scope": "live", "kind": p.Kind}, err)
+				if err != nil {
+					appendLog("Remove error: " + err.Error())
+				}
+				refresh()
+			}()
+		}, window)
+	}
+
+	table := widget.NewTableWithHeaders(
+		func() (int, int) { return len(rows), len(liveTableHeaders) },
+		func() fyne.CanvasObject {
+			return container.NewStack(widget.NewLabel(""), widget.NewButton("Delete", nil))
+		},
+		func(id widget.TableCellID, obj fyne.CanvasObject) {
+			stack := obj.(*fyne.Container)
+			label := stack.Objects[0].(*widget.Label)
+			button := stack.Objects[1].(*widget.Button)
+			p := rows[id.Row]
+			if id.Col == len(liveTableHeaders)-1 {
+				button.OnTapped = func() { remove(p) }
+				button.Show()
+				label.Hide()
+				return
+			}
+			button.Hide()
+			label.SetText(liveTableCell(p, id.Col))
+			label.Show()
+		},
+	)
+	table.CreateHeader = func() fyne.CanvasObject { return widget.NewLabel("") }
+	table.UpdateHeader = func(id widget.TableCellID, obj fyne.CanvasObject) {
+		label := obj.(*widget.Label)
+		if id.Row < 0 && id.Col >= 0 {
+			label.SetText(liveTableHeaders[id.Col])
+		} else {
+			label.SetText("")
+		}
+	}
+	table.ShowHeaderColumn = false
+	table.SetColumnWidth(0, 80)
+	table.SetColumnWidth(1, 240)
+	table.SetColumnWidth(2, 320)
+	table.SetColumnWidth(3, 100)
+	table.SetColumnWidth(4, 80)
+
+	refreshButton := widget.NewButton("Refresh", nil)
+	refresh = func() {
+		fyne.Do(func() {
+			status.SetText("Querying QoS policies and firewall rules...")
+			refreshButton.Disable()
+		})
+		live, err := backend.LiveRules()
+		fyne.Do(func() {
+			refreshButton.Enable()
+			if err != nil {
+				status.SetText("Error: " + err.Error())
+				return
+			}
+			rows = live
+			table.Refresh()
+			status.SetText(fmt.Sprintf("%d policy(ies) and rule(s) in place", len(rows)))
+		})
+	}
+	refreshButton.OnTapped = func() { go refresh() }
+
+	return container.NewBorder(container.NewHBox(refreshButton, status), nil, nil, nil, table)
+}
@@ -0,0 +1,31 @@
+package main
+
+// Log verbosity chosen in the GUI
+type logLevel int
+
+const (
+	logQuiet   logLevel = iota // outcomes only: applied, cleared, errors
+	logNormal                  // plus the steps leading to them
+	logVerbose                 // plus raw PowerShell output
+)
+
+const logLevelPrefKey = "logLevel"
+
+var logLevelNames = []string{"Quiet", "Normal", "Verbose"}
+
+func (l logLevel) String() string {
+	if l < logQuiet || l > logVerbose {
+		return logLevelNames[logNormal]
+	}
+	return logLevelNames[l]
+}
+
+// Level for a selector label; unknown labels mean Normal
+func parseLogLevel(name string) logLevel {
+	for i, n := range logLevelNames {
+		if n == name {
+			return logLevel(i)
+		}
+	}
+	return logNormal
+}
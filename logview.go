@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Lines kept in the log view; older ones are still in the log files
+const logViewMaxLines = 2000
+
+// Line starts marking a severity in free-text log lines, checked lower-cased
+var (
+	errorStarts   = []string{"error", "problem:", "could not", "failed:"}
+	warningStarts = []string{"warning:", "caution:", "note:", "dry run", "queued", "no process found"}
+	successStarts = []string{"applied:", "cleared all", "removed rule:", "self-test passed", "saved ", "imported ", "exported ", "deleted "}
+)
+
+// Severity of a free-text log line, e.g. "Apply error: ...", "Warning: ...",
+// "Applied: ...". Only the line's start and its leading label (the text
+// before the first ": ") count, so a path or name containing "error" or
+// "saved" leaves the line uncoloured. OpResult steps carry their own
+// severity and don't come through here.
+func classifyLogLine(line string) logSeverity {
+	lower := strings.ToLower(strings.TrimSpace(line))
+	lower = strings.TrimSpace(strings.TrimPrefix(lower, ">"))
+	label, value, hasLabel := strings.Cut(lower, ": ")
+	switch {
+	case hasPrefixAny(lower, errorStarts), hasLabel && (strings.Contains(label, " error") || strings.HasPrefix(value, "could not")):
+		return severityError
+	case hasPrefixAny(lower, warningStarts), strings.HasSuffix(label, " cancelled"):
+		return severityWarning
+	case hasPrefixAny(lower, successStarts), hasLabel && value == "success":
+		return severitySuccess
+	}
+	return severityInfo
+}
+
+func hasPrefixAny(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Theme colour of a severity; info uses the normal text colour
+func (s logSeverity) colorName() fyne.ThemeColorName {
+	switch s {
+	case severitySuccess:
+		return theme.ColorNameSuccess
+	case severityWarning:
+		return theme.ColorNameWarning
+	case severityError:
+		return theme.ColorNameError
+	}
+	return theme.ColorNameForeground
+}
+
+// Scrolling log with each line coloured by its severity. Only use it from
+// the UI goroutine (fyne.Do).
+type logView struct {
+	text   *widget.RichText
+	scroll *container.Scroll
+	lines  []string
+}
+
+// Log view at least rows lines tall
+func newLogView(rows int) *logView {
+	v := &logView{text: widget.NewRichText()}
+	v.text.Wrapping = fyne.TextWrapWord
+	v.scroll = container.NewVScroll(v.text)
+	v.scroll.SetMinSize(fyne.NewSize(0, float32(rows)*theme.TextSize()*1.5))
+	return v
+}
+
+// Append free text, one paragraph per line coloured by classifyLogLine,
+// and scroll to it
+func (v *logView) append(text string) {
+	v.add(text, classifyLogLine)
+}
+
+// Append text whose severity is known, e.g. an OpResult step, and scroll to it
+func (v *logView) appendAt(text string, severity logSeverity) {
+	v.add(text, func(string) logSeverity { return severity })
+}
+
+func (v *logView) add(text string, severityOf func(string) logSeverity) {
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		v.lines = append(v.lines, line)
+		v.text.Segments = append(v.text.Segments, &widget.TextSegment{
+			Text:  line,
+			Style: widget.RichTextStyle{ColorName: severityOf(line).colorName(), SizeName: theme.SizeNameText},
+		})
+	}
+	if extra := len(v.lines) - logViewMaxLines; extra > 0 {
+		v.lines = v.lines[extra:]
+		v.text.Segments = v.text.Segments[extra:]
+	}
+	v.text.Refresh()
+	v.scroll.ScrollToBottom()
+}
+
+func (v *logView) clear() {
+	v.lines, v.text.Segments = nil, nil
+	v.text.Refresh()
+}
+
+// Plain text of the lines shown, for copying
+func (v *logView) String() string {
+	return strings.Join(v.lines, "\n")
+}
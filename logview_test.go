@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestClassifyLogLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want logSeverity
+	}{
+		{"Apply error: access denied", severityError},
+		{"Error: enter the process to clear", severityError},
+		{"Webhook error (slack): timeout", severityError},
+		{"Could not save tracked rules: disk full", severityError},
+		{"Shared budget: could not set steam.exe to 100 kbps", severityError},
+		{"Problem: run as administrator", severityError},
+		{"  failed: C:\\app.exe: access denied", severityError},
+		{"Warning: no QoS policy matches", severityWarning},
+		{"Note: IN limit is not enforced", severityWarning},
+		{"Apply cancelled", severityWarning},
+		{"Dry run, nothing changed", severityWarning},
+		{"Applied: chrome.exe OUT 500 kbps", severitySuccess},
+		{"ApplyAllowlist: success", severitySuccess},
+		{"Cleared all rules", severitySuccess},
+		{"Saved profile \"work\" (2 process(es))", severitySuccess},
+		// Wording inside a path, name or count doesn't colour the line
+		{`Including child executable: C:\Windows\System32\WerFault.exe`, severityInfo},
+		{`Process path: C:\Program Files\Windows Error Reporting\wer.exe`, severityInfo},
+		{"Reapplied 3 of 3 saved rule(s) loaded at startup", severityInfo},
+		{"Dropped target: C:\\Tools\\success.exe", severityInfo},
+		{"  > Removing policy cleared-cache", severityInfo},
+		{"", severityInfo},
+	}
+	for _, tt := range tests {
+		if got := classifyLogLine(tt.line); got != tt.want {
+			t.Errorf("classifyLogLine(%q) = %d, want %d", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestOpResultStepSeverity(t *testing.T) {
+	res := &OpResult{}
+	res.step("Applying speed limit for: %s", `C:\Windows Error Reporting\app.exe`)
+	res.warn("Note: priority only takes effect where DSCP is honoured")
+	res.fail("Problem: %s", "access denied")
+	res.succeed("ApplyLimit: success")
+	want := []logSeverity{severityInfo, severityWarning, severityError, severitySuccess}
+	for i, s := range res.Steps {
+		if s.Severity != want[i] {
+			t.Errorf("step %q has severity %d, want %d", s.Text, s.Severity, want[i])
+		}
+	}
+	if got := res.Log(); got != "Applying speed limit for: C:\\Windows Error Reporting\\app.exe\nNote: priority only takes effect where DSCP is honoured\nProblem: access denied\nApplyLimit: success\n" {
+		t.Errorf("Log() = %q", got)
+	}
+}
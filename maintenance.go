@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Age thresholds offered by the "clear older than" action
+var ruleAgeChoices = []struct {
+	Label string
+	Age   time.Duration
+}{
+	{"1 hour", time.Hour},
+	{"1 day", 24 * time.Hour},
+	{"7 days", 7 * 24 * time.Hour},
+	{"30 days", 30 * 24 * time.Hour},
+}
+
+// Tracked rules applied more than age ago, oldest first.
+// Only rules tracked by this tool are considered.
+func rulesOlderThan(rules []LimitRule, age time.Duration, now time.Time) []LimitRule {
+	var old []LimitRule
+	for _, r := range rules {
+		if !r.AppliedAt.IsZero() && now.Sub(r.AppliedAt) > age {
+			old = append(old, r)
+		}
+	}
+	sort.Slice(old, func(i, j int) bool { return old[i].AppliedAt.Before(old[j].AppliedAt) })
+	return old
+}
+
+// Human-friendly age such as "3d 4h" or "25m"
+func formatAge(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// Clear the given rules and stop tracking them
+func clearRules(rules []LimitRule, log func(string)) {
+	for _, r := range rules {
+		clearLog, err := clearRule(r).result()
+		log("Clearing " + r.describe())
+		log(clearLog)
+		if err != nil {
+			log("Clear error: " + err.Error())
+		} else if err := tracked.remove(r.key()); err != nil {
+			log("Could not save tracked rules: " + err.Error())
+		}
+		auditOrLog(log, "clear", r.Process, r.ExePath, map[string]any{"reason": "maintenance"}, err)
+	}
+}
+
+// Toolbar for clearing every rule older than a chosen age, with preview and confirmation
+func clearOldRulesBar(window fyne.Window, appendLog func(string)) fyne.CanvasObject {
+	labels := make([]string, len(ruleAgeChoices))
+	for i, c := range ruleAgeChoices {
+		labels[i] = c.Label
+	}
+	ageSelect := widget.NewSelect(labels, nil)
+	ageSelect.SetSelectedIndex(2)
+
+	button := widget.NewButton("Preview & Clear...", func() {
+		age := ruleAgeChoices[ageSelect.SelectedIndex()].Age
+		now := time.Now()
+		old := rulesOlderThan(tracked.list(), age, now)
+		if len(old) == 0 {
+			dialog.ShowInformation("Clear old rules", "No rules older than "+ageSelect.Selected+".", window)
+			return
+		}
+
+		var b strings.Builder
+		for _, r := range old {
+			fmt.Fprintf(&b, "- %s (applied %s ago)\n", r.describe(), formatAge(now.Sub(r.AppliedAt)))
+		}
+		preview := widget.NewLabel(b.String())
+		content := container.NewVBox(
+			widget.NewLabel(fmt.Sprintf("These %d rule(s) will be removed:", len(old))),
+			container.NewVScroll(preview),
+		)
+		d := dialog.NewCustomConfirm("Clear old rules", "Clear", "Cancel", content, func(ok bool) {
+			if !ok {
+				return
+			}
+			go func() {
+				appendLog("----------------------------------------------------")
+				appendLog(fmt.Sprintf("Clearing %d rule(s) older than %s", len(old), ageSelect.Selected))
+				clearRules(old, appendLog)
+			}()
+		}, window)
+		d.Resize(fyne.NewSize(480, 320))
+		d.Show()
+	})
+
+	return container.NewHBox(widget.NewLabel("Clear rules older than"), ageSelect, button)
+}
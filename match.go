@@ -0,0 +1,358 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shirou/gopsutil/v3/process"
+
+	"netlimiter/netlimiter"
+)
+
+// Process name match modes offered in the GUI
+const (
+	matchModeExact    = "Exact name"
+	matchModePrefix   = "Starts with"
+	matchModeContains = "Contains"
+	matchModeGlob     = "Wildcard (* ?)"
+	matchModeRegex    = "Regex"
+	matchModePath     = "Executable path"
+)
+
+// Wildcard pattern for a name entered in one of the name match modes;
+// exact names are returned unchanged. Prefix and contains matches escape [
+// so it is taken literally.
+func namePattern(mode, name string) string {
+	literal := strings.ReplaceAll(name, "[", `\[`)
+	switch mode {
+	case matchModePrefix:
+		return literal + "*"
+	case matchModeContains:
+		return "*" + literal + "*"
+	}
+	return name
+}
+
+// How many running processes a wildcard matched and under which names,
+// e.g. "chrome* matched 7 process(es): chrome.exe (6), chrome_proxy.exe (1)"
+func describePatternMatches(src ProcessSource, pattern string) (string, error) {
+	procs, err := src.ListProcesses()
+	if err != nil {
+		return "", err
+	}
+	counts := make(map[string]int)
+	var names []string
+	total := 0
+	for _, p := range procs {
+		if !netlimiter.MatchProcessName(pattern, p.Name) {
+			continue
+		}
+		name := strings.ToLower(p.Name)
+		if counts[name] == 0 {
+			names = append(names, name)
+		}
+		counts[name]++
+		total++
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, n := range names {
+		parts[i] = fmt.Sprintf("%s (%d)", n, counts[n])
+	}
+	return fmt.Sprintf("%s matched %d process(es): %s", pattern, total, strings.Join(parts, ", ")), nil
+}
+
+// Longest target the process field accepts; MAX_PATH covers full executable paths
+const maxTargetLength = 260
+
+// Characters Windows never allows in a file name; paths may still use \ / and :
+const invalidNameChars = `<>:"/\|?*`
+
+// Clean a user-supplied target before it reaches process lookups or scripts.
+// Control characters are stripped; overlong input and names that can't be
+// process image names are rejected. Regex patterns keep their metacharacters.
+func sanitizeTarget(s, mode string) (string, error) {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.TrimSpace(s)
+
+	switch {
+	case s == "":
+		return "", errors.New("process name is required")
+	case utf8.RuneCountInString(s) > maxTargetLength:
+		return "", fmt.Errorf("process name is longer than %d characters", maxTargetLength)
+	case !utf8.ValidString(s) || strings.ContainsRune(s, utf8.RuneError):
+		return "", errors.New("process name is not valid text")
+	}
+
+	invalid := invalidNameChars
+	switch mode {
+	case matchModeRegex:
+		return s, nil
+	case matchModePath:
+		invalid = `<>"|?*`
+	case matchModeGlob:
+		invalid = `<>:"/\|`
+		if _, err := path.Match(strings.ToLower(s), ""); err != nil {
+			return "", fmt.Errorf("invalid wildcard pattern %q: %w", s, err)
+		}
+	}
+	if i := strings.IndexAny(s, invalid); i >= 0 {
+		return "", fmt.Errorf("process name contains %q, which can't appear in an executable name", s[i])
+	}
+	if strings.Trim(s, ".") == "" {
+		return "", fmt.Errorf("%q is not a process name", s)
+	}
+	return s, nil
+}
+
+// How a rule made with these form settings treats instances started later.
+// QoS (AppPathNameMatchCondition) and firewall (-Program / -Package) rules are
+// all path or package scoped, and nothing here is PID scoped, so no watcher is needed.
+func instanceCoverage(packageMode bool, matchMode string, narrowed bool) string {
+	switch {
+	case packageMode:
+		return "New instances: covered. Blocks apply to the whole package; limits to its main executable."
+	case matchMode == matchModeRegex:
+		return "New instances of the matched executable: covered. Executables that only match the pattern later: not covered."
+	case matchMode == matchModePrefix || matchMode == matchModeContains || matchMode == matchModeGlob:
+		return "New instances of the matched executables: covered. Executables that only match the pattern later: not covered until the rule is applied again."
+	case narrowed:
+		return "New instances: covered. The filter only picks the executable path; every process started from it is limited, filtered or not."
+	default:
+		return "New instances: covered. Rules key on the executable path, so processes started from it later are limited too."
+	}
+}
+
+// Running process matched by a pattern
+type processMatch struct {
+	PID     int32
+	Name    string
+	ExePath string
+}
+
+// Compile a user-supplied pattern, reporting errors before any scanning.
+// Windows names and paths are case-insensitive, so the pattern is too.
+func compileProcessRegex(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return re, nil
+}
+
+// Validate a full executable path entered or dropped by the user.
+// The process doesn't need to be running: QoS and firewall rules key on the path.
+func resolveExePath(path string) (string, error) {
+	if !strings.EqualFold(filepath.Ext(path), ".exe") {
+		return "", fmt.Errorf("not an .exe file: %s", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("executable not found: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+	return filepath.Clean(path), nil
+}
+
+// PID entered in place of a process name: all digits and in range
+func parsePID(s string) (int32, bool) {
+	if s == "" || strings.TrimLeft(s, "0123456789") != "" {
+		return 0, false
+	}
+	pid, err := strconv.ParseInt(s, 10, 32)
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	return int32(pid), true
+}
+
+// Executable of a single process. QoS and firewall rules can't target a PID,
+// so the rule built from it still covers every process running this path.
+func resolveExeByPID(src ProcessSource, pid int32) (string, error) {
+	exePath, err := src.ExePath(pid)
+	if errors.Is(err, process.ErrorProcessNotRunning) {
+		return "", fmt.Errorf("no process with PID %d: %w", pid, errProcessNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not get executable path for PID %d: %w", pid, err)
+	}
+	return exePath, nil
+}
+
+// Find processes whose name or executable path matches re
+func findProcessesByRegex(re *regexp.Regexp) ([]processMatch, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []processMatch
+	for _, p := range procs {
+		name, err := p.Name()
+		if err != nil {
+			continue
+		}
+		exe, _ := p.Exe()
+		if re.MatchString(name) || (exe != "" && re.MatchString(exe)) {
+			matches = append(matches, processMatch{PID: p.Pid, Name: name, ExePath: exe})
+		}
+	}
+	return matches, nil
+}
+
+// Describe matches for a confirmation dialog, capped at limit lines
+func formatMatches(matches []processMatch, limit int) string {
+	var b strings.Builder
+	for i, m := range matches {
+		if i == limit {
+			fmt.Fprintf(&b, "... and %d more\n", len(matches)-limit)
+			break
+		}
+		path := m.ExePath
+		if path == "" {
+			path = "(path unavailable)"
+		}
+		fmt.Fprintf(&b, "%d  %s  %s\n", m.PID, m.Name, path)
+	}
+	return b.String()
+}
+
+// Process instance whose command line or working directory matched a filter
+type cmdlineMatch struct {
+	PID     int32
+	Cmdline string
+	Cwd     string
+}
+
+// Keep the PIDs whose command line or working directory contains substr
+// (case-insensitive). Processes whose details can't be read are skipped.
+func filterByCommandLine(src ProcessSource, pids []int32, substr string) []cmdlineMatch {
+	substr = strings.ToLower(substr)
+	var matches []cmdlineMatch
+	for _, pid := range pids {
+		cmdline, cwd, err := src.CommandLine(pid)
+		if err != nil {
+			continue
+		}
+		if strings.Contains(strings.ToLower(cmdline), substr) || strings.Contains(strings.ToLower(cwd), substr) {
+			matches = append(matches, cmdlineMatch{PID: pid, Cmdline: cmdline, Cwd: cwd})
+		}
+	}
+	return matches
+}
+
+// Which of several same-named instances feed into an apply
+const (
+	instanceAll    = "All instances"
+	instanceOldest = "Oldest instance"
+	instanceNewest = "Newest instance"
+)
+
+// Running instance of a process with its start time (zero if unreadable)
+type processInstance struct {
+	PID     int32
+	Started time.Time
+}
+
+// Instances sorted oldest first; those without a start time go last
+func sortInstancesByStart(src ProcessSource, pids []int32) []processInstance {
+	instances := make([]processInstance, len(pids))
+	for i, pid := range pids {
+		instances[i].PID = pid
+		instances[i].Started, _ = src.StartTime(pid)
+	}
+	sort.SliceStable(instances, func(i, j int) bool {
+		a, b := instances[i].Started, instances[j].Started
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		return a.Before(b)
+	})
+	return instances
+}
+
+// Pick the instances for a policy from a list sorted by sortInstancesByStart
+func selectInstances(instances []processInstance, policy string) []processInstance {
+	if len(instances) == 0 {
+		return nil
+	}
+	switch policy {
+	case instanceOldest:
+		return instances[:1]
+	case instanceNewest:
+		// Unknown start times sort last; the newest is the last known one
+		for i := len(instances) - 1; i >= 0; i-- {
+			if !instances[i].Started.IsZero() {
+				return instances[i : i+1]
+			}
+		}
+		return instances[len(instances)-1:]
+	}
+	return instances
+}
+
+// Distinct executable paths of the instances, in instance order. Instances
+// whose path can't be read (they exited, or access is denied) are skipped;
+// it is an error only when no path could be read at all.
+func distinctExePaths(src ProcessSource, instances []processInstance) ([]string, error) {
+	var paths []string
+	var firstErr error
+	for _, inst := range instances {
+		exe, err := src.ExePath(inst.PID)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("PID %d: %w", inst.PID, err)
+			}
+			continue
+		}
+		if !slices.ContainsFunc(paths, func(p string) bool { return strings.EqualFold(p, exe) }) {
+			paths = append(paths, exe)
+		}
+	}
+	if len(paths) == 0 {
+		if firstErr == nil {
+			firstErr = errProcessNotFound
+		}
+		return nil, firstErr
+	}
+	return paths, nil
+}
+
+// List instances with their start times, marking the chosen ones
+func formatInstances(instances, chosen []processInstance) string {
+	picked := make(map[int32]bool, len(chosen))
+	for _, c := range chosen {
+		picked[c.PID] = true
+	}
+	var b strings.Builder
+	for _, inst := range instances {
+		mark := "  "
+		if picked[inst.PID] {
+			mark = "> "
+		}
+		started := "start time unknown"
+		if !inst.Started.IsZero() {
+			started = "started " + inst.Started.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(&b, "%s%d  %s\n", mark, inst.PID, started)
+	}
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestSanitizeTargetStripsControlCharacters(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"chrome.exe", "chrome.exe"},
+		{"  chrome.exe\r\n", "chrome.exe"},
+		{"\tchrome.exe", "chrome.exe"},
+		{"chr\x00ome.exe", "chrome.exe"},
+		{"chrome.exe\x7f", "chrome.exe"},
+		{"\x1bchrome\u0085.exe", "chrome.exe"},
+		{"chrome\u200b.exe", "chrome\u200b.exe"}, // format character, not a control one
+	}
+	for _, tt := range tests {
+		got, err := sanitizeTarget(tt.in, matchModeExact)
+		if err != nil || got != tt.want {
+			t.Errorf("sanitizeTarget(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+		if strings.IndexFunc(got, unicode.IsControl) >= 0 {
+			t.Errorf("sanitizeTarget(%q) = %q keeps a control character", tt.in, got)
+		}
+	}
+}
+
+func TestSanitizeTargetRejects(t *testing.T) {
+	modes := []string{matchModeExact, matchModePrefix, matchModeContains, matchModeGlob, matchModeRegex, matchModePath}
+	for _, in := range []string{
+		"",
+		"   ",
+		"\x00\x01\x02",
+		"\r\n\t",
+		strings.Repeat("a", maxTargetLength+1),
+		strings.Repeat("é", maxTargetLength+1), // counted in characters, not bytes
+		"chrome\xff.exe",                       // invalid UTF-8
+		"chrome\xc3.exe",                       // truncated UTF-8 sequence
+		"chrome\ufffd.exe",                     // U+FFFD, what invalid UTF-8 decodes to
+	} {
+		for _, mode := range modes {
+			if got, err := sanitizeTarget(in, mode); err == nil {
+				t.Errorf("sanitizeTarget(%q, %s) = %q, want an error", in, mode, got)
+			}
+		}
+	}
+}
+
+func TestSanitizeTargetLength(t *testing.T) {
+	for _, in := range []string{
+		strings.Repeat("a", maxTargetLength-4) + ".exe",
+		strings.Repeat("é", maxTargetLength),
+	} {
+		if _, err := sanitizeTarget(in, matchModeExact); err != nil {
+			t.Errorf("%d-character name rejected: %v", len([]rune(in)), err)
+		}
+	}
+}
+
+func TestSanitizeTargetModes(t *testing.T) {
+	tests := []struct {
+		in                         string
+		exact, glob, regex, pathOK bool
+	}{
+		{"chrome.exe", true, true, true, true},
+		{"..", false, false, true, false},
+		{".", false, false, true, false},
+		{"...", false, false, true, false},
+		{"chrome<>.exe", false, false, true, false},
+		{"a|b.exe", false, false, true, false},
+		{`"chrome.exe"`, false, false, true, false},
+		{"chrome*", false, true, true, false},
+		{"chrom?.exe", false, true, true, false},
+		{"chrome[", true, false, true, true}, // a literal [ in a name, an unclosed class in a wildcard
+		{"^(chrome|msedge)\\.exe$", false, false, true, false},
+		{`C:\Program Files\App\app.exe`, false, false, true, true},
+		{`C:\Apps\..\app.exe`, false, false, true, true},
+		{"C:/Apps/app.exe", false, false, true, true},
+		{"dir/chrome.exe", false, false, true, true},
+		{"chrome:1", false, false, true, true},
+	}
+	for _, tt := range tests {
+		for mode, want := range map[string]bool{
+			matchModeExact: tt.exact,
+			matchModeGlob:  tt.glob,
+			matchModeRegex: tt.regex,
+			matchModePath:  tt.pathOK,
+		} {
+			got, err := sanitizeTarget(tt.in, mode)
+			if (err == nil) != want {
+				t.Errorf("sanitizeTarget(%q, %s) = %q, %v; want ok %v", tt.in, mode, got, err, want)
+			}
+			if err == nil && got != tt.in {
+				t.Errorf("sanitizeTarget(%q, %s) = %q, want it unchanged", tt.in, mode, got)
+			}
+		}
+	}
+}
+
+func TestCompileProcessRegexIgnoresCase(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"Chrome.*", "chrome.exe", true},
+		{"chrome.*", "CHROME.EXE", true},
+		{`^(chrome|msedge)\.exe$`, "MSEdge.exe", true},
+		{`^(chrome|msedge)\.exe$`, "chrome_proxy.exe", false},
+		{`\\Google\\Chrome\\`, `C:\Program Files\google\chrome\Application\chrome.exe`, true},
+	}
+	for _, tt := range tests {
+		re, err := compileProcessRegex(tt.pattern)
+		if err != nil {
+			t.Fatalf("compileProcessRegex(%q): %v", tt.pattern, err)
+		}
+		if got := re.MatchString(tt.s); got != tt.want {
+			t.Errorf("%q matching %q = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestCompileProcessRegexReportsInvalidPattern(t *testing.T) {
+	for _, pattern := range []string{"chrome(", "[a-", "*chrome"} {
+		if _, err := compileProcessRegex(pattern); err == nil {
+			t.Errorf("compileProcessRegex(%q) accepted an invalid pattern", pattern)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// How often the metered state of the internet connection is polled
+const meteredCheckInterval = 30 * time.Second
+
+// Whether the current internet connection is metered (Fixed/Variable cost,
+// roaming or over its data limit), via the WinRT connection profile
+func isConnectionMetered() (bool, error) {
+	script := `
+[void][Windows.Networking.Connectivity.NetworkInformation, Windows.Networking.Connectivity, ContentType = WindowsRuntime]
+$profile = [Windows.Networking.Connectivity.NetworkInformation]::GetInternetConnectionProfile()
+if (-not $profile) { "false"; exit }
+$cost = $profile.GetConnectionCost()
+$metered = ($cost.NetworkCostType -eq "Fixed") -or ($cost.NetworkCostType -eq "Variable") -or $cost.Roaming -or $cost.OverDataLimit
+if ($metered) { "true" } else { "false" }
+`
+	out, err := runPowerShell(script)
+	if err != nil {
+		return false, fmt.Errorf("metered check error: %w", err)
+	}
+	switch strings.TrimSpace(string(out)) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected metered check output: %s", strings.TrimSpace(string(out)))
+}
+
+// Apply or lift metered-only rules to match the connection state.
+// Rules waiting for a metered connection are kept as pending.
+func enforceMeteredRules(metered bool, log func(string)) {
+	for _, r := range tracked.list() {
+		if !r.MeteredOnly {
+			continue
+		}
+
+		if metered {
+			// A boost ends with the rule reapplied anyway, and a paused rule waits to be resumed
+			if s := r.status(); s == statusActive || s == statusBoosted || s == statusPaused {
+				continue
+			}
+			applyLog, err := applyRule(r).result()
+			log("Metered connection: applying " + r.describe())
+			log(applyLog)
+			if err != nil {
+				log("Apply error: " + err.Error())
+			}
+			if saveErr := tracked.recordResult(r, err); saveErr != nil {
+				log("Could not save tracked rules: " + saveErr.Error())
+			}
+			auditOrLog(log, "limit", r.Process, r.ExePath, map[string]any{"trigger": "metered"}, err)
+			continue
+		}
+
+		if r.status() == statusBoosted {
+			// Already lifted; wait for a metered connection instead of the boost's end
+			if saveErr := tracked.markPending(r); saveErr != nil {
+				log("Could not save tracked rules: " + saveErr.Error())
+			}
+			continue
+		}
+		if r.status() != statusActive {
+			continue
+		}
+		clearLog, err := clearRule(r).result()
+		log("Unmetered connection: lifting " + r.describe())
+		log(clearLog)
+		if err != nil {
+			log("Clear error: " + err.Error())
+			continue
+		}
+		if saveErr := tracked.markPending(r); saveErr != nil {
+			log("Could not save tracked rules: " + saveErr.Error())
+		}
+		auditOrLog(log, "clear", r.Process, r.ExePath, map[string]any{"trigger": "unmetered"}, nil)
+	}
+}
+
+// Poll the metered state and enforce metered-only rules on every change.
+// The first observation counts as a change so state is correct at startup.
+func watchMetered(log func(string)) {
+	var last *bool
+	ticker := time.NewTicker(meteredCheckInterval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		hasRules := false
+		for _, r := range tracked.list() {
+			if r.MeteredOnly {
+				hasRules = true
+				break
+			}
+		}
+		if !hasRules {
+			last = nil
+			continue
+		}
+
+		metered, err := isConnectionMetered()
+		if err != nil {
+			continue
+		}
+		if last != nil && *last == metered {
+			continue
+		}
+		last = &metered
+
+		if metered {
+			log("Connection is now metered")
+		} else {
+			log("Connection is now unmetered")
+		}
+		enforceMeteredRules(metered, log)
+	}
+}
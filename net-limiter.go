@@ -1,308 +1,1448 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"os/exec"
+	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/widget"
-	"github.com/shirou/gopsutil/v3/process"
+
+	"netlimiter/netlimiter"
 )
 
-// Constants for QoS and Firewall
+// Application ID, also the key for stored preferences
+const appID = "com.nearjung.netlimiter"
+
+// Window title without the rule count
+const baseWindowTitle = "Windows NetLimiter GUI"
+
+// How often tracked rules are checked for a changed executable path
+const staleCheckInterval = time.Minute
+
+// Target modes offered in the GUI
 const (
-	qosPolicyName   = "GoNetLimit"
-	firewallRuleIn  = "GoNetBlock_IN"
-	firewallRuleOut = "GoNetBlock_OUT"
+	targetModeProcess = "Process"
+	targetModePackage = "UWP package"
 )
 
-// Convert kbps to bits per second (for ThrottleRateActionBitsPerSecond)
-func kbpsToBitsPerSecond(kbps int) int64 {
-	if kbps <= 0 {
-		return 0
+// Base directory appDataDir lives in; mock builds swap in a temporary one
+var appDataBase = os.UserConfigDir
+
+// Directory for files this tool keeps (%APPDATA%\net-limiter on Windows)
+func appDataDir() (string, error) {
+	base, err := appDataBase()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(base, "net-limiter")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
 	}
-	// Simple conversion: 1 kbps ≈ 1000 bits per second
-	return int64(kbps) * 1000
+	return dir, nil
 }
 
-// Escape string for use in PowerShell
-func escapeForPowerShell(s string) string {
-	s = strings.ReplaceAll(s, "`", "``")
-	s = strings.ReplaceAll(s, `"`, "`\"")
-	return s
+// Window title with the number of active rules, e.g. "Windows NetLimiter GUI — 3 rules".
+// PAUSED is shown when every tracked rule is paused; no rules gives the base title.
+func windowTitle(rules []LimitRule) string {
+	active, paused := 0, 0
+	for _, r := range rules {
+		switch r.status() {
+		case statusActive:
+			active++
+		case statusPaused:
+			paused++
+		}
+	}
+	switch {
+	case active == 0 && paused > 0:
+		return baseWindowTitle + " — PAUSED"
+	case active == 1:
+		return baseWindowTitle + " — 1 rule"
+	case active > 1:
+		return fmt.Sprintf("%s — %d rules", baseWindowTitle, active)
+	}
+	return baseWindowTitle
 }
 
-// Find all PIDs for a given process name (e.g. "chrome.exe")
-func findPIDsByName(target string) ([]int32, error) {
-	procs, err := process.Processes()
-	if err != nil {
-		return nil, err
+// Press b as a keyboard shortcut would; a disabled button stays unpressed
+func tapButton(b *widget.Button) {
+	if !b.Disabled() && b.OnTapped != nil {
+		b.OnTapped()
 	}
+}
 
-	targetLower := strings.ToLower(target)
-	var pids []int32
-	for _, p := range procs {
-		name, err := p.Name()
-		if err != nil {
-			continue
+func main() {
+	reapply := flag.Bool("reapply", false, "reapply saved rules without the GUI and exit (used by the logon task)")
+	cli := registerCLIFlags()
+	flag.Parse()
+	dryRun.Store(cli.dryRun)
+	if cli.dryRun {
+		fmt.Println("Dry run: PowerShell scripts are printed instead of run; nothing is changed")
+	}
+	if *reapply {
+		os.Exit(runReapply())
+	}
+	if cliRequested() {
+		os.Exit(runCLI(cli))
+	}
+
+	application := app.NewWithID(appID)
+	hooks.configure(application.Preferences())
+	psRuns.configure(application.Preferences())
+	configurePolicyStore(application.Preferences())
+	window := application.NewWindow(baseWindowTitle)
+	lastForm := loadFormState(application.Preferences())
+	window.Resize(lastForm.windowSize())
+
+	processEntry := widget.NewEntry()
+	processEntry.SetPlaceHolder("Process name or PID, e.g. chrome.exe or 1234")
+
+	var updateCoverage func()
+	targetMode := widget.NewRadioGroup([]string{targetModeProcess, targetModePackage}, func(mode string) {
+		if updateCoverage != nil {
+			updateCoverage()
 		}
-		if strings.ToLower(name) == targetLower {
-			pids = append(pids, p.Pid)
+		if mode == targetModePackage {
+			processEntry.SetPlaceHolder("Package family name, e.g. Microsoft.WindowsCalculator_8wekyb3d8bbwe")
+		} else {
+			processEntry.SetPlaceHolder("Process name or PID, e.g. chrome.exe or 1234")
 		}
+	})
+	targetMode.Horizontal = true
+	targetMode.SetSelected(targetModeProcess)
+
+	inEntry := widget.NewEntry()
+	inEntry.SetPlaceHolder("Limit IN, not enforceable by Windows QoS; 0 for block if both are 0")
+	inUnit := widget.NewSelect(rateUnits, nil)
+	inUnit.SetSelected(string(unitKbps))
+
+	outEntry := widget.NewEntry()
+	outEntry.SetPlaceHolder("Limit OUT or a % of the link, 0 for block if both are 0")
+	outUnit := widget.NewSelect(rateUnits, nil)
+	outUnit.SetSelected(string(unitKbps))
+
+	// Pick up where the last session left off
+	processEntry.SetText(lastForm.Process)
+	inEntry.SetText(lastForm.In)
+	outEntry.SetText(lastForm.Out)
+	if slices.Contains(rateUnits, lastForm.InUnit) {
+		inUnit.SetSelected(lastForm.InUnit)
+	}
+	if slices.Contains(rateUnits, lastForm.OutUnit) {
+		outUnit.SetSelected(lastForm.OutUnit)
+	}
+
+	// Fill the limit fields from stored kbps values
+	setLimitFields := func(inKbps, outKbps int) {
+		inEntry.SetText(strconv.Itoa(inKbps))
+		outEntry.SetText(strconv.Itoa(outKbps))
+		inUnit.SetSelected(string(unitKbps))
+		outUnit.SetSelected(string(unitKbps))
 	}
-	return pids, nil
-}
 
-// Block all internet (inbound + outbound) for a given executable path
-func blockInternetForProcess(exePath string) (string, error) {
-	log := "Blocking internet for: " + exePath + "\n"
+	prioritySelect := widget.NewSelect(priorityLevels, nil)
+	prioritySelect.SetSelected(string(priorityNormal))
 
-	script := fmt.Sprintf(`
-$path = "%s"
+	watchRestartCheck := widget.NewCheck("Reapply when the process restarts", nil)
+	// Broader than a path match, so it is off by default and explained in the log
+	nameOnlyCheck := widget.NewCheck("Match by name only", nil)
+	// For launchers: cover the executables the matched processes started too
+	childrenCheck := widget.NewCheck("Include child processes", nil)
+	remoteAddrEntry := widget.NewEntry()
+	remoteAddrEntry.SetPlaceHolder("Optional, e.g. 203.0.113.7, 10.0.0.0/8 or ipv4")
+	protocolSelect := widget.NewSelect(blockProtocols, nil)
+	protocolSelect.SetSelected(blockProtocols[0])
+	systemBlocksCheck := widget.NewCheck("Allow blocking Windows system programs", func(on bool) { allowSystemBlocks.Store(on) })
 
-Remove-NetFirewallRule -DisplayName "%s" -ErrorAction SilentlyContinue
-Remove-NetFirewallRule -DisplayName "%s" -ErrorAction SilentlyContinue
+	matchMode := widget.NewSelect([]string{matchModeExact, matchModePrefix, matchModeContains, matchModeGlob, matchModeRegex, matchModePath}, nil)
+	matchMode.SetSelected(matchModeExact)
 
-New-NetFirewallRule -DisplayName "%s" -Program $path -Direction Outbound -Action Block -ErrorAction SilentlyContinue
-New-NetFirewallRule -DisplayName "%s" -Program $path -Direction Inbound  -Action Block -ErrorAction SilentlyContinue
-`,
-		escapeForPowerShell(exePath),
-		firewallRuleIn, firewallRuleOut,
-		firewallRuleOut, firewallRuleIn,
-	)
+	cmdlineEntry := widget.NewEntry()
+	cmdlineEntry.SetPlaceHolder("Optional, name matches only, e.g. D:\\Portable\\AppA")
 
-	cmd := exec.Command("powershell", "-NoProfile", "-ExecutionPolicy", "Bypass", "-Command", script)
-	out, err := cmd.CombinedOutput()
-	if len(out) > 0 {
-		log += "Firewall output:\n" + string(out) + "\n"
-	}
-	if err != nil {
-		return log, fmt.Errorf("firewall error: %w", err)
+	instanceSelect := widget.NewSelect([]string{instanceAll, instanceOldest, instanceNewest}, nil)
+	instanceSelect.SetSelected(instanceAll)
+
+	// Current I/O rate of the process in the form, so a throttle can be checked
+	liveRateLabel := widget.NewLabel("Enter a process name to see its current rate")
+	liveRate := &liveRateMonitor{}
+
+	coverageLabel := widget.NewLabel("")
+	coverageLabel.Wrapping = fyne.TextWrapWord
+	updateCoverage = func() {
+		narrowed := strings.TrimSpace(cmdlineEntry.Text) != "" || instanceSelect.Selected != instanceAll
+		coverageLabel.SetText(instanceCoverage(targetMode.Selected == targetModePackage, matchMode.Selected, narrowed))
+		liveRate.setTarget(liveRateTarget(processEntry.Text, targetMode.Selected == targetModePackage, matchMode.Selected))
 	}
+	matchMode.OnChanged = func(string) { updateCoverage() }
+	instanceSelect.OnChanged = func(string) { updateCoverage() }
+	cmdlineEntry.OnChanged = func(string) { updateCoverage() }
+	processEntry.OnChanged = func(string) { updateCoverage() }
+	updateCoverage()
 
-	log += "BlockInternet: success\n"
-	return log, nil
-}
+	noteEntry := widget.NewEntry()
+	noteEntry.SetPlaceHolder("Optional note, e.g. throttle dev server upload")
 
-// Clear QoS policy and firewall rules used by this tool
-func clearAllLimits() (string, error) {
-	log := "Clearing QoS policy and firewall rules...\n"
-
-	script := fmt.Sprintf(`
-Remove-NetQosPolicy    -Name "%s" -PolicyStore ActiveStore -Confirm:$false -ErrorAction SilentlyContinue
-Remove-NetFirewallRule -DisplayName "%s" -ErrorAction SilentlyContinue
-Remove-NetFirewallRule -DisplayName "%s" -ErrorAction SilentlyContinue
-`,
-		qosPolicyName,
-		firewallRuleIn, firewallRuleOut,
-	)
+	meteredCheck := widget.NewCheck("Only when the connection is metered", nil)
+
+	logArea := newLogView(12)
 
-	cmd := exec.Command("powershell", "-NoProfile", "-ExecutionPolicy", "Bypass", "-Command", script)
-	out, err := cmd.CombinedOutput()
-	if len(out) > 0 {
-		log += "Output:\n" + string(out) + "\n"
+	// Safe log appender from any goroutine, using fyne.Do (Driver.DoFromGoroutine).
+	// Lines above the selected verbosity are dropped; the log files get them all.
+	verbosity := parseLogLevel(application.Preferences().String(logLevelPrefKey))
+	logAt := func(level logLevel, text string) {
+		fileLog.write(level.String(), text)
+		fyne.Do(func() {
+			if level > verbosity {
+				return
+			}
+			logArea.append(text)
+		})
 	}
-	if err != nil {
-		return log, fmt.Errorf("clearAllLimits error: %w", err)
+	appendLog := func(text string) { logAt(logNormal, text) }
+	logOutcome := func(text string) { logAt(logQuiet, text) }
+	// Log each step of an operation as its own entry, coloured by its severity
+	appendResult := func(res *OpResult) {
+		for _, s := range res.Steps {
+			fileLog.write(logNormal.String(), s.Text)
+			fyne.Do(func() {
+				if logNormal > verbosity {
+					return
+				}
+				logArea.appendAt(s.Text, s.Severity)
+			})
+		}
 	}
 
-	log += "ClearAllLimits: success\n"
-	return log, nil
-}
+	liveOutput = func(line string) { logAt(logVerbose, "  > "+line) }
+	exePathNote = appendLog
+	if err := configureFileLog(application.Preferences()); err != nil {
+		appendLog("Log file error: " + err.Error())
+	}
 
-// Apply QoS throttling for a given executable path
-func applyLimitForExe(exePath string, inKbps, outKbps int) (string, error) {
-	log := fmt.Sprintf("Applying speed limit for: %s\n", exePath)
+	logLevelSelect := widget.NewSelect(logLevelNames, func(name string) {
+		verbosity = parseLogLevel(name)
+		application.Preferences().SetString(logLevelPrefKey, name)
+	})
+	logLevelSelect.SetSelected(verbosity.String())
 
-	// Choose the lower non-zero limit
-	limitKbps := 0
-	if inKbps > 0 && outKbps > 0 {
-		if inKbps < outKbps {
-			limitKbps = inKbps
+	var updateElevation func()
+	dryRunCheck := widget.NewCheck("Dry run (log scripts, change nothing)", func(on bool) {
+		dryRun.Store(on)
+		if updateElevation != nil {
+			updateElevation()
+		}
+		if on {
+			appendLog("Dry run on: QoS and firewall scripts are logged instead of run, and rules aren't tracked")
 		} else {
-			limitKbps = outKbps
+			appendLog("Dry run off")
 		}
-	} else if inKbps > 0 {
-		limitKbps = inKbps
-	} else if outKbps > 0 {
-		limitKbps = outKbps
+	})
+	dryRunCheck.SetChecked(dryRun.Load())
+
+	// Record a state change in the audit log; failures only show in the GUI log
+	recordAudit := func(action, process, target string, params map[string]any, opErr error) {
+		auditOrLog(appendLog, action, process, target, params, opErr)
 	}
 
-	if limitKbps <= 0 {
-		return log, fmt.Errorf("limit must be > 0 to use QoS")
+	browseProcessesButton := widget.NewButton("Browse...", func() {
+		showProcessPicker(window, processes, func(name string) {
+			targetMode.SetSelected(targetModeProcess)
+			matchMode.SetSelected(matchModeExact)
+			processEntry.SetText(name)
+		})
+	})
+	browsePackagesButton := widget.NewButton("Browse Packages...", func() {
+		showPackagePicker(window, func(p appxPackage) {
+			targetMode.SetSelected(targetModePackage)
+			processEntry.SetText(p.PackageFamilyName)
+		})
+	})
+
+	// Keep the tracked rule list in sync with what was applied or cleared
+	trackRule := func(r LimitRule, opErr error) {
+		if err := tracked.recordResult(r, opErr); err != nil {
+			logOutcome("Could not save tracked rules: " + err.Error())
+		}
+	}
+	markPending := func(r LimitRule) {
+		if err := tracked.markPending(r); err != nil {
+			logOutcome("Could not save tracked rules: " + err.Error())
+		}
+	}
+	forgetRules := func() {
+		if err := tracked.clear(); err != nil {
+			logOutcome("Could not save tracked rules: " + err.Error())
+		}
 	}
 
-	bitsPerSecond := kbpsToBitsPerSecond(limitKbps)
-	log += fmt.Sprintf("Requested limit: %d kbps (~%d bits per second)\n", limitKbps, bitsPerSecond)
+	// Clear one rule's policies, logging exactly what went; isTracked is
+	// false for a target only cleared of leftovers
+	clearTarget := func(r LimitRule, isTracked bool) {
+		defer history.record("Clear "+r.describe(), tracked.list())
+		appendLog("----------------------------------------------------")
+		res := clearRule(r)
+		appendResult(res)
+		if err := res.Err; err != nil {
+			logOutcome("Clear error: " + err.Error())
+		} else if isTracked {
+			if err := tracked.remove(r.key()); err != nil {
+				logOutcome("Could not save tracked rules: " + err.Error())
+			}
+			logOutcome("Removed rule: " + r.describe())
+		} else {
+			logOutcome("No tracked rule for " + r.key() + "; removed any leftover policies for it")
+		}
+		recordAudit("clear", r.Process, r.ExePath, map[string]any{"scope": "target"}, res.Err)
+	}
 
-	script := fmt.Sprintf(`
-Remove-NetQosPolicy -Name "%s" -PolicyStore ActiveStore -Confirm:$false -ErrorAction SilentlyContinue
+	if err := tracked.load(); err != nil {
+		logOutcome("Could not load tracked rules: " + err.Error())
+	} else if n := len(tracked.list()); n > 0 {
+		appendLog(fmt.Sprintf("%d saved rule(s) loaded; QoS policies outside the persistent store don't survive a reboot, use Reapply Saved on the Rules tab to recreate them", n))
+	}
 
-New-NetQosPolicy -Name "%s" -AppPathNameMatchCondition "%s" -ThrottleRateActionBitsPerSecond %d -PolicyStore ActiveStore
-`,
-		qosPolicyName,
-		qosPolicyName,
-		escapeForPowerShell(exePath),
-		bitsPerSecond,
-	)
+	rulesTable, refreshRulesTable := newRulesTable(window, func(r LimitRule) { go clearTarget(r, true) }, func(r LimitRule, d time.Duration) {
+		go func() {
+			appendLog("----------------------------------------------------")
+			if d == 0 {
+				endBoost(r, "ended early", appendLog)
+				return
+			}
+			if err := startBoost(r, d, appendLog); err != nil {
+				logOutcome("Boost error: " + err.Error())
+			}
+		}()
+	}, func(r LimitRule, pause bool) {
+		go func() {
+			appendLog("----------------------------------------------------")
+			if !pause {
+				if err := resumeRule(r, appendLog); err != nil {
+					logOutcome("Resume error: " + err.Error())
+				}
+				return
+			}
+			if err := pauseRule(r, appendLog); err != nil {
+				logOutcome("Pause error: " + err.Error())
+			}
+		}()
+	}, func(r LimitRule) {
+		go func() {
+			appendLog("----------------------------------------------------")
+			testRuleConnectivity(r, logOutcome)
+		}()
+	})
+	refreshFavorites := func() {} // replaced once the favorites tab exists
+	statusBar, refreshStatusBar := liveStatusBar()
+	tracked.onChange = func() {
+		refreshStatusBar()
+		fyne.Do(func() {
+			refreshRulesTable()
+			refreshFavorites()
+			window.SetTitle(windowTitle(tracked.list()))
+		})
+	}
+	window.SetTitle(windowTitle(tracked.list()))
+	go watchUsage(func() { fyne.Do(refreshRulesTable) })
+	go func() {
+		restoreBoostsAtLaunch(appendLog)
+		watchBoosts(appendLog, func() { fyne.Do(refreshRulesTable) })
+	}()
 
-	cmd := exec.Command("powershell", "-NoProfile", "-ExecutionPolicy", "Bypass", "-Command", script)
-	out, err := cmd.CombinedOutput()
-	if len(out) > 0 {
-		log += "QoS output:\n" + string(out) + "\n"
+	// Ask before moving a rule to a process's new executable path
+	offerMigration := func(c pathChange) {
+		msg := fmt.Sprintf("%s now runs from a different path:\n\n%s\n\nThe rule still targets:\n\n%s\n\nMove the rule to the new path?",
+			c.Rule.Process, c.NewPath, c.Rule.ExePath)
+		dialog.ShowConfirm("Executable path changed", msg, func(ok bool) {
+			if !ok {
+				appendLog("Kept stale rule for " + c.Rule.Process + " (path changed to " + c.NewPath + ")")
+				return
+			}
+			go func() {
+				appendLog("----------------------------------------------------")
+				migrateLog, err := migrateRule(c)
+				appendLog(migrateLog)
+				if err != nil {
+					logOutcome("Migrate error: " + err.Error())
+				}
+				recordAudit("migrate", c.Rule.Process, c.NewPath, map[string]any{"oldPath": c.Rule.ExePath}, err)
+			}()
+		}, window)
 	}
-	if err != nil {
-		return log, fmt.Errorf("QoS error: %w", err)
+
+	// Periodically look for rules left pointing at an old executable path.
+	// Each change is offered once per session.
+	go func() {
+		offered := make(map[string]bool)
+		ticker := time.NewTicker(staleCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			for _, c := range findStaleRules(tracked.list(), processes) {
+				id := c.Rule.key() + "|" + strings.ToLower(c.NewPath)
+				if offered[id] {
+					continue
+				}
+				offered[id] = true
+				fyne.Do(func() { offerMigration(c) })
+			}
+		}
+	}()
+
+	// Ask the user from a worker goroutine and wait for the answer
+	confirmFromWorker := func(title, message, confirmText string) bool {
+		answer := make(chan bool, 1)
+		fyne.Do(func() {
+			d := dialog.NewConfirm(title, message, func(ok bool) { answer <- ok }, window)
+			d.SetConfirmText(confirmText)
+			d.Show()
+		})
+		return <-answer
 	}
 
-	log += "ApplyLimit: success\n"
-	return log, nil
-}
+	// Show the executable paths a rule resolved to and ask before applying,
+	// unless the user ticked "Don't ask again" earlier this session
+	var skipPathConfirm atomic.Bool
+	confirmPaths := func(procName string, paths []string) bool {
+		if skipPathConfirm.Load() {
+			return true
+		}
+		answer := make(chan bool, 1)
+		fyne.Do(func() {
+			msg := widget.NewLabel(fmt.Sprintf("%s resolved to:\n\n%s\n\nIs this the program to apply the rule to?", procName, strings.Join(paths, "\n")))
+			msg.Wrapping = fyne.TextWrapBreak
+			dontAsk := widget.NewCheck("Don't ask again this session", nil)
+			d := dialog.NewCustomConfirm("Confirm executable", "Apply", "Cancel", container.NewVBox(msg, dontAsk), func(ok bool) {
+				if ok && dontAsk.Checked {
+					skipPathConfirm.Store(true)
+				}
+				answer <- ok
+			}, window)
+			d.Resize(fyne.NewSize(520, 0))
+			d.Show()
+		})
+		return <-answer
+	}
 
-func main() {
-	application := app.New()
-	window := application.NewWindow("Windows NetLimiter GUI")
-	window.Resize(fyne.NewSize(600, 480))
+	// Note for a new rule: the entered text, or the note of the rule it replaces
+	ruleNote := func(procName, pkg string) string {
+		if note := strings.TrimSpace(noteEntry.Text); note != "" {
+			return note
+		}
+		key := (LimitRule{Process: procName, Package: pkg}).key()
+		if prev, ok := tracked.get(key); ok {
+			return prev.Note
+		}
+		return ""
+	}
 
-	processEntry := widget.NewEntry()
-	processEntry.SetPlaceHolder("Process name, e.g. chrome.exe")
+	// Resolve a regex to a single target after the user confirms the full match list.
+	// A rule targets one executable, so the first match with a path is used.
+	resolveRegexTarget := func(pattern string) (name, exePath string, ok bool) {
+		re, err := compileProcessRegex(pattern)
+		if err != nil {
+			logOutcome("Error: " + err.Error())
+			return "", "", false
+		}
+		matches, err := processes.FindByRegex(re)
+		if err != nil {
+			logOutcome("Error finding process: " + err.Error())
+			return "", "", false
+		}
 
-	inEntry := widget.NewEntry()
-	inEntry.SetPlaceHolder("Limit IN (kbps), 0 for block if both are 0")
+		var target *processMatch
+		for i := range matches {
+			if matches[i].ExePath != "" {
+				target = &matches[i]
+				break
+			}
+		}
+		if target == nil {
+			logOutcome(fmt.Sprintf("No process with an accessible path matches: %s (%d matches)", pattern, len(matches)))
+			return "", "", false
+		}
 
-	outEntry := widget.NewEntry()
-	outEntry.SetPlaceHolder("Limit OUT (kbps), 0 for block if both are 0")
+		list := formatMatches(matches, 30)
+		appendLog(fmt.Sprintf("Regex %s matched %d process(es):\n%s", pattern, len(matches), list))
+		msg := fmt.Sprintf("%d process(es) match:\n\n%s\nThe rule will target:\n%s\n\nContinue?", len(matches), list, target.ExePath)
+		if !confirmFromWorker("Confirm regex matches", msg, "Apply") {
+			logOutcome("Apply cancelled")
+			return "", "", false
+		}
+		return target.Name, target.ExePath, true
+	}
 
-	logArea := widget.NewMultiLineEntry()
-	logArea.SetPlaceHolder("Log output...")
-	logArea.Wrapping = fyne.TextWrapWord
-	logArea.SetMinRowsVisible(12)
+	// Warn about interacting rules before applying; returns the rules the new
+	// one replaces, or false when the user cancelled
+	checkConflicts := func(r LimitRule) ([]LimitRule, bool) {
+		conflicts := findConflicts(r, tracked.list())
+		if len(conflicts) == 0 {
+			return nil, true
+		}
+		msg := formatConflicts(conflicts)
+		appendLog(msg)
+		if !confirmFromWorker("Conflicting rules", msg, "Replace") {
+			logOutcome("Apply cancelled because of conflicting rules")
+			return nil, false
+		}
+		replaced := make([]LimitRule, len(conflicts))
+		for i, c := range conflicts {
+			replaced[i] = c.Existing
+		}
+		return replaced, true
+	}
 
-	// Safe log appender from any goroutine, using fyne.Do (Driver.DoFromGoroutine)
-	appendLog := func(text string) {
-		fyne.Do(func() {
-			logArea.SetText(logArea.Text + text + "\n")
-		})
+	// Warn about other tools' policies on the same executables, which can
+	// shadow ours or make a limit seem to have no effect; false when the
+	// user cancelled. A failed query only logs, it doesn't block the apply.
+	checkForeign := func(r LimitRule) bool {
+		if r.Package != "" || dryRun.Load() {
+			return true
+		}
+		var paths []string
+		for _, t := range r.exeTargets() {
+			paths = append(paths, t.Path)
+		}
+		foreign, err := backend.ForeignRules(paths)
+		if err != nil {
+			appendLog("Could not check for other tools' policies: " + err.Error())
+			return true
+		}
+		if len(foreign) == 0 {
+			return true
+		}
+		lines := make([]string, len(foreign))
+		for i, p := range foreign {
+			lines[i] = describeForeign(p)
+		}
+		list := strings.Join(lines, "\n")
+		appendLog("Warning: policies not created by this tool already target " + r.Process + ":\n" + list)
+		msg := fmt.Sprintf("Other policies already target %s:\n\n%s\n\nThey can override or stack with this rule, making it seem to have no effect. Apply anyway?", r.Process, list)
+		if !confirmFromWorker("Other policies found", msg, "Apply anyway") {
+			logOutcome("Apply cancelled because of other tools' policies")
+			return false
+		}
+		return true
 	}
 
-	applyButton := widget.NewButton("Apply Limit / Block", func() {
-		// Run heavy work in a goroutine to avoid freezing the UI
-		go func() {
-			appendLog("----------------------------------------------------")
+	// Metered-only rules wait as pending until the connection is metered.
+	// Returns true when the rule was deferred instead of applied.
+	waitForMetered := func(r LimitRule) bool {
+		if !r.MeteredOnly {
+			return false
+		}
+		metered, err := isConnectionMetered()
+		if err != nil {
+			logOutcome("Could not read metered state: " + err.Error())
+		}
+		return !metered
+	}
+
+	// Last rule applied this session, for the tray's "Reapply last rule"
+	var lastApplied atomic.Pointer[LimitRule]
 
-			procName := strings.TrimSpace(processEntry.Text)
-			if procName == "" {
-				appendLog("Error: process name is required")
+	// Apply, track and audit r after checking conflicts and services.
+	// Only r's previous rule and the rules it replaces are cleared first,
+	// unless the user chose to reset everything before each apply.
+	applyNewRule := func(r LimitRule) {
+		defer history.record("Apply "+r.describe(), tracked.list())
+		if r.Blocked && r.Package == "" && isCriticalProcess(r.Process) {
+			msg := fmt.Sprintf("%s is a core Windows process. Blocking its internet access can cut off networking for the whole machine "+
+				"(DNS, DHCP, updates, remote access) until the rule is cleared.\n\nBlock it anyway?", r.Process)
+			if !confirmFromWorker("Block a system process?", msg, "Block") {
+				logOutcome("Apply cancelled: " + r.Process + " is a core Windows process")
 				return
 			}
+		}
+		replaced, ok := checkConflicts(r)
+		if !ok || !checkForeign(r) || !ensureServices(r.Blocked, appendLog, confirmFromWorker) {
+			return
+		}
 
-			// Parse IN / OUT limits
-			parseInt := func(s string) (int, error) {
-				s = strings.TrimSpace(s)
-				if s == "" {
-					return 0, nil
+		// The target's own previous policies, plus those of the rules it replaces
+		clearAll := application.Preferences().Bool(resetBeforeApplyPrefKey)
+		var clearing []LimitRule
+		var keys []string
+		if !clearAll {
+			prev, ok := tracked.get(r.key())
+			if !ok {
+				prev = r
+			}
+			for _, old := range append([]LimitRule{prev}, replaced...) {
+				if slices.ContainsFunc(clearing, func(c LimitRule) bool { return c.policyKey() == old.policyKey() }) {
+					continue
+				}
+				clearing = append(clearing, old)
+				for _, t := range old.exeTargets() {
+					keys = append(keys, t.Key)
 				}
-				return strconv.Atoi(s)
 			}
-
-			inKbps, err := parseInt(inEntry.Text)
-			if err != nil {
-				appendLog("Error: Limit IN must be an integer")
+		}
+		cleared := func(err error) {
+			if clearAll {
+				if err != nil {
+					logOutcome("ClearAllLimits error: " + err.Error())
+				} else {
+					forgetRules()
+				}
+				recordAudit("clear", r.Process, "", nil, err)
 				return
 			}
-			outKbps, err := parseInt(outEntry.Text)
 			if err != nil {
-				appendLog("Error: Limit OUT must be an integer")
-				return
+				logOutcome("Clear error: " + err.Error())
+			}
+			for _, old := range clearing {
+				if err == nil && old.key() != r.key() {
+					if err := tracked.remove(old.key()); err != nil {
+						logOutcome("Could not save tracked rules: " + err.Error())
+					}
+				}
+				recordAudit("clear", old.Process, old.ExePath, nil, err)
 			}
+		}
 
-			// Find process
-			pids, err := findPIDsByName(procName)
+		// A metered-only rule waiting for a metered connection only clears for now
+		if waitForMetered(r) {
+			var err error
+			if clearAll {
+				res := backend.ClearAll()
+				appendResult(res)
+				err = res.Err
+			} else {
+				for _, old := range clearing {
+					res := clearRule(old)
+					appendResult(res)
+					err = errors.Join(err, res.Err)
+				}
+			}
+			cleared(err)
+			markPending(r)
+			appendLog("Connection is not metered; rule will apply when it is: " + r.describe())
+			return
+		}
+
+		markPending(r)
+		res, clearErr := backend.ApplyReplacing(keys, clearAll, r)
+		appendResult(res)
+		cleared(clearErr)
+		err := res.Err
+
+		action, target := "limit", r.ExePath
+		params := map[string]any{"inKbps": r.InKbps, "outKbps": r.OutKbps, "priority": string(r.Priority)}
+		if r.Blocked {
+			action, params = "block", nil
+		}
+		if r.Package != "" {
+			target = r.Package
+			params = map[string]any{"mode": "package", "inKbps": r.InKbps, "outKbps": r.OutKbps}
+		}
+		if err != nil {
+			logOutcome("Apply error: " + err.Error())
+			if hint := remediation(err); hint != "" {
+				logOutcome("  " + hint)
+			}
+		} else if dryRun.Load() {
+			logOutcome("Dry run, nothing changed: " + r.describe())
+		} else {
+			logOutcome("Applied: " + r.describe())
+			lastApplied.Store(&r)
+		}
+		trackRule(r, err)
+		recordAudit(action, r.Process, target, params, err)
+	}
+
+	// Limit the app the user was last using, at the quick-limit rate, after confirming
+	quickLimitForeground := func() {
+		appendLog("----------------------------------------------------")
+		r, err := quickLimitRule(processes, quickLimitKbps(application.Preferences()))
+		if err != nil {
+			logOutcome("Quick-limit error: " + err.Error())
+			return
+		}
+		r.Note = ruleNote(r.Process, "")
+		appendLog("Quick-limit target: " + r.ExePath)
+
+		fyne.Do(window.Show)
+		msg := fmt.Sprintf("Limit the app you were using?\n\n%s\n\nIN %d / OUT %d kbps", r.ExePath, r.InKbps, r.OutKbps)
+		if !confirmFromWorker("Quick-limit foreground app", msg, "Limit") {
+			logOutcome("Quick-limit cancelled")
+			return
+		}
+		applyNewRule(r)
+	}
+
+	// Remove every limit and block, then forget the tracked rules. Runs
+	// PowerShell, so callers run it off the UI thread.
+	clearAllRules := func() {
+		cleared := tracked.list()
+		defer history.record("Clear all", cleared)
+		res := backend.ClearAll()
+		err := res.Err
+		appendLog("----------------------------------------------------")
+		appendResult(res)
+		if err != nil {
+			logOutcome("ClearAllLimits error: " + err.Error())
+		} else {
+			forgetRules()
+			logOutcome("Cleared all rules")
+			if dryRun.Load() {
+				logOutcome("Dry run, nothing changed")
+			}
+		}
+		recordAudit("clear", "", "", nil, err)
+
+		if err == nil && application.Preferences().Bool(verifyAfterClearPrefKey) {
+			verifyClear(cleared, appendLog)
+		}
+	}
+
+	// Apply the last rule applied this session again, e.g. after a tray Clear all
+	reapplyLast := func() {
+		appendLog("----------------------------------------------------")
+		r := lastApplied.Load()
+		if r == nil {
+			logOutcome("Nothing was applied this session to reapply")
+			return
+		}
+		appendLog("Reapplying last rule: " + r.describe())
+		applyNewRule(*r)
+	}
+
+	go lastForeground.watch()
+
+	// The tray menu gains a "Throttle ..." item for the latest busy process;
+	// notifications can't carry actions, so this is where the alert leads
+	setTrayMenu := func(busy *busyProcess) {
+		desk, ok := application.(desktop.App)
+		if !ok {
+			return
+		}
+		items := []*fyne.MenuItem{fyne.NewMenuItem("Limit foreground app", func() { go quickLimitForeground() })}
+		if busy != nil {
+			name := busy.Name
+			items = append(items, fyne.NewMenuItem("Throttle "+name, func() {
+				targetMode.SetSelected(targetModeProcess)
+				matchMode.SetSelected(matchModeExact)
+				processEntry.SetText(name)
+				window.Show()
+				appendLog("Prefilled busy process: " + name + " (set the limits and apply)")
+			}))
+		}
+		items = append(items,
+			fyne.NewMenuItemSeparator(),
+			fyne.NewMenuItem("Reapply last rule", func() { go reapplyLast() }),
+			fyne.NewMenuItem("Clear all limits", func() { go clearAllRules() }),
+			fyne.NewMenuItemSeparator(),
+			fyne.NewMenuItem("Show window", window.Show),
+		)
+		desk.SetSystemTrayMenu(fyne.NewMenu("NetLimiter", items...))
+	}
+	setTrayMenu(nil)
+	closeToTray(window, application)
+	go watchBusyProcesses(application.Preferences(), func(b busyProcess) {
+		appendLog("High network activity: " + b.message())
+		application.SendNotification(fyne.NewNotification("High network activity", b.message()))
+		fyne.Do(func() { setTrayMenu(&b) })
+	})
+
+	go watchMetered(appendLog)
+	go watchSSID(application.Preferences(), appendLog)
+	go watchStatusFile(application.Preferences(), appendLog)
+	go watchCurfews(application.Preferences(), appendLog)
+	go watchDataCaps(application.Preferences(), appendLog, func(c dataCap) {
+		application.SendNotification(fyne.NewNotification("Daily data cap reached",
+			fmt.Sprintf("%s used its %d MB for today and is blocked until midnight.", c.Process, c.CapMB)))
+	})
+	go watchRestarts(appendLog)
+
+	// Stop sampling once the window is gone, saving the form for next time
+	liveRateCtx, stopLiveRate := context.WithCancel(context.Background())
+	defer stopLiveRate()
+	window.SetOnClosed(func() {
+		stopLiveRate()
+		size := window.Canvas().Size()
+		saveFormState(application.Preferences(), formState{
+			Width: size.Width, Height: size.Height, Process: processEntry.Text,
+			In: inEntry.Text, InUnit: inUnit.Selected, Out: outEntry.Text, OutUnit: outUnit.Selected,
+		})
+	})
+	go liveRate.run(liveRateCtx, processes, func(text string) {
+		fyne.Do(func() { liveRateLabel.SetText(text) })
+	})
+
+	// The power event subscription is a child PowerShell process; stop it on exit
+	powerCtx, stopPowerWatch := context.WithCancel(context.Background())
+	defer stopPowerWatch()
+	go func() {
+		err := watchPowerResume(powerCtx, func() {
+			time.Sleep(resumeSettleDelay)
+			reapplyAfterResume(appendLog)
+		})
+		if err != nil {
+			logOutcome("Sleep/resume watcher unavailable: " + err.Error())
+		}
+	}()
+
+	// Report stopped services up front rather than on the first failed apply
+	go func() {
+		report, err := backend.CheckServices()
+		if err != nil {
+			logOutcome("Could not check required services: " + err.Error())
+			return
+		}
+		if problem := report.qosProblem(); problem != "" {
+			appendLog("QoS limiting is unavailable: " + problem)
+		}
+		if !report.QosCmdlets {
+			// Only blocking can work, so leave the form in its 0/0 block state
+			fyne.Do(func() {
+				setLimitFields(0, 0)
+				for _, w := range []fyne.Disableable{inEntry, inUnit, outEntry, outUnit, prioritySelect} {
+					w.Disable()
+				}
+				dialog.ShowInformation("Limits unavailable",
+					"This Windows edition has no NetQos cmdlets (New-NetQosPolicy), which Windows Home lacks.\n"+
+						"Speed limits and priorities can't be applied; blocking an app still works.", window)
+			})
+		}
+		if problem := report.firewallProblem(); problem != "" {
+			appendLog("Firewall blocking is unavailable: " + problem)
+		}
+		if stopped := report.stopped(serviceBFE, serviceFirewall); len(stopped) > 0 {
+			offerServiceStart(stopped, appendLog, confirmFromWorker)
+		}
+	}()
+
+	// Parse a limit field into kbps; "50%" is taken of the link capacity in
+	// that direction (up for OUT). Runs on the apply goroutine.
+	resolveLimit := func(field, text string, unit rateUnit, up bool) (int, error) {
+		pct, isPercent, err := parsePercent(text)
+		if err != nil {
+			return 0, err
+		}
+		if !isPercent {
+			kbps, err := parseLimit(text, unit)
 			if err != nil {
-				appendLog("Error finding process: " + err.Error())
-				return
+				return 0, fmt.Errorf("Limit %s: %w (enter kbps/Mbps or a percentage)", field, err)
 			}
-			if len(pids) == 0 {
-				appendLog("No process found with name: " + procName)
+			return kbps, nil
+		}
+		capacity, source, err := linkCapacityKbps(application.Preferences(), up)
+		if err != nil {
+			return 0, fmt.Errorf("Limit %s of %d%%: %w", field, pct, err)
+		}
+		kbps := percentOfKbps(pct, capacity)
+		appendLog(fmt.Sprintf("Limit %s: %d%% of %d kbps (%s) = %d kbps", field, pct, capacity, source, kbps))
+		return kbps, nil
+	}
+
+	applyButton := widget.NewButton("Apply Limit / Block", func() {
+		// Run heavy work in a goroutine to avoid freezing the UI
+		go func() {
+			appendLog("----------------------------------------------------")
+
+			packageMode := targetMode.Selected == targetModePackage
+			mode := matchMode.Selected
+			if packageMode {
+				mode = matchModeExact
+			}
+			procName, err := sanitizeTarget(processEntry.Text, mode)
+			if err != nil {
+				logOutcome("Error: " + err.Error())
 				return
 			}
 
-			p, err := process.NewProcess(pids[0])
+			// Parse IN / OUT limits into kbps
+			inKbps, err := resolveLimit("IN", inEntry.Text, rateUnit(inUnit.Selected), false)
+			if err != nil {
+				logOutcome("Error: " + err.Error())
+				return
+			}
+			outKbps, err := resolveLimit("OUT", outEntry.Text, rateUnit(outUnit.Selected), true)
 			if err != nil {
-				appendLog("Error reading process info: " + err.Error())
+				logOutcome("Error: " + err.Error())
 				return
 			}
-			exePath, err := p.Exe()
-			if err != nil || exePath == "" {
-				appendLog("Could not get executable path for process")
+			if err := checkInboundLimit(inKbps, outKbps); err != nil {
+				logOutcome("Error: " + err.Error())
 				return
 			}
 
-			appendLog("Process path: " + exePath)
+			// Only blocks (IN and OUT both 0) can be narrowed
+			scope := blockScope{RemoteAddress: strings.TrimSpace(remoteAddrEntry.Text), Protocol: protocolSelect.Selected}
+			if err := scope.validate(); err != nil {
+				logOutcome("Error: " + err.Error())
+				return
+			}
+			if scope.Protocol == blockProtocols[0] {
+				scope.Protocol = ""
+			}
 
-			// Clear previous rules/policies
-			if clearLog, err := clearAllLimits(); err != nil {
-				appendLog(clearLog)
-				appendLog("ClearAllLimits error: " + err.Error())
-			} else {
-				appendLog(clearLog)
+			// UWP packages are matched by family name, not by a running process
+			applyPackage := func(pfn string) {
+				if scope.scoped() {
+					appendLog("Remote address and protocol are not supported for UWP packages; blocking all of its traffic")
+				}
+				newRule := LimitRule{
+					Process: pfn, Package: pfn,
+					InKbps: inKbps, OutKbps: outKbps, Blocked: inKbps == 0 && outKbps == 0,
+					MeteredOnly: meteredCheck.Checked, Note: ruleNote(pfn, pfn),
+				}
+				if qosPriority(prioritySelect.Selected).active() {
+					appendLog("Priority is not supported for UWP packages; applying the rate limit only")
+				}
+				applyNewRule(newRule)
+			}
+			if packageMode {
+				applyPackage(procName)
+				return
 			}
 
-			// If both IN and OUT are 0: block internet
-			if inKbps == 0 && outKbps == 0 {
-				blockLog, err := blockInternetForProcess(exePath)
-				appendLog(blockLog)
+			// Find process
+			var exePath string
+			var extraPaths []string
+			var roots []int32 // matched PIDs, for Include child processes
+			if matchMode.Selected == matchModePath {
+				if canonical, note := canonicalExePath(processes, procName); note != "" {
+					appendLog(note)
+					procName = canonical
+				}
+				exePath, err = resolveExePath(procName)
+				if err != nil {
+					logOutcome("Error: " + err.Error())
+					return
+				}
+				procName = filepath.Base(exePath)
+			} else if matchMode.Selected == matchModeRegex {
+				var ok bool
+				procName, exePath, ok = resolveRegexTarget(procName)
+				if !ok {
+					return
+				}
+			} else if pid, ok := parsePID(procName); ok {
+				exePath, err = resolveExeByPID(processes, pid)
 				if err != nil {
-					appendLog("BlockInternet error: " + err.Error())
+					logOutcome("Error: " + err.Error())
+					return
 				}
+				procName = filepath.Base(exePath)
+				roots = []int32{pid}
+				appendLog(fmt.Sprintf("PID %d is %s; the rule covers every process running this executable", pid, procName))
 			} else {
-				// Otherwise: apply QoS limit
-				limitLog, err := applyLimitForExe(exePath, inKbps, outKbps)
-				appendLog(limitLog)
+				procName = namePattern(mode, procName)
+				pids, err := processes.FindPIDsByName(procName)
+				if err != nil {
+					logOutcome("Error finding process: " + err.Error())
+					return
+				}
+				if len(pids) == 0 {
+					logOutcome("No process found with name: " + procName)
+					return
+				}
+				if netlimiter.IsNamePattern(procName) {
+					if matched, err := describePatternMatches(processes, procName); err == nil {
+						appendLog(matched)
+					}
+				}
+
+				if filter := strings.TrimSpace(cmdlineEntry.Text); filter != "" {
+					matches := filterByCommandLine(processes, pids, filter)
+					appendLog(fmt.Sprintf("%d of %d %s instance(s) include %q in the command line or working directory:", len(matches), len(pids), procName, filter))
+					for _, m := range matches {
+						appendLog(fmt.Sprintf("  %d  %s  (in %s)", m.PID, m.Cmdline, m.Cwd))
+					}
+					if len(matches) == 0 {
+						return
+					}
+					pids = pids[:0]
+					for _, m := range matches {
+						pids = append(pids, m.PID)
+					}
+				}
+
+				instances := sortInstancesByStart(processes, pids)
+				chosen := selectInstances(instances, instanceSelect.Selected)
+				if len(instances) > 1 {
+					list := formatInstances(instances, chosen)
+					appendLog(fmt.Sprintf("%d instances of %s (%s):\n%s", len(instances), procName, strings.ToLower(instanceSelect.Selected), list))
+					msg := fmt.Sprintf("%d instances of %s are running; the marked ones will be targeted:\n\n%s\nContinue?", len(instances), procName, list)
+					if !confirmFromWorker("Confirm instances", msg, "Apply") {
+						logOutcome("Apply cancelled")
+						return
+					}
+				}
+
+				// Policies key on the executable path, so collect every distinct
+				// path the chosen instances run from and cover each one
+				paths, err := distinctExePaths(processes, chosen)
+				if err != nil {
+					logOutcome("Could not get executable path for process: " + err.Error())
+					return
+				}
+				appendLog(fmt.Sprintf("%d distinct executable path(s) for %s", len(paths), procName))
+				exePath, extraPaths = paths[0], paths[1:]
+				for _, inst := range chosen {
+					roots = append(roots, inst.PID)
+				}
+			}
+
+			// Store apps run from WindowsApps inside an AppContainer, where
+			// path-based policies often miss their traffic
+			if pfn, ok := packageFamilyFromPath(exePath); ok {
+				appendLog(fmt.Sprintf("%s is a UWP/Store app from package %s; use the UWP package target to limit it by package", procName, pfn))
+				msg := fmt.Sprintf("%s is a UWP/Store app (package %s).\n\nRules on its executable path may not catch its traffic. Target the package instead?", exePath, pfn)
+				if confirmFromWorker("UWP app", msg, "Use package") {
+					fyne.Do(func() {
+						targetMode.SetSelected(targetModePackage)
+						processEntry.SetText(pfn)
+					})
+					applyPackage(pfn)
+					return
+				}
+				appendLog("Keeping the executable path target")
+			}
+
+			if childrenCheck.Checked {
+				if roots == nil {
+					roots, err = pidsRunning(processes, exePath)
+					if err != nil {
+						appendLog("Could not list processes for child processes: " + err.Error())
+					}
+				}
+				if len(roots) == 0 {
+					appendLog(procName + " is not running, so there are no child processes to include")
+				} else {
+					extraPaths = append(extraPaths, includeChildProcesses(processes, roots, append([]string{exePath}, extraPaths...), appendLog)...)
+				}
+			}
+
+			for _, path := range append([]string{exePath}, extraPaths...) {
+				appendLog("Process path: " + path)
+				info, err := queryExeInfo(path)
 				if err != nil {
-					appendLog("ApplyLimit error: " + err.Error())
+					appendLog(err.Error())
+					continue
+				}
+				appendLog(info.describe())
+				if !info.signed() {
+					msg := fmt.Sprintf("Caution: this executable is not validly signed, so it may not be the app its name suggests.\n\n%s\n\n%s\n\nApply anyway?", path, info.describe())
+					if !confirmFromWorker("Unsigned executable", msg, "Apply") {
+						logOutcome("Apply cancelled")
+						return
+					}
 				}
 			}
+			if !confirmPaths(procName, append([]string{exePath}, extraPaths...)) {
+				logOutcome("Apply cancelled")
+				return
+			}
+			if prev, ok := tracked.get(strings.ToLower(procName)); ok && prev.ExePath != "" && !strings.EqualFold(prev.ExePath, exePath) {
+				appendLog("Executable path changed since the rule was applied, replacing it")
+				appendLog("  old path: " + prev.ExePath)
+				appendLog("  new path: " + exePath)
+			}
+
+			priority := qosPriority(prioritySelect.Selected)
+			newRule := LimitRule{
+				Process: procName, ExePath: exePath, ExtraPaths: extraPaths, Priority: priority,
+				InKbps: inKbps, OutKbps: outKbps, Blocked: inKbps == 0 && outKbps == 0 && !priority.active(),
+				MeteredOnly: meteredCheck.Checked, WatchRestart: watchRestartCheck.Checked,
+				NameOnly: nameOnlyCheck.Checked, Note: ruleNote(procName, ""),
+			}
+			if newRule.Blocked {
+				newRule.RemoteAddr, newRule.Protocol = scope.RemoteAddress, scope.Protocol
+			} else if scope.scoped() {
+				appendLog("Remote address and protocol only narrow blocks; set IN and OUT to 0 to block")
+			}
+			applyNewRule(newRule)
 		}()
 	})
 
-	clearLimitButton := widget.NewButton("Clear Limit", func() {
+	// Apply the form's limits, priority and options to each queued executable
+	// as its own path rule, after one confirmation. Returns the paths it tried;
+	// a limit the form can't parse leaves the queue as it is.
+	applyQueued := func(paths []string) []string {
+		appendLog("----------------------------------------------------")
+		inKbps, err := resolveLimit("IN", inEntry.Text, rateUnit(inUnit.Selected), false)
+		if err != nil {
+			logOutcome("Error: " + err.Error())
+			return nil
+		}
+		outKbps, err := resolveLimit("OUT", outEntry.Text, rateUnit(outUnit.Selected), true)
+		if err != nil {
+			logOutcome("Error: " + err.Error())
+			return nil
+		}
+		if err := checkInboundLimit(inKbps, outKbps); err != nil {
+			logOutcome("Error: " + err.Error())
+			return nil
+		}
+		priority := qosPriority(prioritySelect.Selected)
+		blocked := inKbps == 0 && outKbps == 0 && !priority.active()
+		what := fmt.Sprintf("IN %d / OUT %d kbps", inKbps, outKbps)
+		if blocked {
+			what = "a block"
+		}
+		msg := fmt.Sprintf("Apply %s to %d queued executable(s)?\n\n%s", what, len(paths), strings.Join(paths, "\n"))
+		if !confirmFromWorker("Apply to queued executables", msg, "Apply") {
+			logOutcome("Apply cancelled")
+			return nil
+		}
+		for _, path := range paths {
+			exePath, err := resolveExePath(path)
+			if err != nil {
+				logOutcome("Error: " + err.Error())
+				continue
+			}
+			procName := filepath.Base(exePath)
+			applyNewRule(LimitRule{
+				Process: procName, ExePath: exePath, Priority: priority,
+				InKbps: inKbps, OutKbps: outKbps, Blocked: blocked,
+				MeteredOnly: meteredCheck.Checked, WatchRestart: watchRestartCheck.Checked,
+				NameOnly: nameOnlyCheck.Checked, Note: ruleNote(procName, ""),
+			})
+		}
+		return paths
+	}
+	queue, queuePanel := newBatchQueue(applyQueued)
+
+	clearLimitButton := widget.NewButton("Clear All", func() {
 		// Run in goroutine as it calls PowerShell too
+		go clearAllRules()
+	})
+
+	// Clear only the target in the Process Name field, logging exactly what went
+	clearCurrentTarget := func() {
+		name := strings.TrimSpace(processEntry.Text)
+		if name == "" {
+			logOutcome("Error: enter the process to clear first")
+			return
+		}
+		r := LimitRule{Process: name}
+		switch {
+		case targetMode.Selected == targetModePackage:
+			r = LimitRule{Process: name, Package: name}
+		case matchMode.Selected == matchModePath:
+			r = LimitRule{Process: filepath.Base(name), ExePath: name}
+		default:
+			r = LimitRule{Process: namePattern(matchMode.Selected, name)}
+		}
+		prev, isTracked := tracked.get(r.key())
+		if isTracked {
+			r = prev
+		}
+
+		go clearTarget(r, isTracked)
+	}
+	clearTargetButton := widget.NewButton("Clear This Target", clearCurrentTarget)
+	clearTargetShortcut := &desktop.CustomShortcut{KeyName: fyne.KeyDelete, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}
+	window.Canvas().AddShortcut(clearTargetShortcut, func(fyne.Shortcut) { clearCurrentTarget() })
+
+	undoButton := widget.NewButton("Undo", func() {
 		go func() {
-			logText, err := clearAllLimits()
 			appendLog("----------------------------------------------------")
-			appendLog(logText)
-			if err != nil {
-				appendLog("ClearAllLimits error: " + err.Error())
+			history.undoLast(logOutcome)
+		}()
+	})
+	redoButton := widget.NewButton("Redo", func() {
+		go func() {
+			appendLog("----------------------------------------------------")
+			history.redoLast(logOutcome)
+		}()
+	})
+
+	reapplySavedButton := widget.NewButton("Reapply Saved", func() {
+		go func() {
+			appendLog("----------------------------------------------------")
+			reapplySavedRules(appendLog)
+		}()
+	})
+
+	pauseAllButton := widget.NewButton("Pause All", func() {
+		go func() {
+			appendLog("----------------------------------------------------")
+			pauseAllRules(appendLog)
+		}()
+	})
+	resumeAllButton := widget.NewButton("Resume All", func() {
+		go func() {
+			appendLog("----------------------------------------------------")
+			resumeAllRules(appendLog)
+		}()
+	})
+
+	// Without elevation every change fails, so the buttons making one are disabled
+	elevationLabel := widget.NewLabel("")
+	restartAdminButton := widget.NewButton("Restart as Admin", func() {
+		if err := relaunchElevated(); err != nil {
+			logOutcome(err.Error())
+			return
+		}
+		application.Quit()
+	})
+	elevationRow := container.NewHBox(elevationLabel, restartAdminButton)
+	updateElevation = func() {
+		missing := elevationMissing()
+		for _, b := range []*widget.Button{applyButton, queue.applyButton, clearTargetButton, clearLimitButton, undoButton, redoButton, reapplySavedButton, pauseAllButton, resumeAllButton} {
+			if missing {
+				b.Disable()
+			} else {
+				b.Enable()
 			}
+		}
+		switch {
+		case !backendNeedsElevation:
+			elevationRow.Hide()
+		case isElevated():
+			elevationLabel.SetText("Running as Administrator.")
+			restartAdminButton.Hide()
+		case missing:
+			elevationLabel.SetText("Not running as Administrator: Apply and Clear are disabled.")
+		default:
+			elevationLabel.SetText("Not running as Administrator; a dry run needs no rights.")
+		}
+	}
+	updateElevation()
+
+	verifyButton := widget.NewButton("Verify Live State", func() {
+		go func() {
+			appendLog("----------------------------------------------------")
+			verifyLiveState(appendLog)
 		}()
 	})
 
+	curfewsButton := widget.NewButton("Curfews...", func() {
+		showCurfews(window, application.Preferences(), appendLog)
+	})
+
+	dataCapsButton := widget.NewButton("Data Caps...", func() {
+		showDataCaps(window, application.Preferences(), appendLog)
+	})
+
+	importRulesButton := widget.NewButton("Import Rules...", func() {
+		showImportRules(window, appendLog)
+	})
+
+	ssidProfilesButton := widget.NewButton("Wi-Fi Rule Sets...", func() {
+		showSSIDProfiles(window, application.Preferences(), appendLog)
+	})
+
+	exportConfigButton := widget.NewButton("Export Config...", func() {
+		showExportConfig(window, application.Preferences(), appendLog)
+	})
+
+	importConfigButton := widget.NewButton("Import Config...", func() {
+		showImportConfig(window, application.Preferences(), appendLog)
+	})
+
+	profilesButton := widget.NewButton("Profiles...", func() {
+		showProfiles(window, application.Preferences(), appendLog)
+	})
+
+	exportScriptButton := widget.NewButton("Export as .ps1", func() {
+		exportRulesScript(window, tracked.list(), appendLog)
+	})
+
+	exportAuditButton := widget.NewButton("Export Audit Log", func() {
+		dialog.ShowFileSave(func(w fyne.URIWriteCloser, err error) {
+			if err != nil || w == nil {
+				return
+			}
+			defer w.Close()
+			if err := audit.export(w); err != nil {
+				logOutcome("Export audit log error: " + err.Error())
+				return
+			}
+			appendLog("Audit log exported to: " + w.URI().Path())
+		}, window)
+	})
+
+	// Preset buttons only fill the fields; the user still picks the target and applies
+	presetRow := container.NewHBox()
+	buildPresetRow := func() {
+		presetRow.RemoveAll()
+		for _, p := range loadPresets(application.Preferences()) {
+			presetRow.Add(widget.NewButton(p.Name, func() {
+				setLimitFields(p.InKbps, p.OutKbps)
+			}))
+		}
+	}
+	buildPresetRow()
+
+	selfTestButton := widget.NewButton("Self-Test", func() {
+		showSelfTest(window, appendLog)
+	})
+
+	// First launch: check the environment before the user tries a real rule
+	if !application.Preferences().Bool(selfTestDonePrefKey) {
+		application.Preferences().SetBool(selfTestDonePrefKey, true)
+		showSelfTest(window, appendLog)
+	}
+
+	settingsButton := widget.NewButton("Settings...", func() {
+		showSettings(window, application.Preferences(), appendLog, buildPresetRow)
+	})
+
 	clearLogButton := widget.NewButton("Clear Log", func() {
-		fyne.Do(func() {
-			logArea.SetText("")
-		})
+		fyne.Do(logArea.clear)
+	})
+	copyLogButton := widget.NewButton("Copy Log", func() {
+		application.Clipboard().SetContent(logArea.String())
+	})
+
+	// Keyboard shortcuts press the buttons, so they run the same handlers and
+	// respect a button disabled for lack of elevation. Canvas shortcuts don't
+	// fire while an entry has focus, so Ctrl+L and Ctrl+Shift+C never clear
+	// anything mid-typing; Apply is a menu shortcut so Ctrl+Enter submits from
+	// the fields too.
+	applyShortcut := &desktop.CustomShortcut{KeyName: fyne.KeyReturn, Modifier: fyne.KeyModifierShortcutDefault}
+	window.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyL, Modifier: fyne.KeyModifierShortcutDefault}, func(fyne.Shortcut) { tapButton(clearLogButton) })
+	window.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyC, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}, func(fyne.Shortcut) { tapButton(clearLimitButton) })
+	shortcutsLabel := widget.NewLabel("Shortcuts: Ctrl+Enter apply, Ctrl+Shift+Delete clear this target, Ctrl+Shift+C clear all, Ctrl+L clear log (the last two not while typing in a field)")
+	shortcutsLabel.Wrapping = fyne.TextWrapWord
+	shortcutsLabel.Importance = widget.LowImportance
+
+	// Kill stuck PowerShell runs; their operations then fail as cancelled
+	cancelRunsButton := widget.NewButton("Cancel", func() {
+		if n := psRuns.cancelAll(); n > 0 {
+			logOutcome(fmt.Sprintf("Cancelling %d PowerShell run(s)", n))
+		} else {
+			appendLog("No PowerShell run in progress")
+		}
 	})
 
+	// Picks which instance's executable is used; QoS and firewall rules still
+	// key on the path, so instances sharing one path are limited together
+	blockScopeItem := widget.NewFormItem("Block only", container.NewBorder(nil, nil, nil, protocolSelect, remoteAddrEntry))
+	blockScopeItem.HintText = "Remote IPs/subnets and protocol a block applies to; other traffic flows normally"
+
+	cmdlineItem := widget.NewFormItem("Must include in command line", cmdlineEntry)
+	cmdlineItem.HintText = "Matches the command line or working directory"
+
+	inLimitItem := widget.NewFormItem("Limit IN", container.NewBorder(nil, nil, nil, inUnit, inEntry))
+	inLimitItem.HintText = "Not enforced: Windows QoS only throttles outbound traffic. An IN limit without an OUT limit is refused."
+
+	queueAccordion := widget.NewAccordion(widget.NewAccordionItem("Batch queue: executables to apply the settings above to", queuePanel))
+
 	form := container.NewVBox(
 		widget.NewLabel("Windows NetLimiter (GUI)"),
-		widget.NewLabel("Run this program as Administrator."),
+		elevationRow,
+		widget.NewLabel("Backend: "+backend.Name()),
 		widget.NewSeparator(),
 		widget.NewForm(
-			widget.NewFormItem("Process Name", processEntry),
-			widget.NewFormItem("Limit IN (kbps)", inEntry),
-			widget.NewFormItem("Limit OUT (kbps)", outEntry),
+			widget.NewFormItem("Target", targetMode),
+			widget.NewFormItem("Process Name", container.NewBorder(nil, nil, nil, container.NewHBox(browseProcessesButton, browsePackagesButton), processEntry)),
+			widget.NewFormItem("Match", matchMode),
+			cmdlineItem,
+			widget.NewFormItem("Instances", instanceSelect),
+			widget.NewFormItem("", coverageLabel),
+			widget.NewFormItem("Current rate", liveRateLabel),
+			inLimitItem,
+			widget.NewFormItem("Limit OUT", container.NewBorder(nil, nil, nil, outUnit, outEntry)),
+			widget.NewFormItem("Priority", prioritySelect),
+			blockScopeItem,
+			widget.NewFormItem("Options", container.NewVBox(meteredCheck, watchRestartCheck, nameOnlyCheck, childrenCheck, systemBlocksCheck)),
+			widget.NewFormItem("Note", noteEntry),
+		),
+		presetRow,
+		container.NewHBox(applyButton, clearTargetButton, clearLimitButton, undoButton, redoButton, clearLogButton, copyLogButton, cancelRunsButton, exportScriptButton, exportAuditButton, selfTestButton, settingsButton),
+		shortcutsLabel,
+		queueAccordion,
+		widget.NewAccordion(
+			widget.NewAccordionItem("Advanced: latency proxy (proxy backend only)", proxyPanel(appendLog)),
+			widget.NewAccordionItem("Advanced: shared budget for several processes", budgetPanel(appendLog)),
+			widget.NewAccordionItem("Advanced: measure link speed", speedTestPanel(application.Preferences(), appendLog)),
+			widget.NewAccordionItem("Advanced: allowlist mode (block everything except listed apps)", allowlistPanel(window, application.Preferences(), appendLog)),
 		),
-		container.NewHBox(applyButton, clearLimitButton, clearLogButton),
 		widget.NewSeparator(),
-		widget.NewLabel("Log:"),
-		logArea,
+		container.NewHBox(widget.NewLabel("Log:"), logLevelSelect, dryRunCheck),
+		logArea.scroll,
+	)
+
+	// Dropping an .exe from Explorer targets it by path
+	window.SetOnDropped(func(_ fyne.Position, uris []fyne.URI) {
+		var exes []string
+		for _, u := range uris {
+			path := filepath.FromSlash(u.Path())
+			if !strings.EqualFold(filepath.Ext(path), ".exe") {
+				appendLog("Ignored dropped file (not an .exe): " + u.Name())
+				continue
+			}
+			exes = append(exes, path)
+		}
+		if len(exes) == 0 {
+			return
+		}
+
+		targetMode.SetSelected(targetModeProcess)
+		matchMode.SetSelected(matchModePath)
+		processEntry.SetText(exes[0])
+		appendLog("Dropped target: " + exes[0])
+		if len(exes) > 1 {
+			added := queue.add(exes[1:])
+			appendLog(fmt.Sprintf("Added %d other dropped executable(s) to the batch queue:", len(added)))
+			for _, exe := range added {
+				appendLog("  " + exe)
+			}
+			queueAccordion.Open(0)
+		}
+	})
+
+	loadFavorite := func(f favorite) {
+		targetMode.SetSelected(targetModeProcess)
+		matchMode.SetSelected(matchModeExact)
+		processEntry.SetText(f.Process)
+		if f.HasLimits {
+			setLimitFields(f.InKbps, f.OutKbps)
+		}
+	}
+	currentFavorite := func() (favorite, error) {
+		name := strings.TrimSpace(processEntry.Text)
+		if targetMode.Selected != targetModeProcess || matchMode.Selected != matchModeExact || name == "" {
+			return favorite{}, errors.New("favorites need an exact process name on the Limit tab")
+		}
+		f := favorite{Process: name}
+		in, errIn := parseLimit(inEntry.Text, rateUnit(inUnit.Selected))
+		out, errOut := parseLimit(outEntry.Text, rateUnit(outUnit.Selected))
+		if errIn == nil && errOut == nil && strings.TrimSpace(inEntry.Text) != "" && strings.TrimSpace(outEntry.Text) != "" {
+			f.HasLimits, f.InKbps, f.OutKbps = true, in, out
+		}
+		return f, nil
+	}
+	favoritesContent, refreshFavoritesPanel := favoritesPanel(application.Preferences(), currentFavorite, loadFavorite, func(f favorite) {
+		loadFavorite(f)
+		applyButton.OnTapped()
+	}, appendLog)
+	refreshFavorites = refreshFavoritesPanel
+
+	tabs := container.NewAppTabs(
+		container.NewTabItem("Limit", form),
+		container.NewTabItem("Favorites", favoritesContent),
+		container.NewTabItem("Rules", container.NewBorder(
+			container.NewHBox(clearOldRulesBar(window, appendLog), reapplySavedButton, pauseAllButton, resumeAllButton, verifyButton, profilesButton, ssidProfilesButton, curfewsButton, dataCapsButton, importRulesButton, exportConfigButton, importConfigButton),
+			nil, nil, nil, rulesTable,
+		)),
+		container.NewTabItem("Manage Rules", liveRulesPanel(window, appendLog)),
 	)
 
-	window.SetContent(form)
+	clearTargetItem := fyne.NewMenuItem("Clear This Target", clearCurrentTarget)
+	clearTargetItem.Shortcut = clearTargetShortcut
+	applyItem := fyne.NewMenuItem("Apply Limit / Block", func() { tapButton(applyButton) })
+	applyItem.Shortcut = applyShortcut
+	window.SetMainMenu(fyne.NewMainMenu(fyne.NewMenu("Rules", applyItem, clearTargetItem)))
+
+	window.SetContent(container.NewBorder(nil, statusBar, nil, nil, tabs))
 	window.ShowAndRun()
 }
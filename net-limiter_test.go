@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"netlimiter/netlimiter"
+)
+
+// Replace scriptLimiter's Run with one that records each script instead of
+// running it, for the rest of the test
+func captureScripts(t *testing.T) *[]string {
+	t.Helper()
+	if !firewallCmdletsAvailable() || !qosCmdletsAvailable() {
+		t.Skip("NetSecurity or NetQos cmdlets missing; the netsh scripts are used instead")
+	}
+	var scripts []string
+	saved := scriptLimiter.Run
+	scriptLimiter.Run = func(_ context.Context, script string) (string, error) {
+		scripts = append(scripts, script)
+		return "", nil
+	}
+	t.Cleanup(func() { scriptLimiter.Run = saved })
+	return &scripts
+}
+
+// The one script an operation ran
+func onlyScript(t *testing.T, scripts []string) string {
+	t.Helper()
+	if len(scripts) != 1 {
+		t.Fatalf("ran %d scripts, want 1:\n%s", len(scripts), strings.Join(scripts, "\n-----\n"))
+	}
+	return scripts[0]
+}
+
+func TestApplyQosLimitScript(t *testing.T) {
+	scripts := captureScripts(t)
+	res := applyQosLimit("chrome.exe", `C:\Program Files\Google\Chrome\Application\chrome.exe`, 0, 500)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	want := `
+Remove-NetQosPolicy -Name "GoNetLimit_chrome.exe" -PolicyStore ActiveStore -Confirm:$false -ErrorAction SilentlyContinue
+Remove-NetQosPolicy -Name "GoNetLimit_OUT_chrome.exe" -PolicyStore ActiveStore -Confirm:$false -ErrorAction SilentlyContinue
+
+New-NetQosPolicy -Name "GoNetLimit_OUT_chrome.exe" -AppPathNameMatchCondition 'C:\Program Files\Google\Chrome\Application\chrome.exe' -ThrottleRateActionBitsPerSecond 500000 -PolicyStore ActiveStore
+`
+	if got := onlyScript(t, *scripts); got != want {
+		t.Errorf("script:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestApplyQosLimitEscapesPath(t *testing.T) {
+	scripts := captureScripts(t)
+	path := `C:\Users\O'Brien\App $x` + "`" + `\chrome.exe`
+	if res := applyQosLimit("chrome.exe", path, 0, 500); res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	want := ` -AppPathNameMatchCondition 'C:\Users\O''Brien\App $x` + "`" + `\chrome.exe' -ThrottleRateActionBitsPerSecond 500000 `
+	if got := onlyScript(t, *scripts); !strings.Contains(got, want) {
+		t.Errorf("script:\n%s\nwant it to contain:\n%s", got, want)
+	}
+}
+
+func TestApplyQosLimitBitsPerSecond(t *testing.T) {
+	tests := []struct {
+		inKbps, outKbps int
+		want            string
+	}{
+		{0, 1, " -ThrottleRateActionBitsPerSecond 1000 "},
+		{0, 500, " -ThrottleRateActionBitsPerSecond 500000 "},
+		{500, 100, " -ThrottleRateActionBitsPerSecond 100000 "}, // IN is reported, not folded in
+		{0, 25_000, " -ThrottleRateActionBitsPerSecond 25000000 "},
+		{0, 100_000_000, " -ThrottleRateActionBitsPerSecond 100000000000 "},
+	}
+	for _, tt := range tests {
+		scripts := captureScripts(t)
+		if res := applyQosLimit("app.exe", `C:\app.exe`, tt.inKbps, tt.outKbps); res.Err != nil {
+			t.Fatalf("in %d / out %d: %v", tt.inKbps, tt.outKbps, res.Err)
+		}
+		if got := onlyScript(t, *scripts); !strings.Contains(got, tt.want) {
+			t.Errorf("in %d / out %d: script:\n%s\nwant it to contain %q", tt.inKbps, tt.outKbps, got, tt.want)
+		}
+	}
+}
+
+func TestApplyQosLimitRejectsBeforeRunning(t *testing.T) {
+	for _, tt := range []struct{ inKbps, outKbps int }{{0, 0}, {-1, 500}, {0, 100_000_001}, {800, 0}} {
+		scripts := captureScripts(t)
+		if res := applyQosLimit("app.exe", `C:\app.exe`, tt.inKbps, tt.outKbps); res.Err == nil {
+			t.Errorf("in %d / out %d: no error", tt.inKbps, tt.outKbps)
+		}
+		if len(*scripts) != 0 {
+			t.Errorf("in %d / out %d: ran a script for an invalid limit", tt.inKbps, tt.outKbps)
+		}
+	}
+}
+
+func TestBlockInternetScript(t *testing.T) {
+	scripts := captureScripts(t)
+	res := blockInternetForProcess("chrome.exe", `C:\Apps\chrome.exe`, blockScope{})
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	want := `
+$path = 'C:\Apps\chrome.exe'
+
+Get-NetFirewallRule -DisplayName "GoNetBlock_OUT_chrome.exe" -ErrorAction SilentlyContinue | Remove-NetFirewallRule -ErrorAction SilentlyContinue
+New-NetFirewallRule -DisplayName "GoNetBlock_OUT_chrome.exe" -Program $path -Direction Outbound -Action Block -ErrorAction Stop | Out-Null
+$count = @(Get-NetFirewallRule -DisplayName "GoNetBlock_OUT_chrome.exe" -ErrorAction SilentlyContinue).Count
+if ($count -ne 1) {
+  Write-Output "Expected one firewall rule named GoNetBlock_OUT_chrome.exe, found $count"
+  exit 1
+}
+
+Get-NetFirewallRule -DisplayName "GoNetBlock_IN_chrome.exe" -ErrorAction SilentlyContinue | Remove-NetFirewallRule -ErrorAction SilentlyContinue
+New-NetFirewallRule -DisplayName "GoNetBlock_IN_chrome.exe" -Program $path -Direction Inbound -Action Block -ErrorAction Stop | Out-Null
+$count = @(Get-NetFirewallRule -DisplayName "GoNetBlock_IN_chrome.exe" -ErrorAction SilentlyContinue).Count
+if ($count -ne 1) {
+  Write-Output "Expected one firewall rule named GoNetBlock_IN_chrome.exe, found $count"
+  exit 1
+}
+`
+	if got := onlyScript(t, *scripts); got != want {
+		t.Errorf("script:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBlockInternetEscapesPath(t *testing.T) {
+	scripts := captureScripts(t)
+	path := `C:\it's $(calc)` + "`" + `\app.exe`
+	if res := blockInternetForProcess("app.exe", path, blockScope{}); res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	want := "\n$path = 'C:\\it''s $(calc)`\\app.exe'\n"
+	if got := onlyScript(t, *scripts); !strings.HasPrefix(got, want) {
+		t.Errorf("script:\n%s\nwant it to start with:\n%s", got, want)
+	}
+}
+
+func TestClearTargetScript(t *testing.T) {
+	scripts := captureScripts(t)
+	if err := clearLimitsForTarget("chrome.exe").Err; err != nil {
+		t.Fatal(err)
+	}
+	want := `
+$persistent = @(Get-NetQosPolicy -Name "GoNetLimit_chrome.exe", "GoNetLimit_OUT_chrome.exe" -PolicyStore localhost -ErrorAction SilentlyContinue)
+$qos = $persistent + @(Get-NetQosPolicy -Name "GoNetLimit_chrome.exe", "GoNetLimit_OUT_chrome.exe" -PolicyStore ActiveStore -ErrorAction SilentlyContinue | Where-Object { $persistent.Name -notcontains $_.Name })
+$fw = @(Get-NetFirewallRule -DisplayName "GoNetBlock_IN_chrome.exe", "GoNetBlock_OUT_chrome.exe" -ErrorAction SilentlyContinue)
+if ($qos.Count + $fw.Count -eq 0) { Write-Output "No existing policy/rules found" }
+else { Write-Output "Removing $($qos.Count) QoS policy(ies) and $($fw.Count) firewall rule(s)" }
+$qos | Remove-NetQosPolicy -Confirm:$false -ErrorAction SilentlyContinue
+$fw | Remove-NetFirewallRule -ErrorAction SilentlyContinue
+`
+	if got := onlyScript(t, *scripts); got != want {
+		t.Errorf("script:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestClearAllLimitsScript(t *testing.T) {
+	scripts := captureScripts(t)
+	if res := clearAllLimits(); res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	got := onlyScript(t, *scripts)
+	for _, want := range []string{
+		`Where-Object { $_.Name -like "GoNetLimit*" }`,
+		`$fw = @(Get-NetFirewallRule -DisplayName "GoNetBlock_IN*", "GoNetBlock_OUT*" -ErrorAction SilentlyContinue)`,
+		`$qos | Remove-NetQosPolicy -Confirm:$false -ErrorAction SilentlyContinue`,
+		`$fw | Remove-NetFirewallRule -ErrorAction SilentlyContinue`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("script:\n%s\nwant it to contain:\n%s", got, want)
+		}
+	}
+}
+
+// Firewall rule lines of a block script, for replaying it against a fake firewall
+var (
+	removeRuleLine = regexp.MustCompile(`^Get-NetFirewallRule -DisplayName "([^"]+)" .*\| Remove-NetFirewallRule`)
+	newRuleLine    = regexp.MustCompile(`^New-NetFirewallRule -DisplayName "([^"]+)" `)
+	countRuleLine  = regexp.MustCompile(`^\$count = @\(Get-NetFirewallRule -DisplayName "([^"]+)" `)
+)
+
+// Replay the firewall rule commands of script on rules (display name to
+// number of rules with it), as Windows would run them. Returns the count
+// each guard saw.
+func replayFirewallScript(script string, rules map[string]int) map[string]int {
+	guarded := make(map[string]int)
+	for _, line := range strings.Split(script, "\n") {
+		switch {
+		case removeRuleLine.MatchString(line):
+			delete(rules, removeRuleLine.FindStringSubmatch(line)[1])
+		case newRuleLine.MatchString(line):
+			rules[newRuleLine.FindStringSubmatch(line)[1]]++
+		case countRuleLine.MatchString(line):
+			name := countRuleLine.FindStringSubmatch(line)[1]
+			guarded[name] = rules[name]
+		}
+	}
+	return guarded
+}
+
+func TestBlockReplacesDuplicateFirewallRules(t *testing.T) {
+	for _, scope := range []blockScope{{}, {RemoteAddress: "10.0.0.0/8", Protocol: "TCP"}} {
+		scripts := captureScripts(t)
+		if res := blockInternetForProcess("app.exe", `C:\app.exe`, scope); res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		script := onlyScript(t, *scripts)
+
+		out := netlimiter.PolicyName(netlimiter.FirewallRuleOut, "app.exe")
+		in := netlimiter.PolicyName(netlimiter.FirewallRuleIn, "app.exe")
+		// Manual edits or a crash left duplicates behind; other rules are untouched
+		rules := map[string]int{out: 3, in: 2, "Some other rule": 2}
+		guarded := replayFirewallScript(script, rules)
+		for _, name := range []string{out, in} {
+			if rules[name] != 1 {
+				t.Errorf("%s%s: %d rule(s) after applying, want exactly 1", name, scope.describe(), rules[name])
+			}
+			if guarded[name] != 1 {
+				t.Errorf("%s%s: count guard saw %d rule(s), want it to check after creating", name, scope.describe(), guarded[name])
+			}
+		}
+		if rules["Some other rule"] != 2 {
+			t.Errorf("%s: other rules changed: %v", scope.describe(), rules)
+		}
+	}
+}
+
+func TestFirewallRuleScriptRemovesBeforeAdding(t *testing.T) {
+	for _, dir := range []string{"Outbound", "Inbound"} {
+		script := netlimiter.FirewallRuleScript("GoNetBlock_X", "-Program $path", dir)
+		remove := strings.Index(script, `Get-NetFirewallRule -DisplayName "GoNetBlock_X" -ErrorAction SilentlyContinue | Remove-NetFirewallRule`)
+		add := strings.Index(script, `New-NetFirewallRule -DisplayName "GoNetBlock_X" -Program $path -Direction `+dir+` -Action Block -ErrorAction Stop`)
+		count := strings.Index(script, `$count = @(Get-NetFirewallRule -DisplayName "GoNetBlock_X" -ErrorAction SilentlyContinue).Count`)
+		guard := strings.Index(script, "if ($count -ne 1) {")
+		exit := strings.Index(script, "exit 1")
+		if remove < 0 || add < 0 || count < 0 || guard < 0 || exit < 0 {
+			t.Fatalf("%s: missing a step:\n%s", dir, script)
+		}
+		if !(remove < add && add < count && count < guard && guard < exit) {
+			t.Errorf("%s: want remove, add, count, then guard:\n%s", dir, script)
+		}
+		if n := strings.Count(script, "New-NetFirewallRule"); n != 1 {
+			t.Errorf("%s: %d New-NetFirewallRule calls, want 1", dir, n)
+		}
+	}
+}
+
+func TestWindowTitle(t *testing.T) {
+	cases := []struct {
+		rules []LimitRule
+		want  string
+	}{
+		{nil, baseWindowTitle},
+		{[]LimitRule{{Process: "a.exe"}}, baseWindowTitle + " — 1 rule"},
+		{[]LimitRule{{Process: "a.exe"}, {Process: "b.exe", Status: statusPaused}}, baseWindowTitle + " — 1 rule"},
+		{[]LimitRule{{Process: "a.exe", Status: statusFailed}}, baseWindowTitle},
+	}
+	for _, c := range cases {
+		if got := windowTitle(c.rules); got != c.want {
+			t.Errorf("windowTitle(%+v) = %q, want %q", c.rules, got, c.want)
+		}
+	}
+}
+
+// Pause All turns the title to PAUSED and Resume All brings the count back
+func TestWindowTitleFollowsPauseAll(t *testing.T) {
+	recordingTestEnv(t)
+	for _, r := range []LimitRule{
+		{Process: "chrome.exe", ExePath: `C:\Chrome\chrome.exe`, OutKbps: 500, Status: statusActive},
+		{Process: "steam.exe", ExePath: `C:\Steam\steam.exe`, OutKbps: 800, Status: statusActive},
+	} {
+		if err := tracked.put(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pauseAllRules(func(string) {})
+	if got, want := windowTitle(tracked.list()), baseWindowTitle+" — PAUSED"; got != want {
+		t.Errorf("title after Pause All = %q, want %q", got, want)
+	}
+	resumeAllRules(func(string) {})
+	if got, want := windowTitle(tracked.list()), baseWindowTitle+" — 2 rules"; got != want {
+		t.Errorf("title after Resume All = %q, want %q", got, want)
+	}
+}
+
+// QoS can't throttle inbound traffic, so an IN limit needs an OUT one beside it
+func TestCheckInboundLimit(t *testing.T) {
+	for _, tt := range []struct {
+		inKbps, outKbps int
+		ok              bool
+	}{
+		{0, 0, true},
+		{0, 500, true},
+		{500, 100, true},
+		{800, 0, false},
+	} {
+		if err := checkInboundLimit(tt.inKbps, tt.outKbps); (err == nil) != tt.ok {
+			t.Errorf("checkInboundLimit(%d, %d) = %v, want ok %v", tt.inKbps, tt.outKbps, err, tt.ok)
+		}
+	}
+}
+
+func TestApplyScriptRefusesInboundOnlyLimit(t *testing.T) {
+	if _, err := buildApplyScript([]LimitRule{{Process: "app.exe", ExePath: `C:\app.exe`, InKbps: 800}}); err == nil {
+		t.Error("exported an IN-only limit, want an error")
+	}
+}
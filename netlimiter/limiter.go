@@ -0,0 +1,93 @@
+package netlimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// ErrUnsupported is returned when PowerShell is run anywhere but Windows.
+var ErrUnsupported = fmt.Errorf("%w on %s: QoS and firewall limits need Windows", errors.ErrUnsupported, runtime.GOOS)
+
+// Limiter applies and clears limits by running PowerShell scripts. Each
+// target's policies and rules are named after a key; ExePolicyKey gives the
+// usual one for an executable path. The zero value runs powershell.exe and
+// needs an elevated (Administrator) process.
+type Limiter struct {
+	// Run executes a script and returns its combined output; nil runs
+	// RunPowerShell. Replace it to log, time out, retry or capture scripts.
+	Run func(ctx context.Context, script string) (string, error)
+
+	// Store QoS policies are created in; "" is ActiveStore, which a reboot
+	// clears. Clearing removes policies from both stores.
+	Store string
+
+	// ClearAllPrefix runs ahead of ClearAll's own script, in the same run,
+	// for state the caller keeps besides this package's policies and rules.
+	ClearAllPrefix string
+}
+
+// PowerShellCommand returns the powershell.exe command running script,
+// killed when ctx ends.
+func PowerShellCommand(ctx context.Context, script string) *exec.Cmd {
+	return exec.CommandContext(ctx, "powershell", "-NoProfile", "-ExecutionPolicy", "Bypass", "-Command", script)
+}
+
+// RunPowerShell runs a script in powershell.exe and returns its combined
+// stdout/stderr. It is the default Limiter.Run.
+func RunPowerShell(ctx context.Context, script string) (string, error) {
+	if runtime.GOOS != "windows" {
+		return "", ErrUnsupported
+	}
+	out, err := PowerShellCommand(ctx, script).CombinedOutput()
+	return string(out), err
+}
+
+// RunScript runs a script through l.Run, e.g. several of this package's
+// scripts joined to make one change in a single PowerShell run.
+func (l Limiter) RunScript(ctx context.Context, script string) (string, error) {
+	if l.Run == nil {
+		return RunPowerShell(ctx, script)
+	}
+	return l.Run(ctx, script)
+}
+
+func (l Limiter) store() string {
+	if l.Store == "" {
+		return ActiveStore
+	}
+	return l.Store
+}
+
+// Limit throttles the traffic exePath sends to outKbps under key's QoS
+// policy, replacing an earlier limit of key. Windows QoS only throttles
+// outbound traffic, so there is no inbound limit.
+func (l Limiter) Limit(ctx context.Context, key, exePath string, outKbps int) (string, error) {
+	if err := ValidateLimitKbps(outKbps); err != nil {
+		return "", err
+	}
+	if outKbps == 0 {
+		return "", errors.New("limit must be > 0 to use QoS; use Block to cut a program off")
+	}
+	return l.RunScript(ctx, LimitScript(key, exePath, KbpsToBitsPerSecond(outKbps), l.store()))
+}
+
+// Block blocks the inbound and outbound traffic of exePath under key's
+// firewall rules, replacing an earlier block of key. extraMatch narrows the
+// block as in BlockScript; "" blocks all of it.
+func (l Limiter) Block(ctx context.Context, key, exePath, extraMatch string) (string, error) {
+	return l.RunScript(ctx, BlockScript(key, exePath, extraMatch))
+}
+
+// Clear removes the limit and block of key, leaving other keys' alone.
+func (l Limiter) Clear(ctx context.Context, key string) (string, error) {
+	return l.RunScript(ctx, ClearTargetScript(key))
+}
+
+// ClearAll runs ClearAllPrefix and removes every limit and block this
+// package created.
+func (l Limiter) ClearAll(ctx context.Context) (string, error) {
+	return l.RunScript(ctx, l.ClearAllPrefix+ClearScript())
+}
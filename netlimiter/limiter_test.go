@@ -0,0 +1,86 @@
+package netlimiter
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Limiter whose Run records each script instead of running it
+func recordingLimiter(store string) (*Limiter, *[]string) {
+	var scripts []string
+	return &Limiter{
+		Run: func(_ context.Context, script string) (string, error) {
+			scripts = append(scripts, script)
+			return "", nil
+		},
+		Store:          store,
+		ClearAllPrefix: "# caller's own cleanup\n",
+	}, &scripts
+}
+
+func TestLimiterRunsTheScriptBuilders(t *testing.T) {
+	ctx := context.Background()
+	exe := `C:\Program Files\App\app.exe`
+	key := ExePolicyKey(exe)
+	tests := []struct {
+		name string
+		op   func(l *Limiter) (string, error)
+		want string
+	}{
+		{"Limit", func(l *Limiter) (string, error) { return l.Limit(ctx, key, exe, 500) }, LimitScript(key, exe, 500_000, ActiveStore)},
+		{"Block", func(l *Limiter) (string, error) { return l.Block(ctx, key, exe, "") }, BlockScript(key, exe, "")},
+		{"Clear", func(l *Limiter) (string, error) { return l.Clear(ctx, key) }, ClearTargetScript(key)},
+		{"ClearAll", func(l *Limiter) (string, error) { return l.ClearAll(ctx) }, "# caller's own cleanup\n" + ClearScript()},
+	}
+	for _, tt := range tests {
+		l, scripts := recordingLimiter("")
+		if _, err := tt.op(l); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !slices.Equal(*scripts, []string{tt.want}) {
+			t.Errorf("%s ran %q, want %q", tt.name, *scripts, tt.want)
+		}
+	}
+}
+
+func TestLimiterLimitUsesStore(t *testing.T) {
+	l, scripts := recordingLimiter(PersistentStore)
+	if _, err := l.Limit(context.Background(), "app.exe", `C:\app.exe`, 100); err != nil {
+		t.Fatal(err)
+	}
+	if len(*scripts) != 1 || !strings.Contains((*scripts)[0], "-PolicyStore "+PersistentStore) {
+		t.Errorf("ran %q, want a policy in the %s store", *scripts, PersistentStore)
+	}
+}
+
+func TestLimiterLimitRejectsBeforeRunning(t *testing.T) {
+	for _, kbps := range []int{-1, 0, MaxLimitKbps + 1} {
+		l, scripts := recordingLimiter("")
+		if _, err := l.Limit(context.Background(), "app.exe", `C:\app.exe`, kbps); err == nil {
+			t.Errorf("Limit(%d) accepted", kbps)
+		}
+		if len(*scripts) != 0 {
+			t.Errorf("Limit(%d) ran a script", kbps)
+		}
+	}
+}
+
+func TestMatchingPIDs(t *testing.T) {
+	procs := []Process{{1, "chrome.exe"}, {2, "Chrome.EXE"}, {3, "chrome_proxy.exe"}, {4, "steam.exe"}}
+	tests := []struct {
+		pattern string
+		want    []int32
+	}{
+		{"chrome.exe", []int32{1, 2}},
+		{"CHROME*", []int32{1, 2, 3}},
+		{"?team.exe", []int32{4}},
+		{"firefox.exe", nil},
+	}
+	for _, tt := range tests {
+		if got := MatchingPIDs(procs, tt.pattern); !slices.Equal(got, tt.want) {
+			t.Errorf("MatchingPIDs(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Package netlimiter limits and blocks the network traffic of Windows
+// programs through QoS policies and Windows Firewall rules, driven by
+// PowerShell. It holds the policy naming, rate conversions and scripts of
+// the Windows NetLimiter GUI, for embedding in other Go programs.
+//
+// The script builders work on any platform. Limiter runs them, which needs
+// Windows and an elevated (Administrator) process.
+package netlimiter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Name prefixes of the QoS policies and firewall rules this package creates
+const (
+	QoSPolicyName   = "GoNetLimit"
+	QoSPolicyOut    = "GoNetLimit_OUT" // outbound throttle; QoS can't throttle inbound per app
+	FirewallRuleIn  = "GoNetBlock_IN"
+	FirewallRuleOut = "GoNetBlock_OUT"
+)
+
+// QoS policy stores. Policies in the active store are gone after a reboot;
+// those in the local computer's persistent store (the default of the NetQos
+// cmdlets) are reapplied at every start. Firewall rules always persist.
+const (
+	ActiveStore     = "ActiveStore"
+	PersistentStore = "localhost"
+)
+
+// Characters replaced in per-target policy names
+var policyNameUnsafe = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// PolicyName is the name of the QoS policy or firewall rule owned by one
+// target, e.g. "GoNetLimit_chrome.exe". key identifies the target, usually
+// ExePolicyKey of its executable.
+func PolicyName(base, key string) string {
+	return base + "_" + policyNameUnsafe.ReplaceAllString(strings.ToLower(key), "_")
+}
+
+// ExeFileName returns the file name of an executable path, for Windows and
+// slash-separated paths alike.
+func ExeFileName(exePath string) string {
+	return exePath[strings.LastIndexAny(exePath, `\/`)+1:]
+}
+
+// ExePolicyKey is the policy key for an executable path: its lower-cased file name.
+func ExePolicyKey(exePath string) string {
+	return strings.ToLower(ExeFileName(exePath))
+}
+
+// QuotePowerShell quotes s as a single-quoted PowerShell string literal.
+// Nothing is expanded inside single quotes ($, backticks and newlines are
+// literal); the only way out is a quote, so every quote character
+// PowerShell accepts is doubled, including the typographic ones it treats
+// the same as '.
+func QuotePowerShell(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\'', '\u2018', '\u2019', '\u201A', '\u201B':
+			b.WriteRune(r)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
@@ -0,0 +1,126 @@
+package netlimiter
+
+import (
+	"strings"
+	"testing"
+)
+
+// Paths and names that break naive quoting
+var hostileStrings = []string{
+	"",
+	`C:\Program Files\App\app.exe`,
+	"C:\\Program Files\\App $x`\\weird.exe",
+	`C:\Users\O'Brien\app.exe`,
+	`C:\it''s\app.exe`,
+	"C:\\typo\u2018graphic\u2019\\app.exe",
+	"C:\\low\u201Aand\u201Breversed\\app.exe",
+	"C:\\line\nbreak\\app.exe",
+	"C:\\crlf\r\n\\app.exe",
+	`C:\$(Remove-Item C:\ -Recurse)\app.exe`,
+	`C:\x'; Remove-Item C:\ -Recurse; '\app.exe`,
+	"C:\\x\u2019; calc; \u2018\\app.exe",
+	`C:\"double"\app.exe`,
+	`'`,
+	`''`,
+	"\u2019",
+}
+
+// PowerShell's single quote characters: ' and the typographic ones it treats alike
+func isQuote(r rune) bool {
+	return strings.ContainsRune("'\u2018\u2019\u201A\u201B", r)
+}
+
+// Read the single-quoted PowerShell literal s starts with, as PowerShell's
+// tokenizer does: two quote characters in a row are one literal quote, a
+// lone one ends the string. Returns the value and the text after the literal.
+func readLiteral(t *testing.T, s string) (value, rest string) {
+	t.Helper()
+	runes := []rune(s)
+	if len(runes) == 0 || !isQuote(runes[0]) {
+		t.Fatalf("%q does not start with a single-quoted string", s)
+	}
+	var b strings.Builder
+	for i := 1; i < len(runes); i++ {
+		if !isQuote(runes[i]) {
+			b.WriteRune(runes[i])
+			continue
+		}
+		if i+1 < len(runes) && isQuote(runes[i+1]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		return b.String(), string(runes[i+1:])
+	}
+	t.Fatalf("unterminated string in %q", s)
+	return "", ""
+}
+
+func TestQuotePowerShellRoundTrips(t *testing.T) {
+	for _, s := range hostileStrings {
+		quoted := QuotePowerShell(s)
+		value, rest := readLiteral(t, quoted)
+		if value != s {
+			t.Errorf("QuotePowerShell(%q) = %s reads back as %q", s, quoted, value)
+		}
+		if rest != "" {
+			t.Errorf("QuotePowerShell(%q) = %s ends early, leaving %q outside the string", s, quoted, rest)
+		}
+	}
+}
+
+func TestQuotePowerShellKeepsSpecialCharactersLiteral(t *testing.T) {
+	// Single quotes expand nothing, so these must pass through untouched
+	for _, s := range []string{"$x", "`n", "$(calc)", "\n", `"`} {
+		if got, want := QuotePowerShell(s), "'"+s+"'"; got != want {
+			t.Errorf("QuotePowerShell(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestBlockScriptCannotBreakOutOfPath(t *testing.T) {
+	for _, path := range hostileStrings {
+		script := BlockScript("app.exe", path, "")
+		_, after, ok := strings.Cut(script, "$path = ")
+		if !ok {
+			t.Fatalf("BlockScript(%q) has no $path assignment:\n%s", path, script)
+		}
+		value, rest := readLiteral(t, after)
+		if value != path {
+			t.Errorf("BlockScript(%q) assigns %q", path, value)
+		}
+		// The path only reaches the rules through the variable, so the rest
+		// of the script is the same whatever the path
+		if _, want, _ := strings.Cut(BlockScript("app.exe", `C:\app.exe`, ""), "'C:\\app.exe'"); rest != want {
+			t.Errorf("BlockScript(%q) differs after the path literal:\n%s", path, rest)
+		}
+	}
+}
+
+func TestLimitScriptCannotBreakOutOfPath(t *testing.T) {
+	for _, path := range hostileStrings {
+		script := LimitScript("app.exe", path, 1_000_000, ActiveStore)
+		_, after, ok := strings.Cut(script, "-AppPathNameMatchCondition ")
+		if !ok {
+			t.Fatalf("LimitScript(%q) has no path condition:\n%s", path, script)
+		}
+		value, rest := readLiteral(t, after)
+		if value != path {
+			t.Errorf("LimitScript(%q) matches %q", path, value)
+		}
+		if want := " -ThrottleRateActionBitsPerSecond 1000000 -PolicyStore ActiveStore\n"; !strings.HasPrefix(rest, want) {
+			t.Errorf("LimitScript(%q): path literal is followed by %q, want %q", path, rest, want)
+		}
+	}
+}
+
+func TestPolicyNameIsSafeInDoubleQuotes(t *testing.T) {
+	// Policy names are interpolated into double-quoted strings, so keys
+	// must lose everything PowerShell would expand or end the string on
+	for _, key := range hostileStrings {
+		name := PolicyName(QoSPolicyOut, key)
+		if strings.ContainsAny(name, "\"'`$\r\n\u2018\u2019\u201A\u201B\u201C\u201D") {
+			t.Errorf("PolicyName(%q) = %q keeps characters unsafe in a double-quoted string", key, name)
+		}
+	}
+}
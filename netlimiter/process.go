@@ -0,0 +1,114 @@
+package netlimiter
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Upper bound on concurrent Name() calls while listing processes
+var processNameWorkers = min(8, runtime.NumCPU()*2)
+
+// Process is a running process's PID and image name.
+type Process struct {
+	PID  int32
+	Name string
+}
+
+// Processes lists the running processes with their image names, reading
+// the names concurrently. Processes whose name can't be read (exited,
+// access denied) are skipped.
+func Processes() ([]Process, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(procs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < processNameWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if name, err := procs[i].Name(); err == nil {
+					names[i] = name
+				}
+			}
+		}()
+	}
+	for i := range procs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	entries := make([]Process, 0, len(procs))
+	for i, p := range procs {
+		if names[i] != "" {
+			entries = append(entries, Process{PID: p.Pid, Name: names[i]})
+		}
+	}
+	return entries, nil
+}
+
+// IsNamePattern reports whether a process name is a wildcard pattern.
+// Windows names can't contain * or ?, so a name with either one matches by
+// pattern.
+func IsNamePattern(name string) bool {
+	return strings.ContainsAny(name, "*?")
+}
+
+// MatchProcessName reports whether a process name matches pattern:
+// case-insensitively, and with path.Match on the lower-cased name when
+// pattern is a wildcard pattern (e.g. "chrome*").
+func MatchProcessName(pattern, name string) bool {
+	if !IsNamePattern(pattern) {
+		return strings.EqualFold(name, pattern)
+	}
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return ok
+}
+
+// MatchingPIDs returns the PIDs of the processes in procs whose name
+// matches pattern (see MatchProcessName).
+func MatchingPIDs(procs []Process, pattern string) []int32 {
+	var pids []int32
+	for _, p := range procs {
+		if MatchProcessName(pattern, p.Name) {
+			pids = append(pids, p.PID)
+		}
+	}
+	return pids
+}
+
+// FindPIDsByName returns the PIDs of the running processes whose name
+// matches pattern, a name like "chrome.exe" or a wildcard like "chrome*".
+func FindPIDsByName(pattern string) ([]int32, error) {
+	procs, err := Processes()
+	if err != nil {
+		return nil, err
+	}
+	return MatchingPIDs(procs, pattern), nil
+}
+
+// ExePath returns the executable path of a running process.
+func ExePath(pid int32) (string, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return "", fmt.Errorf("error reading process info: %w", err)
+	}
+	exePath, err := p.Exe()
+	if err != nil {
+		return "", err
+	}
+	if exePath == "" {
+		return "", fmt.Errorf("empty executable path for PID %d", pid)
+	}
+	return exePath, nil
+}
@@ -0,0 +1,127 @@
+package netlimiter
+
+import "fmt"
+
+// FirewallRuleScript builds the script that replaces the block rule
+// displayName with exactly one rule. Windows allows duplicate display names
+// and manual edits or crashes can leave several, so every existing copy is
+// removed first and the count is checked after. match is the rule's
+// condition, e.g. "-Program $path".
+func FirewallRuleScript(displayName, match, direction string) string {
+	return fmt.Sprintf(`
+Get-NetFirewallRule -DisplayName "%[1]s" -ErrorAction SilentlyContinue | Remove-NetFirewallRule -ErrorAction SilentlyContinue
+New-NetFirewallRule -DisplayName "%[1]s" %[2]s -Direction %[3]s -Action Block -ErrorAction Stop | Out-Null
+$count = @(Get-NetFirewallRule -DisplayName "%[1]s" -ErrorAction SilentlyContinue).Count
+if ($count -ne 1) {
+  Write-Output "Expected one firewall rule named %[1]s, found $count"
+  exit 1
+}
+`, displayName, match, direction)
+}
+
+// BlockScript builds the script that blocks internet, inbound and outbound,
+// for an executable path under target key's rules. extraMatch adds
+// New-NetFirewallRule conditions narrowing the block, e.g.
+// " -RemoteAddress '10.0.0.0/8'"; "" blocks all of its traffic.
+func BlockScript(key, exePath, extraMatch string) string {
+	match := "-Program $path" + extraMatch
+	return fmt.Sprintf(`
+$path = %s
+`, QuotePowerShell(exePath)) +
+		FirewallRuleScript(PolicyName(FirewallRuleOut, key), match, "Outbound") +
+		FirewallRuleScript(PolicyName(FirewallRuleIn, key), match, "Inbound")
+}
+
+// LimitScript builds the script that (re)creates the outbound QoS throttle
+// policy of target key for an executable path in store (ActiveStore or
+// PersistentStore). The single policy used before IN/OUT were separated is
+// removed so the two don't stack.
+func LimitScript(key, exePath string, bitsPerSecond int64, store string) string {
+	return fmt.Sprintf(`
+Remove-NetQosPolicy -Name "%[1]s" -PolicyStore %[5]s -Confirm:$false -ErrorAction SilentlyContinue
+Remove-NetQosPolicy -Name "%[2]s" -PolicyStore %[5]s -Confirm:$false -ErrorAction SilentlyContinue
+
+New-NetQosPolicy -Name "%[2]s" -AppPathNameMatchCondition %[3]s -ThrottleRateActionBitsPerSecond %[4]d -PolicyStore %[5]s
+`,
+		PolicyName(QoSPolicyName, key),
+		PolicyName(QoSPolicyOut, key),
+		QuotePowerShell(exePath),
+		bitsPerSecond,
+		store,
+	)
+}
+
+// QoSScript builds the script that (re)creates the QoS policy of target key
+// for an executable path in store. bitsPerSecond <= 0 skips the throttle;
+// dscp < 0 skips DSCP marking.
+func QoSScript(key, exePath string, bitsPerSecond int64, dscp int, store string) string {
+	actions := ""
+	if bitsPerSecond > 0 {
+		actions += fmt.Sprintf(" -ThrottleRateActionBitsPerSecond %d", bitsPerSecond)
+	}
+	if dscp >= 0 {
+		actions += fmt.Sprintf(" -DSCPAction %d", dscp)
+	}
+	return fmt.Sprintf(`
+Remove-NetQosPolicy -Name "%[1]s" -PolicyStore %[4]s -Confirm:$false -ErrorAction SilentlyContinue
+
+New-NetQosPolicy -Name "%[1]s" -AppPathNameMatchCondition %[2]s%[3]s -PolicyStore %[4]s
+`,
+		PolicyName(QoSPolicyName, key),
+		QuotePowerShell(exePath),
+		actions,
+		store,
+	)
+}
+
+// ClearFoundLine reports what a clear script found. Clear scripts collect
+// what exists into $qos and $fw and run it before removing anything, so a
+// clear that found nothing says so.
+const ClearFoundLine = `if ($qos.Count + $fw.Count -eq 0) { Write-Output "No existing policy/rules found" }
+else { Write-Output "Removing $($qos.Count) QoS policy(ies) and $($fw.Count) firewall rule(s)" }`
+
+// FindQoSScript builds the line collecting into $qos the QoS policies that
+// match, from both stores. args selects them in Get-NetQosPolicy (e.g.
+// `-Name "a", "b"`) and filter narrows them further (e.g. a Where-Object
+// pipeline); either may be empty. A persistent policy is also listed in the
+// active store, so it is only collected from the persistent one, where
+// removing it removes both.
+func FindQoSScript(args, filter string) string {
+	return fmt.Sprintf(`$persistent = @(Get-NetQosPolicy %[1]s -PolicyStore %[3]s -ErrorAction SilentlyContinue%[2]s)
+$qos = $persistent + @(Get-NetQosPolicy %[1]s -PolicyStore %[4]s -ErrorAction SilentlyContinue%[2]s | Where-Object { $persistent.Name -notcontains $_.Name })`,
+		args, filter, PersistentStore, ActiveStore)
+}
+
+// ClearTargetScript builds the script that removes the QoS policies and
+// firewall rules of one target, from whichever store they are in, leaving
+// other targets' alone.
+func ClearTargetScript(key string) string {
+	return fmt.Sprintf(`
+%s
+$fw = @(Get-NetFirewallRule -DisplayName "%s", "%s" -ErrorAction SilentlyContinue)
+%s
+$qos | Remove-NetQosPolicy -Confirm:$false -ErrorAction SilentlyContinue
+$fw | Remove-NetFirewallRule -ErrorAction SilentlyContinue
+`,
+		FindQoSScript(fmt.Sprintf(`-Name "%s", "%s"`, PolicyName(QoSPolicyName, key), PolicyName(QoSPolicyOut, key)), ""),
+		PolicyName(FirewallRuleIn, key), PolicyName(FirewallRuleOut, key),
+		ClearFoundLine,
+	)
+}
+
+// ClearScript builds the script that removes every QoS policy, in either
+// store, and firewall rule carrying this package's names, including the
+// shared names used before policies were named per target.
+func ClearScript() string {
+	return fmt.Sprintf(`
+%s
+$fw = @(Get-NetFirewallRule -DisplayName "%s*", "%s*" -ErrorAction SilentlyContinue)
+%s
+$qos | Remove-NetQosPolicy -Confirm:$false -ErrorAction SilentlyContinue
+$fw | Remove-NetFirewallRule -ErrorAction SilentlyContinue
+`,
+		FindQoSScript("", fmt.Sprintf(` | Where-Object { $_.Name -like "%s*" }`, QoSPolicyName)),
+		FirewallRuleIn, FirewallRuleOut,
+		ClearFoundLine,
+	)
+}
@@ -0,0 +1,37 @@
+package netlimiter
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxLimitKbps is the highest limit accepted, 100 Gbps; anything above is a
+// typo, not a link.
+const MaxLimitKbps = 100_000_000
+
+// ValidateLimitKbps checks a limit in kbps: 0 (no limit, or block when IN
+// and OUT both are) up to MaxLimitKbps.
+func ValidateLimitKbps(kbps int) error {
+	switch {
+	case kbps < 0:
+		return fmt.Errorf("limit can't be negative (%d kbps); use 0 for no limit, or 0 for both to block", kbps)
+	case kbps > MaxLimitKbps:
+		return fmt.Errorf("limit of %d kbps is above the %d kbps (100 Gbps) maximum", kbps, MaxLimitKbps)
+	}
+	return nil
+}
+
+// KbpsToBitsPerSecond converts kbps to bits per second for
+// ThrottleRateActionBitsPerSecond. Network rates are decimal (1 kbps = 1000
+// bits per second), which is also how QoS reads its bit rate. 0 or less
+// means no throttle and converts to 0; values too large for int64 saturate
+// rather than wrap around to a negative rate.
+func KbpsToBitsPerSecond(kbps int) int64 {
+	if kbps <= 0 {
+		return 0
+	}
+	if int64(kbps) > math.MaxInt64/1000 {
+		return math.MaxInt64
+	}
+	return int64(kbps) * 1000
+}
@@ -0,0 +1,55 @@
+package netlimiter
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestValidateLimitKbps(t *testing.T) {
+	tests := []struct {
+		kbps int
+		ok   bool
+	}{
+		{math.MinInt, false},
+		{-1, false},
+		{0, true}, // no limit, or a block when IN and OUT both are
+		{1, true},
+		{MaxLimitKbps, true},
+		{MaxLimitKbps + 1, false},
+		{math.MaxInt, false},
+	}
+	for _, tt := range tests {
+		if err := ValidateLimitKbps(tt.kbps); (err == nil) != tt.ok {
+			t.Errorf("ValidateLimitKbps(%d) = %v, want ok %v", tt.kbps, err, tt.ok)
+		}
+	}
+}
+
+func TestKbpsToBitsPerSecond(t *testing.T) {
+	type test struct {
+		kbps int64 // int64 so the 64-bit cases below compile on 32-bit targets
+		want int64
+	}
+	tests := []test{
+		{math.MinInt, 0},
+		{-1, 0},
+		{0, 0},
+		{1, 1_000},
+		{MaxLimitKbps, 100_000_000_000},
+		{MaxLimitKbps + 1, 100_000_001_000},
+		{math.MaxInt32, math.MaxInt32 * 1000},
+	}
+	if strconv.IntSize == 64 {
+		tests = append(tests,
+			test{math.MaxInt64 / 1000, math.MaxInt64 / 1000 * 1000},
+			test{math.MaxInt64/1000 + 1, math.MaxInt64}, // saturates instead of wrapping negative
+			test{math.MaxInt64, math.MaxInt64},
+		)
+	}
+	for _, tt := range tests {
+		if got := KbpsToBitsPerSecond(int(tt.kbps)); got != tt.want {
+			t.Errorf("KbpsToBitsPerSecond(%d) = %d, want %d", tt.kbps, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"netlimiter/netlimiter"
+)
+
+// Whether the NetSecurity firewall cmdlets exist; detected once per run.
+// Locked-down or older systems may lack the module while netsh still works.
+var firewallCmdlets struct {
+	once      sync.Once
+	available bool
+}
+
+func firewallCmdletsAvailable() bool {
+	firewallCmdlets.once.Do(func() {
+		out, err := runPowerShell(`if (Get-Command New-NetFirewallRule -ErrorAction SilentlyContinue) { "yes" } else { "no" }`)
+		// If detection itself fails, assume the cmdlets and let the real call report errors
+		firewallCmdlets.available = err != nil || strings.TrimSpace(string(out)) != "no"
+	})
+	return firewallCmdlets.available
+}
+
+// netsh equivalent of blockScript, using the same rule names so clearing and
+// reconciling find the rules whichever way they were created.
+// "delete rule name=" removes every rule with that name, so duplicates can't pile up.
+func netshBlockScript(key, exePath string, scope blockScope) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "$path = %s\n", netlimiter.QuotePowerShell(exePath))
+	for _, rule := range []struct{ name, dir string }{
+		{netlimiter.PolicyName(netlimiter.FirewallRuleOut, key), "out"},
+		{netlimiter.PolicyName(netlimiter.FirewallRuleIn, key), "in"},
+	} {
+		fmt.Fprintf(&b, `
+netsh advfirewall firewall delete rule name="%[1]s" | Out-Null
+netsh advfirewall firewall add rule name="%[1]s" dir=%[2]s action=block "program=$path" enable=yes%[3]s
+if ($LASTEXITCODE -ne 0) { exit $LASTEXITCODE }
+`, rule.name, rule.dir, scope.netshArgs())
+	}
+	return b.String()
+}
+
+// netsh equivalent of netlimiter.ClearTargetScript. "show rule" fails for a name with no rules.
+func netshClearTargetScript(key string) string {
+	return fmt.Sprintf(`
+%s
+$fw = @("%s", "%s" | Where-Object { netsh advfirewall firewall show rule name="$_" | Out-Null; $LASTEXITCODE -eq 0 })
+%s
+$qos | Remove-NetQosPolicy -Confirm:$false -ErrorAction SilentlyContinue
+foreach ($n in $fw) { netsh advfirewall firewall delete rule name="$n" | Out-Null }
+# netsh fails for names with no rule; that isn't an error here
+$global:LASTEXITCODE = 0
+`,
+		netlimiter.FindQoSScript(fmt.Sprintf(`-Name "%s", "%s"`, netlimiter.PolicyName(netlimiter.QoSPolicyName, key), netlimiter.PolicyName(netlimiter.QoSPolicyOut, key)), ""),
+		netlimiter.PolicyName(netlimiter.FirewallRuleIn, key), netlimiter.PolicyName(netlimiter.FirewallRuleOut, key),
+		netlimiter.ClearFoundLine,
+	)
+}
+
+// netsh equivalent of clearScript. netsh has no wildcards, so rule names are
+// read from "show rule" output, which is only parsed in English.
+func netshClearScript() string {
+	return fmt.Sprintf(`
+%s
+$fw = @(netsh advfirewall firewall show rule name=all |
+  Select-String '^Rule Name:\s+(%s.*)$' |
+  ForEach-Object { $_.Matches[0].Groups[1].Value.Trim() } |
+  Sort-Object -Unique)
+%s
+$qos | Remove-NetQosPolicy -Confirm:$false -ErrorAction SilentlyContinue
+foreach ($n in $fw) { netsh advfirewall firewall delete rule name="$n" | Out-Null }
+# netsh fails for names with no rule; that isn't an error here
+$global:LASTEXITCODE = 0
+`, netlimiter.FindQoSScript("", fmt.Sprintf(` | Where-Object { $_.Name -like "%s*" }`, netlimiter.QoSPolicyName)), strings.TrimSuffix(netlimiter.FirewallRuleIn, "_IN"), netlimiter.ClearFoundLine)
+}
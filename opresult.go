@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// How a log line or step is coloured
+type logSeverity int
+
+const (
+	severityInfo logSeverity = iota
+	severitySuccess
+	severityWarning
+	severityError
+)
+
+// One thing an operation did, with how much it matters
+type opStep struct {
+	Text     string
+	Severity logSeverity
+}
+
+// Outcome of a QoS/firewall operation, step by step. Backends return it so
+// the GUI can show each step; headless callers take its log text (see Log).
+type OpResult struct {
+	Steps     []opStep // what was done, in order
+	Script    string   // PowerShell script the operation runs
+	RawOutput string   // script output, empty when it was streamed to the log
+	Err       error
+}
+
+// Record a step
+func (r *OpResult) step(format string, args ...any) {
+	r.stepAt(severityInfo, format, args...)
+}
+
+// Record a step the user should know about, e.g. a limit that isn't enforced
+func (r *OpResult) warn(format string, args ...any) {
+	r.stepAt(severityWarning, format, args...)
+}
+
+// Record a step that completed the operation or one of its parts
+func (r *OpResult) succeed(format string, args ...any) {
+	r.stepAt(severitySuccess, format, args...)
+}
+
+// Record a step that failed
+func (r *OpResult) fail(format string, args ...any) {
+	r.stepAt(severityError, format, args...)
+}
+
+func (r *OpResult) stepAt(severity logSeverity, format string, args ...any) {
+	r.Steps = append(r.Steps, opStep{Text: fmt.Sprintf(format, args...), Severity: severity})
+}
+
+// Run the script, recording its output under label; a failure is wrapped with errPrefix
+func (r *OpResult) run(label, errPrefix string) *OpResult {
+	out, err := scriptLimiter.RunScript(context.Background(), r.Script)
+	return r.record(label, errPrefix, out, err)
+}
+
+// Record the output of a run made for r, e.g. one of qosLimiter's operations
+func (r *OpResult) record(label, errPrefix, out string, err error) *OpResult {
+	r.RawOutput = out
+	if len(out) > 0 {
+		r.step("%s:\n%s", label, out)
+	}
+	if err != nil {
+		r.fail("Problem: %s", interpretPowerShellError(out, err))
+		r.Err = fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	return r
+}
+
+// Append o's steps, script and output to r, for an operation made of
+// several; r.Err is left to the caller, which knows how the errors combine
+func (r *OpResult) merge(o *OpResult) *OpResult {
+	r.Steps = append(r.Steps, o.Steps...)
+	r.Script += o.Script
+	r.RawOutput += o.RawOutput
+	return r
+}
+
+// Log text for the result, one line per step
+func (r *OpResult) Log() string {
+	var b strings.Builder
+	for _, s := range r.Steps {
+		b.WriteString(s.Text + "\n")
+	}
+	return b.String()
+}
+
+// Log text and error, for callers that take the two separately
+func (r *OpResult) result() (string, error) {
+	return r.Log(), r.Err
+}
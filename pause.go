@@ -0,0 +1,61 @@
+package main
+
+import "fmt"
+
+// Remove a rule's policies but keep tracking it as paused, so it can be
+// resumed with the same settings. Nothing reapplies a paused rule on its own.
+func pauseRule(r LimitRule, log func(string)) error {
+	clearLog, err := clearRule(r).result()
+	log("Pausing " + r.describe())
+	log(clearLog)
+	auditOrLog(log, "pause", r.Process, r.ExePath, nil, err)
+	if err != nil {
+		return err
+	}
+	r.Status, r.LastError = statusPaused, ""
+	return tracked.put(r)
+}
+
+// Reapply a paused rule's policies
+func resumeRule(r LimitRule, log func(string)) error {
+	applyLog, err := applyRule(r).result()
+	log("Resuming " + r.describe())
+	log(applyLog)
+	if saveErr := tracked.recordResult(r, err); saveErr != nil {
+		log("Could not save tracked rules: " + saveErr.Error())
+	}
+	auditOrLog(log, "limit", r.Process, r.ExePath, map[string]any{"trigger": "resume"}, err)
+	return err
+}
+
+// Pause every active rule, leaving the window title at PAUSED
+func pauseAllRules(log func(string)) {
+	n, failed := 0, 0
+	for _, r := range tracked.list() {
+		if r.status() != statusActive {
+			continue
+		}
+		n++
+		if err := pauseRule(r, log); err != nil {
+			log("Pause error: " + err.Error())
+			failed++
+		}
+	}
+	log(fmt.Sprintf("Paused %d of %d active rule(s)", n-failed, n))
+}
+
+// Resume every paused rule
+func resumeAllRules(log func(string)) {
+	n, failed := 0, 0
+	for _, r := range tracked.list() {
+		if r.status() != statusPaused {
+			continue
+		}
+		n++
+		if err := resumeRule(r, log); err != nil {
+			log("Resume error: " + err.Error())
+			failed++
+		}
+	}
+	log(fmt.Sprintf("Resumed %d of %d paused rule(s)", n-failed, n))
+}
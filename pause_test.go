@@ -0,0 +1,59 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPauseAndResumeRule(t *testing.T) {
+	l, _ := recordingTestEnv(t)
+	r := LimitRule{Process: "steam.exe", ExePath: `C:\Steam\steam.exe`, ExtraPaths: []string{`D:\Steam\steam.exe`}, OutKbps: 800, Status: statusActive}
+	var keys []string
+	for _, target := range r.exeTargets() {
+		keys = append(keys, target.Key)
+	}
+	if err := tracked.put(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pauseRule(r, func(string) {}); err != nil {
+		t.Fatalf("pauseRule: %v", err)
+	}
+	if got, _ := tracked.get(r.key()); got.status() != statusPaused {
+		t.Errorf("status after pause = %q, want %q", got.status(), statusPaused)
+	}
+	if !slices.Equal(l.cleared, keys) || len(l.applied) != 0 {
+		t.Errorf("pause cleared %q and applied %q, want %q cleared only", l.cleared, l.applied, keys)
+	}
+
+	paused, _ := tracked.get(r.key())
+	if err := resumeRule(paused, func(string) {}); err != nil {
+		t.Fatalf("resumeRule: %v", err)
+	}
+	if got, _ := tracked.get(r.key()); got.status() != statusActive || got.OutKbps != 800 {
+		t.Errorf("after resume got %+v, want the active 800 kbps rule", got)
+	}
+	if !slices.Equal(l.applied, keys) {
+		t.Errorf("resume applied %q, want %q", l.applied, keys)
+	}
+}
+
+// A paused rule stays out of bulk reapplies until resumed
+func TestPauseAllSkipsNonActiveRules(t *testing.T) {
+	l, _ := recordingTestEnv(t)
+	for _, r := range []LimitRule{
+		{Process: "chrome.exe", ExePath: `C:\Chrome\chrome.exe`, OutKbps: 500, Status: statusActive},
+		{Process: "steam.exe", ExePath: `C:\Steam\steam.exe`, OutKbps: 800, Status: statusFailed},
+	} {
+		if err := tracked.put(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pauseAllRules(func(string) {})
+	if want := []string{"chrome.exe"}; !slices.Equal(l.cleared, want) {
+		t.Errorf("pause all cleared %q, want %q", l.cleared, want)
+	}
+	if got := reapplicableRules(tracked.list()); len(got) != 1 || got[0].Process != "steam.exe" {
+		t.Errorf("reapplicable after pause all = %+v, want only the failed steam.exe rule", got)
+	}
+}
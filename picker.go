@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// A running process as listed by the picker
+type runningProcess struct {
+	PID     int32
+	Name    string
+	ExePath string // empty when access is denied
+	Memory  uint64 // resident set size in bytes
+}
+
+// Every running process whose name can be read
+func listRunningProcesses() ([]runningProcess, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	var running []runningProcess
+	for _, p := range procs {
+		name, err := p.Name()
+		if err != nil || name == "" {
+			continue
+		}
+		rp := runningProcess{PID: p.Pid, Name: name}
+		rp.ExePath, _ = p.Exe()
+		if mem, err := p.MemoryInfo(); err == nil {
+			rp.Memory = mem.RSS
+		}
+		running = append(running, rp)
+	}
+	return running, nil
+}
+
+// Every running instance of one process name, as listed by the picker
+type pickerRow struct {
+	Name    string
+	PIDs    []int32
+	ExePath string // of the first instance whose path could be read
+	Memory  uint64 // resident memory of all instances
+}
+
+// Group processes by name (case-insensitively) and sort the groups by name
+func groupProcesses(procs []runningProcess) []pickerRow {
+	byName := make(map[string]*pickerRow)
+	for _, p := range procs {
+		key := strings.ToLower(p.Name)
+		row := byName[key]
+		if row == nil {
+			row = &pickerRow{Name: p.Name}
+			byName[key] = row
+		}
+		row.PIDs = append(row.PIDs, p.PID)
+		row.Memory += p.Memory
+		if row.ExePath == "" {
+			row.ExePath = p.ExePath
+		}
+	}
+
+	rows := make([]pickerRow, 0, len(byName))
+	for _, row := range byName {
+		sort.Slice(row.PIDs, func(i, j int) bool { return row.PIDs[i] < row.PIDs[j] })
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool { return strings.ToLower(rows[i].Name) < strings.ToLower(rows[j].Name) })
+	return rows
+}
+
+func (r pickerRow) title() string {
+	instances := fmt.Sprintf("PID %d", r.PIDs[0])
+	if len(r.PIDs) > 1 {
+		instances = fmt.Sprintf("%d processes", len(r.PIDs))
+	}
+	return fmt.Sprintf("%s  (%s, %s)", r.Name, instances, formatBytes(r.Memory))
+}
+
+func (r pickerRow) detail() string {
+	if r.ExePath == "" {
+		return "path not accessible"
+	}
+	return r.ExePath
+}
+
+// Show a searchable, refreshable list of running processes; the chosen name
+// is passed to onSelect
+func showProcessPicker(window fyne.Window, src ProcessSource, onSelect func(name string)) {
+	var all, filtered []pickerRow
+
+	list := widget.NewList(
+		func() int { return len(filtered) },
+		func() fyne.CanvasObject {
+			detail := widget.NewLabel("")
+			detail.TextStyle = fyne.TextStyle{Italic: true}
+			return container.NewVBox(widget.NewLabel(""), detail)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			r := filtered[id]
+			labels := obj.(*fyne.Container).Objects
+			labels[0].(*widget.Label).SetText(r.title())
+			labels[1].(*widget.Label).SetText(r.detail())
+		},
+	)
+
+	search := widget.NewEntry()
+	search.SetPlaceHolder("Filter by name or path...")
+	applyFilter := func() {
+		text := strings.ToLower(strings.TrimSpace(search.Text))
+		filtered = filtered[:0]
+		for _, r := range all {
+			if text == "" || strings.Contains(strings.ToLower(r.Name), text) || strings.Contains(strings.ToLower(r.ExePath), text) {
+				filtered = append(filtered, r)
+			}
+		}
+		list.UnselectAll()
+		list.Refresh()
+	}
+	search.OnChanged = func(string) { applyFilter() }
+
+	status := widget.NewLabel("")
+	var load func()
+	refresh := widget.NewButton("Refresh", func() { load() })
+	load = func() {
+		status.SetText("Loading running processes...")
+		refresh.Disable()
+		// Reading every process's path and memory takes a moment
+		go func() {
+			procs, err := src.ListProcesses()
+			fyne.Do(func() {
+				refresh.Enable()
+				if err != nil {
+					status.SetText("Error: " + err.Error())
+					return
+				}
+				all = groupProcesses(procs)
+				status.SetText(fmt.Sprintf("%d running apps (%d processes)", len(all), len(procs)))
+				applyFilter()
+			})
+		}()
+	}
+
+	content := container.NewBorder(container.NewVBox(search, container.NewBorder(nil, nil, nil, refresh, status)), nil, nil, nil, list)
+	d := dialog.NewCustom("Select Process", "Cancel", content, window)
+	d.Resize(fyne.NewSize(560, 460))
+
+	list.OnSelected = func(id widget.ListItemID) {
+		onSelect(filtered[id].Name)
+		d.Hide()
+	}
+
+	d.Show()
+	load()
+}
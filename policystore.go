@@ -0,0 +1,35 @@
+package main
+
+import (
+	"sync/atomic"
+
+	"fyne.io/fyne/v2"
+
+	"netlimiter/netlimiter"
+)
+
+// Create QoS policies in the persistent store so they survive a reboot
+const persistPoliciesPrefKey = "persistPolicies"
+
+var persistPolicies atomic.Bool
+
+func configurePolicyStore(prefs fyne.Preferences) {
+	persistPolicies.Store(prefs.Bool(persistPoliciesPrefKey))
+}
+
+// Store new QoS policies are created in
+func qosStore() string {
+	if persistPolicies.Load() {
+		return netlimiter.PersistentStore
+	}
+	return netlimiter.ActiveStore
+}
+
+// Store r's QoS policies were created in; rules saved before the store was
+// recorded used the active store
+func (r LimitRule) qosStore() string {
+	if r.PolicyStore == "" {
+		return netlimiter.ActiveStore
+	}
+	return r.PolicyStore
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Wait after a resume before reapplying, so adapters and the QoS stack are back
+const resumeSettleDelay = 5 * time.Second
+
+// Long-running script printing "resume" for every Win32_PowerManagementEvent
+// of type 7 (resume from suspend)
+const powerEventScript = `
+Register-WmiEvent -Class Win32_PowerManagementEvent -SourceIdentifier NetLimiterPower | Out-Null
+while ($true) {
+  $e = Wait-Event -SourceIdentifier NetLimiterPower
+  if ($e.SourceEventArgs.NewEvent.EventType -eq 7) { Write-Output "resume" }
+  Remove-Event -EventIdentifier $e.EventIdentifier
+}
+`
+
+// Subscribe to power events and call onResume after each resume from sleep.
+// Blocks until ctx is cancelled or the subscription process exits.
+func watchPowerResume(ctx context.Context, onResume func()) error {
+	if runtime.GOOS != "windows" {
+		return errUnsupportedPlatform
+	}
+	cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-ExecutionPolicy", "Bypass", "-Command", powerEventScript)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("power event watcher error: %w", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "resume" {
+			onResume()
+		}
+	}
+	if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("power event watcher exited: %w", err)
+	}
+	return nil
+}
+
+// Re-verify and reapply every active tracked rule after a resume.
+// ActiveStore policies sometimes stop taking effect across sleep.
+func reapplyAfterResume(log func(string)) {
+	log("----------------------------------------------------")
+	log("Resumed from sleep, reapplying tracked rules")
+
+	if live, err := backend.LiveRules(); err == nil {
+		log(formatDrift(reconcileRules(tracked.list(), live)))
+	}
+
+	for _, r := range tracked.list() {
+		if r.status() != statusActive {
+			continue
+		}
+		applyLog, err := applyRule(r).result()
+		log("Reapplying " + r.describe())
+		log(applyLog)
+		if err != nil {
+			log("Reapply error: " + err.Error())
+		}
+		if saveErr := tracked.recordResult(r, err); saveErr != nil {
+			log("Could not save tracked rules: " + saveErr.Error())
+		}
+		auditOrLog(log, "reapply", r.Process, r.ExePath, map[string]any{"trigger": "resume"}, err)
+	}
+}
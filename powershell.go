@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+
+	"netlimiter/netlimiter"
+)
+
+// Run a PowerShell script and return its combined stdout/stderr.
+// The run is killed on timeout or when cancelled (see psRuns).
+func runPowerShell(script string) ([]byte, error) {
+	if runtime.GOOS != "windows" {
+		return nil, errUnsupportedPlatform
+	}
+	return runPowerShellCommand(script)
+}
+
+// Receives each PowerShell output line as it arrives during limiter
+// operations; set by the GUI, nil in headless mode
+var liveOutput func(string)
+
+// Run a limiter script, streaming its combined stdout/stderr to liveOutput
+// line by line. Returns the output still to be logged: all of it when
+// nothing was streamed, "" otherwise. Errors carry the failure mode found
+// in the output (see classifyPowerShellError).
+func runPowerShellLive(script string) (string, error) {
+	if dryRun.Load() {
+		return dryRunOutput(script), nil
+	}
+	fileLog.write("script", script)
+	return runWithRetry(script)
+}
+
+// Runs the scripts of limiter operations through runPowerShellLive, so the
+// log, dry run and retries apply to them. Runs are bounded by psRuns rather
+// than the operations' context. Run is replaceable, so the scripts the
+// operations build can be captured without running PowerShell; see
+// qosLimiter for the store policies go to.
+var scriptLimiter = netlimiter.Limiter{
+	Run:            func(_ context.Context, script string) (string, error) { return runPowerShellLive(script) },
+	ClearAllPrefix: allowlistClearScript(),
+}
+
+// scriptLimiter creating policies in the QoS store chosen in the settings
+func qosLimiter() netlimiter.Limiter {
+	l := scriptLimiter
+	l.Store = qosStore()
+	return l
+}
+
+// Runs a limiter script for real, as described at runPowerShellLive
+func executePowerShell(script string) (string, error) {
+	// Fail up front rather than with PowerShell's access-denied errors
+	if runtime.GOOS == "windows" && !isElevated() {
+		return "", fmt.Errorf("QoS and firewall changes need this program to run as Administrator: %w", errNotElevated)
+	}
+	if liveOutput == nil {
+		out, err := runPowerShell(script)
+		return string(out), classifyPowerShellError(string(out), err)
+	}
+	if runtime.GOOS != "windows" {
+		return "", errUnsupportedPlatform
+	}
+
+	ctx, timeout, done := psRuns.start()
+	defer done()
+	pr, pw := io.Pipe()
+	cmd := powerShellCommand(ctx, script)
+	cmd.Stdout, cmd.Stderr = pw, pw
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	waitErr := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		pw.Close()
+		waitErr <- err
+	}()
+
+	var seen strings.Builder
+	scanner := bufio.NewScanner(pr)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); strings.TrimSpace(line) != "" {
+			liveOutput(line)
+			seen.WriteString(line + "\n")
+		}
+	}
+	// Keep the process unblocked if a line was too long to scan
+	io.Copy(io.Discard, pr)
+	return "", classifyPowerShellError(seen.String(), psContextError(ctx, timeout, <-waitErr))
+}
+
+// Run a PowerShell script that prints JSON and decode its stdout into v
+func queryPowerShellJSON(script string, v any) error {
+	if runtime.GOOS != "windows" {
+		return errUnsupportedPlatform
+	}
+	ctx, timeout, done := psRuns.start()
+	defer done()
+	out, err := powerShellCommand(ctx, script).Output()
+	if err != nil {
+		return fmt.Errorf("powershell query error: %w", psContextError(ctx, timeout, err))
+	}
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(out, v); err != nil {
+		return fmt.Errorf("could not parse PowerShell output: %w", err)
+	}
+	return nil
+}
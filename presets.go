@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+)
+
+const presetsPrefKey = "presets"
+
+// Named IN/OUT values that fill the limit fields with one click
+type limitPreset struct {
+	Name    string `json:"name"`
+	InKbps  int    `json:"inKbps"`
+	OutKbps int    `json:"outKbps"`
+}
+
+var defaultPresets = []limitPreset{
+	{Name: "Slow (256 kbps)", InKbps: 256, OutKbps: 256},
+	{Name: "Medium (2 Mbps)", InKbps: 2000, OutKbps: 2000},
+	{Name: "Fast (10 Mbps)", InKbps: 10000, OutKbps: 10000},
+}
+
+// Load presets from preferences, falling back to the defaults
+func loadPresets(prefs fyne.Preferences) []limitPreset {
+	raw := prefs.String(presetsPrefKey)
+	if raw == "" {
+		return defaultPresets
+	}
+	var presets []limitPreset
+	if err := json.Unmarshal([]byte(raw), &presets); err != nil {
+		return defaultPresets
+	}
+	return presets
+}
+
+// Store presets in preferences
+func savePresets(prefs fyne.Preferences, presets []limitPreset) {
+	data, _ := json.Marshal(presets)
+	prefs.SetString(presetsPrefKey, string(data))
+}
+
+// Render presets as editable "Name = IN/OUT" lines
+func formatPresets(presets []limitPreset) string {
+	var b strings.Builder
+	for _, p := range presets {
+		fmt.Fprintf(&b, "%s = %d/%d\n", p.Name, p.InKbps, p.OutKbps)
+	}
+	return b.String()
+}
+
+// Parse "Name = IN/OUT" lines; blank lines are ignored
+func parsePresets(text string) ([]limitPreset, error) {
+	var presets []limitPreset
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		eq := strings.LastIndex(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected \"Name = IN/OUT\"", i+1)
+		}
+		name := strings.TrimSpace(line[:eq])
+		in, out, found := strings.Cut(strings.TrimSpace(line[eq+1:]), "/")
+		if name == "" || !found {
+			return nil, fmt.Errorf("line %d: expected \"Name = IN/OUT\"", i+1)
+		}
+
+		inKbps, err := parseLimit(in, unitKbps)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: IN: %w", i+1, err)
+		}
+		outKbps, err := parseLimit(out, unitKbps)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: OUT: %w", i+1, err)
+		}
+		presets = append(presets, limitPreset{Name: name, InKbps: inKbps, OutKbps: outKbps})
+	}
+	return presets, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"netlimiter/netlimiter"
+)
+
+// QoS priority level for the deprioritize mode
+type qosPriority string
+
+const (
+	priorityLow    qosPriority = "Low"
+	priorityNormal qosPriority = "Normal"
+	priorityHigh   qosPriority = "High"
+)
+
+var priorityLevels = []string{string(priorityLow), string(priorityNormal), string(priorityHigh)}
+
+// DSCP value marked on the app's traffic for a priority level:
+// CS1 ("scavenger") yields to everything, EF is expedited.
+// Normal leaves traffic unmarked (-1).
+func dscpForPriority(p qosPriority) int {
+	switch p {
+	case priorityLow:
+		return 8
+	case priorityHigh:
+		return 46
+	}
+	return -1
+}
+
+// Whether a rule changes priority rather than (or as well as) throttling
+func (p qosPriority) active() bool {
+	return p != "" && p != priorityNormal
+}
+
+// Check a priority rule and build its script: DSCP marking, optionally
+// together with a throttle rate when an OUT limit is set (see checkInboundLimit)
+func priorityPlan(key, exePath string, priority qosPriority, inKbps, outKbps int) *OpResult {
+	res := &OpResult{}
+	res.step("Applying %s priority for: %s", strings.ToLower(string(priority)), exePath)
+
+	dscp := dscpForPriority(priority)
+	if dscp < 0 {
+		res.Err = fmt.Errorf("priority %q does not need a policy", priority)
+		return res
+	}
+	if err := checkInboundLimit(inKbps, outKbps); err != nil {
+		res.Err = err
+		return res
+	}
+	if !qosCmdletsAvailable() {
+		res.Err = errNoQosCmdlets()
+		return res
+	}
+
+	var bitsPerSecond int64
+	noteInboundLimit(res, inKbps)
+	if outKbps > 0 {
+		bitsPerSecond = convertToBitsPerSecond(outKbps, unitKbps)
+		res.step("Requested OUT limit: %d kbps (~%d bits per second)", outKbps, bitsPerSecond)
+	}
+	res.step("DSCP value: %d", dscp)
+	res.warn("Note: priority only takes effect where the NIC, driver and network honour DSCP/QoS marking")
+	res.Script = netlimiter.QoSScript(key, exePath, bitsPerSecond, dscp, qosStore())
+	return res
+}
+
+// Apply a QoS priority (DSCP marking) for an executable path
+func applyPriorityForExe(key, exePath string, priority qosPriority, inKbps, outKbps int) *OpResult {
+	res := priorityPlan(key, exePath, priority, inKbps, outKbps)
+	if res.Err != nil {
+		return res
+	}
+	if res.run("QoS output", "QoS error").Err == nil {
+		res.succeed("ApplyPriority: success")
+	}
+	return res
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"netlimiter/netlimiter"
+)
+
+// How long a process list snapshot is reused for rapid repeated lookups
+const processCacheTTL = 2 * time.Second
+
+// Short-lived cache of the running processes' names
+type processCache struct {
+	mu    sync.Mutex
+	taken time.Time
+	procs []netlimiter.Process
+}
+
+var procCache = &processCache{}
+
+// Process names, reusing the previous snapshot if it's younger than the TTL
+func (c *processCache) snapshot() ([]netlimiter.Process, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.procs != nil && time.Since(c.taken) < processCacheTTL {
+		return c.procs, nil
+	}
+	procs, err := netlimiter.Processes()
+	if err != nil {
+		return nil, err
+	}
+	c.procs, c.taken = procs, time.Now()
+	return procs, nil
+}
+
+// Drop the snapshot so the next lookup sees fresh data
+func (c *processCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.procs = nil
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"netlimiter/netlimiter"
+)
+
+const profilesPrefKey = "profiles"
+
+// Named group of processes with their limits, applied and cleared together.
+// Each process still gets its own rule and policies, so entries never collide.
+type limitProfile struct {
+	Name    string        `json:"name"`
+	Entries []limitPreset `json:"entries"` // Name is the process; IN and OUT both 0 blocks, as on the Limit tab
+
+	// Total for all entries together, overriding their own limits; 0 for none.
+	// Windows QoS can't put several apps in one bucket, so the cap is divided
+	// evenly and each app gets a fixed share, used or not. The shared budget
+	// (Advanced) rebalances by usage instead.
+	SharedCapKbps int `json:"sharedCapKbps,omitempty"`
+}
+
+// Check the shared cap leaves every entry at least 1 kbps
+func (p limitProfile) validateSharedCap() error {
+	if err := netlimiter.ValidateLimitKbps(p.SharedCapKbps); err != nil {
+		return fmt.Errorf("shared cap: %w", err)
+	}
+	if p.SharedCapKbps > 0 && p.SharedCapKbps < len(p.Entries) {
+		return fmt.Errorf("shared cap of %d kbps is less than 1 kbps for each of %d processes", p.SharedCapKbps, len(p.Entries))
+	}
+	return nil
+}
+
+// Entries with the limits they are applied at: their own, or their share of
+// the shared cap, the remainder going to the first entries
+func (p limitProfile) appliedEntries() []limitPreset {
+	if p.SharedCapKbps <= 0 || len(p.Entries) == 0 {
+		return p.Entries
+	}
+	share, rest := p.SharedCapKbps/len(p.Entries), p.SharedCapKbps%len(p.Entries)
+	entries := make([]limitPreset, len(p.Entries))
+	for i, e := range p.Entries {
+		kbps := share
+		if i < rest {
+			kbps++
+		}
+		entries[i] = limitPreset{Name: e.Name, InKbps: kbps, OutKbps: kbps}
+	}
+	return entries
+}
+
+// Log line on how the shared cap was divided, e.g. "Shared cap 1000 kbps
+// divided among 3 process(es): chrome.exe 334, ..."
+func sharedCapDivision(p limitProfile, entries []limitPreset) string {
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		parts[i] = fmt.Sprintf("%s %d", e.Name, e.OutKbps)
+	}
+	return fmt.Sprintf("Shared cap %d kbps divided among %d process(es): %s kbps (fixed shares; Windows QoS has no shared bucket)",
+		p.SharedCapKbps, len(entries), strings.Join(parts, ", "))
+}
+
+// Note on the rules a profile applies
+func profileNote(name string) string {
+	return "profile: " + name
+}
+
+// Saved profiles, sorted by name
+func loadProfiles(prefs fyne.Preferences) []limitProfile {
+	var profiles []limitProfile
+	if raw := prefs.String(profilesPrefKey); raw != "" {
+		if json.Unmarshal([]byte(raw), &profiles) != nil {
+			return nil
+		}
+	}
+	sort.Slice(profiles, func(i, j int) bool { return strings.ToLower(profiles[i].Name) < strings.ToLower(profiles[j].Name) })
+	return profiles
+}
+
+func saveProfiles(prefs fyne.Preferences, profiles []limitProfile) {
+	data, _ := json.Marshal(profiles)
+	prefs.SetString(profilesPrefKey, string(data))
+}
+
+func findProfile(profiles []limitProfile, name string) (limitProfile, bool) {
+	for _, p := range profiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return limitProfile{}, false
+}
+
+// Store p, replacing a profile with the same name; no entries removes it
+func setProfile(prefs fyne.Preferences, p limitProfile) {
+	var kept []limitProfile
+	for _, have := range loadProfiles(prefs) {
+		if !strings.EqualFold(have.Name, p.Name) {
+			kept = append(kept, have)
+		}
+	}
+	if len(p.Entries) > 0 {
+		kept = append(kept, p)
+	}
+	saveProfiles(prefs, kept)
+}
+
+// Rule for a profile entry, resolved against the running instances; ok is
+// false when the process isn't running
+func profileEntryRule(p limitProfile, e limitPreset) (r LimitRule, ok bool, err error) {
+	r = LimitRule{Process: e.Name, InKbps: e.InKbps, OutKbps: e.OutKbps, Blocked: e.InKbps == 0 && e.OutKbps == 0, Note: profileNote(p.Name)}
+	pids, err := processes.FindPIDsByName(e.Name)
+	if err != nil {
+		return r, false, fmt.Errorf("error finding process: %w", err)
+	}
+	if len(pids) == 0 {
+		return r, false, nil
+	}
+	instances := make([]processInstance, len(pids))
+	for i, pid := range pids {
+		instances[i].PID = pid
+	}
+	paths, err := distinctExePaths(processes, instances)
+	if err != nil {
+		return r, false, fmt.Errorf("could not get executable path of %s: %w", e.Name, err)
+	}
+	r.ExePath, r.ExtraPaths = paths[0], paths[1:]
+	return r, true, nil
+}
+
+// Apply every entry of a profile, replacing each process's previous rule.
+// Processes that aren't running are queued as pending, as on import.
+func applyProfile(p limitProfile, log func(string)) {
+	entries := p.appliedEntries()
+	if p.SharedCapKbps > 0 {
+		log(sharedCapDivision(p, entries))
+	}
+	for _, e := range entries {
+		r, running, err := profileEntryRule(p, e)
+		if err != nil {
+			log(fmt.Sprintf("Profile %s: %s: %v", p.Name, e.Name, err))
+			continue
+		}
+		if !running {
+			log("Queued (not running): " + r.describe())
+			if err := tracked.markPending(r); err != nil {
+				log("Could not save tracked rules: " + err.Error())
+			}
+			continue
+		}
+
+		if prev, ok := tracked.get(r.key()); ok {
+			if clearLog, err := clearRule(prev).result(); err != nil {
+				log(clearLog)
+				log("Clear error: " + err.Error())
+			}
+		}
+		applyLog, err := applyRule(r).result()
+		log("Applying " + r.describe())
+		log(applyLog)
+		if err != nil {
+			log("Apply error: " + err.Error())
+		}
+		if saveErr := tracked.recordResult(r, err); saveErr != nil {
+			log("Could not save tracked rules: " + saveErr.Error())
+		}
+		action := "limit"
+		if r.Blocked {
+			action = "block"
+		}
+		details := map[string]any{"inKbps": r.InKbps, "outKbps": r.OutKbps, "profile": p.Name}
+		if p.SharedCapKbps > 0 {
+			details["sharedCapKbps"] = p.SharedCapKbps
+		}
+		auditOrLog(log, action, r.Process, r.ExePath, details, err)
+	}
+}
+
+// Clear the rules of a profile's processes, leaving every other rule in place
+func clearProfile(p limitProfile, log func(string)) {
+	for _, e := range p.Entries {
+		r, ok := tracked.get(strings.ToLower(e.Name))
+		if !ok {
+			r = LimitRule{Process: e.Name}
+		}
+		clearLog, err := clearRule(r).result()
+		log("Clearing " + r.describe())
+		log(clearLog)
+		auditOrLog(log, "clear", r.Process, r.ExePath, map[string]any{"scope": "target", "profile": p.Name}, err)
+		if err != nil {
+			log("Clear error: " + err.Error())
+			continue
+		}
+		if err := tracked.remove(r.key()); err != nil {
+			log("Could not save tracked rules: " + err.Error())
+		}
+	}
+}
+
+// Process names of a shared-cap profile, one per line; limits after "=" are
+// ignored since the cap decides them
+func parseProfileNames(text string) []limitPreset {
+	var entries []limitPreset
+	for _, line := range strings.Split(text, "\n") {
+		if eq := strings.LastIndex(line, "="); eq >= 0 {
+			line = line[:eq]
+		}
+		if name := strings.TrimSpace(line); name != "" {
+			entries = append(entries, limitPreset{Name: name})
+		}
+	}
+	return entries
+}
+
+func formatProfileNames(entries []limitPreset) string {
+	var b strings.Builder
+	for _, e := range entries {
+		b.WriteString(e.Name + "\n")
+	}
+	return b.String()
+}
+
+// Dialog to edit profiles and apply or clear one in a click
+func showProfiles(window fyne.Window, prefs fyne.Preferences, appendLog func(string)) {
+	nameSelect := widget.NewSelectEntry(nil)
+	nameSelect.SetPlaceHolder("Profile name, e.g. Browsing")
+	entriesEntry := widget.NewMultiLineEntry()
+	entriesEntry.SetPlaceHolder("One per line: process = IN/OUT (kbps), 0/0 blocks\nchrome.exe = 1000/1000\nspotify.exe = 1000/1000\nWith a shared cap, just the process names")
+	entriesEntry.SetMinRowsVisible(6)
+	sharedCapEntry := widget.NewEntry()
+	sharedCapEntry.SetPlaceHolder("Shared cap (kbps), blank for per-process limits")
+
+	refresh := func() {
+		profiles := loadProfiles(prefs)
+		names := make([]string, len(profiles))
+		for i, p := range profiles {
+			names[i] = p.Name
+		}
+		nameSelect.SetOptions(names)
+	}
+	refresh()
+	nameSelect.OnChanged = func(name string) {
+		p, ok := findProfile(loadProfiles(prefs), strings.TrimSpace(name))
+		if !ok {
+			return
+		}
+		sharedCapEntry.SetText("")
+		entriesEntry.SetText(formatPresets(p.Entries))
+		if p.SharedCapKbps > 0 {
+			sharedCapEntry.SetText(strconv.Itoa(p.SharedCapKbps))
+			entriesEntry.SetText(formatProfileNames(p.Entries))
+		}
+	}
+
+	// Profile as entered, validated
+	current := func() (limitProfile, error) {
+		name := strings.TrimSpace(nameSelect.Text)
+		if name == "" {
+			return limitProfile{}, errors.New("enter a profile name")
+		}
+		sharedCap := 0
+		if text := strings.TrimSpace(sharedCapEntry.Text); text != "" {
+			var err error
+			if sharedCap, err = parseLimit(text, unitKbps); err != nil {
+				return limitProfile{}, fmt.Errorf("shared cap: %w", err)
+			}
+		}
+		var entries []limitPreset
+		var err error
+		if sharedCap > 0 {
+			entries = parseProfileNames(entriesEntry.Text)
+		} else if entries, err = parsePresets(entriesEntry.Text); err != nil {
+			return limitProfile{}, err
+		}
+		if len(entries) == 0 {
+			return limitProfile{}, errors.New("list at least one process")
+		}
+		p := limitProfile{Name: name, Entries: entries, SharedCapKbps: sharedCap}
+		return p, p.validateSharedCap()
+	}
+
+	saveButton := widget.NewButton("Save", func() {
+		p, err := current()
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		setProfile(prefs, p)
+		appendLog(fmt.Sprintf("Saved profile %q (%d process(es))", p.Name, len(p.Entries)))
+		refresh()
+	})
+	applyButton := widget.NewButton("Apply", func() {
+		p, err := current()
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		setProfile(prefs, p)
+		refresh()
+		go func() {
+			appendLog("----------------------------------------------------")
+			appendLog(fmt.Sprintf("Applying profile %q (%d process(es))", p.Name, len(p.Entries)))
+			applyProfile(p, appendLog)
+		}()
+	})
+	clearButton := widget.NewButton("Clear", func() {
+		p, ok := findProfile(loadProfiles(prefs), strings.TrimSpace(nameSelect.Text))
+		if !ok {
+			return
+		}
+		go func() {
+			appendLog("----------------------------------------------------")
+			appendLog(fmt.Sprintf("Clearing profile %q", p.Name))
+			clearProfile(p, appendLog)
+		}()
+	})
+	deleteButton := widget.NewButton("Delete", func() {
+		name := strings.TrimSpace(nameSelect.Text)
+		if _, ok := findProfile(loadProfiles(prefs), name); !ok {
+			return
+		}
+		setProfile(prefs, limitProfile{Name: name})
+		appendLog(fmt.Sprintf("Deleted profile %q; its rules stay in place", name))
+		nameSelect.SetText("")
+		entriesEntry.SetText("")
+		sharedCapEntry.SetText("")
+		refresh()
+	})
+
+	content := container.NewBorder(
+		container.NewVBox(nameSelect, sharedCapEntry),
+		container.NewHBox(saveButton, applyButton, clearButton, deleteButton),
+		nil, nil,
+		entriesEntry,
+	)
+	d := dialog.NewCustom("Profiles", "Close", content, window)
+	d.Resize(fyne.NewSize(520, 400))
+	d.Show()
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+
+	"netlimiter/netlimiter"
+)
+
+// Extra delay for a "lost" chunk. TCP can't drop bytes without corrupting the
+// stream, so loss is simulated as the retransmission the sender would do.
+const proxyRetransmitPenalty = 200 * time.Millisecond
+
+// Dials the proxy target; a variable so tests can stand in a target that
+// never answers
+var proxyDial = (&net.Dialer{Timeout: 10 * time.Second}).DialContext
+
+// Settings for the in-process TCP proxy backend.
+// Unlike QoS this only affects apps that connect through ListenAddr.
+type proxyConfig struct {
+	ListenAddr  string
+	TargetAddr  string
+	RateKbps    int
+	Latency     time.Duration
+	Jitter      time.Duration
+	LossPercent float64
+}
+
+// Local TCP forwarder that throttles, delays and "drops" traffic
+type tcpProxy struct {
+	cfg    proxyConfig
+	ln     net.Listener
+	ctx    context.Context // cancelled by Close, ending dials in progress
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+}
+
+// Chunk waiting in the delay queue until its release time
+type delayedChunk struct {
+	data    []byte
+	release time.Time
+}
+
+// Check proxy settings before starting
+func (c proxyConfig) validate() error {
+	if _, _, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		return fmt.Errorf("invalid listen address: %w", err)
+	}
+	if _, _, err := net.SplitHostPort(c.TargetAddr); err != nil {
+		return fmt.Errorf("invalid target address: %w", err)
+	}
+	if c.RateKbps < 0 || c.Latency < 0 || c.Jitter < 0 {
+		return errors.New("rate, latency and jitter must not be negative")
+	}
+	if c.LossPercent < 0 || c.LossPercent > 100 {
+		return errors.New("packet loss must be between 0 and 100 percent")
+	}
+	return nil
+}
+
+// Start listening; connections are handled until Close is called
+func startProxy(cfg proxyConfig, log func(string)) (*tcpProxy, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("proxy listen error: %w", err)
+	}
+
+	p := &tcpProxy{cfg: cfg, ln: ln, conns: make(map[net.Conn]struct{})}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for {
+			client, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			p.wg.Add(1)
+			go func() {
+				defer p.wg.Done()
+				if err := p.handle(client); err != nil {
+					log("Proxy connection error: " + err.Error())
+				}
+			}()
+		}
+	}()
+	return p, nil
+}
+
+// Stop accepting, cancel dials, close open connections and wait for
+// handlers to exit. It can block until a handler's delayed chunks are
+// written, so the GUI calls it off the UI thread.
+func (p *tcpProxy) Close() error {
+	p.cancel()
+	err := p.ln.Close()
+	p.mu.Lock()
+	for c := range p.conns {
+		c.Close()
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+	return err
+}
+
+func (p *tcpProxy) track(c net.Conn, add bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if add {
+		p.conns[c] = struct{}{}
+	} else {
+		delete(p.conns, c)
+	}
+}
+
+// Forward one client connection to the target in both directions
+func (p *tcpProxy) handle(client net.Conn) error {
+	upstream, err := proxyDial(p.ctx, "tcp", p.cfg.TargetAddr)
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("dial %s: %w", p.cfg.TargetAddr, err)
+	}
+	p.track(client, true)
+	p.track(upstream, true)
+	defer func() {
+		client.Close()
+		upstream.Close()
+		p.track(client, false)
+		p.track(upstream, false)
+	}()
+	// Close may have run between the dial and tracking the connections
+	if p.ctx.Err() != nil {
+		return nil
+	}
+
+	// Each direction half-closes the other side when its source ends, so a
+	// client that sends and then shuts down writing still gets the reply.
+	// An error in either direction ends both.
+	errs := make(chan error, 2)
+	go func() { errs <- p.pipe(upstream, client) }()
+	go func() { errs <- p.pipe(client, upstream) }()
+	for range 2 {
+		if err := <-errs; err != nil {
+			client.Close()
+			upstream.Close()
+		}
+	}
+	return nil
+}
+
+// Sleep for d, returning false early when the proxy is closed
+func (p *tcpProxy) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return p.ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// Delay for the next chunk: latency ± jitter, plus a penalty when "lost"
+func (p *tcpProxy) chunkDelay() time.Duration {
+	d := p.cfg.Latency
+	if p.cfg.Jitter > 0 {
+		d += time.Duration(rand.Int64N(int64(2*p.cfg.Jitter))) - p.cfg.Jitter
+	}
+	if p.cfg.LossPercent > 0 && rand.Float64()*100 < p.cfg.LossPercent {
+		d += proxyRetransmitPenalty
+	}
+	return max(d, 0)
+}
+
+// Copy src to dst through a delay queue so latency doesn't cut throughput.
+// Release times never go backwards, which keeps the stream in order. When
+// src ends cleanly dst is closed for writing; otherwise the error is returned.
+func (p *tcpProxy) pipe(dst io.Writer, src io.Reader) error {
+	queue := make(chan delayedChunk, 64)
+	var readErr error // set before queue is closed
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer close(queue)
+		var lastRelease time.Time
+		buf := make([]byte, 16*1024)
+		for {
+			n, err := src.Read(buf)
+			if n > 0 {
+				release := time.Now().Add(p.chunkDelay())
+				if release.Before(lastRelease) {
+					release = lastRelease
+				}
+				lastRelease = release
+				select {
+				case queue <- delayedChunk{data: append([]byte(nil), buf[:n]...), release: release}:
+				case <-p.ctx.Done():
+					readErr = p.ctx.Err()
+					return
+				}
+
+				if p.cfg.RateKbps > 0 && !p.sleep(time.Duration(int64(n)*8*int64(time.Second)/netlimiter.KbpsToBitsPerSecond(p.cfg.RateKbps))) {
+					readErr = p.ctx.Err()
+					return
+				}
+			}
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					readErr = err
+				}
+				return
+			}
+		}
+	}()
+
+	for chunk := range queue {
+		var err error
+		if p.sleep(time.Until(chunk.release)) {
+			_, err = dst.Write(chunk.data)
+		} else {
+			err = p.ctx.Err()
+		}
+		if err != nil {
+			// Drain so the reader goroutine can exit once handle closes src
+			go func() {
+				for range queue {
+				}
+			}()
+			return err
+		}
+	}
+	if readErr != nil {
+		return readErr
+	}
+	if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+// Advanced panel for the proxy backend (latency, jitter and loss simulation)
+func proxyPanel(appendLog func(string)) fyne.CanvasObject {
+	listenEntry := widget.NewEntry()
+	listenEntry.SetText("127.0.0.1:8081")
+	targetEntry := widget.NewEntry()
+	targetEntry.SetPlaceHolder("host:port the app should really reach")
+	rateEntry := widget.NewEntry()
+	rateEntry.SetPlaceHolder("0 = unlimited")
+	latencyEntry := widget.NewEntry()
+	latencyEntry.SetPlaceHolder("0")
+	jitterEntry := widget.NewEntry()
+	jitterEntry.SetPlaceHolder("0")
+	lossEntry := widget.NewEntry()
+	lossEntry.SetPlaceHolder("0")
+
+	var running *tcpProxy
+	var toggle *widget.Button
+	toggle = widget.NewButton("Start Proxy", func() {
+		if running != nil {
+			// Closing waits for the connection handlers, so keep it off the UI thread
+			stopping := running
+			running = nil
+			toggle.Disable()
+			toggle.SetText("Stopping Proxy...")
+			go func() {
+				stopping.Close()
+				appendLog("Proxy stopped")
+				fyne.Do(func() {
+					toggle.SetText("Start Proxy")
+					toggle.Enable()
+				})
+			}()
+			return
+		}
+
+		parse := func(s string) (int, error) {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				return 0, nil
+			}
+			return strconv.Atoi(s)
+		}
+		rate, err1 := parse(rateEntry.Text)
+		latency, err2 := parse(latencyEntry.Text)
+		jitter, err3 := parse(jitterEntry.Text)
+		loss, err4 := parse(lossEntry.Text)
+		if err := errors.Join(err1, err2, err3, err4); err != nil {
+			appendLog("Error: proxy fields must be integers")
+			return
+		}
+
+		cfg := proxyConfig{
+			ListenAddr:  strings.TrimSpace(listenEntry.Text),
+			TargetAddr:  strings.TrimSpace(targetEntry.Text),
+			RateKbps:    rate,
+			Latency:     time.Duration(latency) * time.Millisecond,
+			Jitter:      time.Duration(jitter) * time.Millisecond,
+			LossPercent: float64(loss),
+		}
+		p, err := startProxy(cfg, appendLog)
+		if err != nil {
+			appendLog("Proxy error: " + err.Error())
+			return
+		}
+		running = p
+		toggle.SetText("Stop Proxy")
+		appendLog(fmt.Sprintf("Proxy listening on %s -> %s (rate %d kbps, latency %v ± %v, loss %d%%)",
+			cfg.ListenAddr, cfg.TargetAddr, cfg.RateKbps, cfg.Latency, cfg.Jitter, loss))
+	})
+
+	return widget.NewForm(
+		widget.NewFormItem("Listen", listenEntry),
+		widget.NewFormItem("Target", targetEntry),
+		widget.NewFormItem("Rate (kbps)", rateEntry),
+		widget.NewFormItem("Latency (ms)", latencyEntry),
+		widget.NewFormItem("Jitter (ms)", jitterEntry),
+		widget.NewFormItem("Packet loss (%)", lossEntry),
+		widget.NewFormItem("", toggle),
+	)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Close returns promptly while a connection is open and data is in flight
+func TestProxyCloseWithOpenConnection(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if c, err := target.Accept(); err == nil {
+			accepted <- c
+		}
+	}()
+
+	p, err := startProxy(proxyConfig{ListenAddr: "127.0.0.1:0", TargetAddr: target.Addr().String(), RateKbps: 8, Latency: 50 * time.Millisecond}, func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := net.Dial("tcp", p.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.Write(make([]byte, 4096)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case c := <-accepted:
+		defer c.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("proxy never dialled the target")
+	}
+
+	closeWithin(t, p, 2*time.Second)
+}
+
+// Close aborts a handler still dialling a target that never answers
+func TestProxyCloseAbortsPendingDial(t *testing.T) {
+	dialing := make(chan struct{})
+	saved := proxyDial
+	proxyDial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		close(dialing)
+		<-ctx.Done() // the SYN is never answered; only Close ends the dial
+		return nil, ctx.Err()
+	}
+	t.Cleanup(func() { proxyDial = saved })
+
+	var mu sync.Mutex
+	var logged []string
+	p, err := startProxy(proxyConfig{ListenAddr: "127.0.0.1:0", TargetAddr: "192.0.2.1:80"}, func(line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		logged = append(logged, line)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := net.Dial("tcp", p.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	select {
+	case <-dialing:
+	case <-time.After(5 * time.Second):
+		t.Fatal("proxy never started dialling the target")
+	}
+
+	// Close waits for every handler, so returning means handle exited
+	closeWithin(t, p, 2*time.Second)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(logged) != 1 || !strings.Contains(logged[0], "context canceled") {
+		t.Errorf("handler logged %q, want its dial cancelled", logged)
+	}
+	// The client's connection was closed with the handler
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := client.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Errorf("client read after Close = %v, want EOF", err)
+	}
+}
+
+// A client that sends, shuts down writing and then waits still gets the reply
+func TestProxyForwardsHalfClose(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	go func() {
+		c, err := target.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		request, _ := io.ReadAll(c) // until the client's half-close arrives
+		c.Write([]byte("got " + string(request)))
+	}()
+
+	p, err := startProxy(proxyConfig{ListenAddr: "127.0.0.1:0", TargetAddr: target.Addr().String(), Latency: 20 * time.Millisecond}, func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeWithin(t, p, 2*time.Second)
+	client, err := net.Dial("tcp", p.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.(*net.TCPConn).CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reply, err := io.ReadAll(client)
+	if err != nil || string(reply) != "got hello" {
+		t.Errorf("reply = %q, %v; want %q", reply, err, "got hello")
+	}
+}
+
+func closeWithin(t *testing.T, p *tcpProxy, limit time.Duration) {
+	t.Helper()
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(limit):
+		t.Fatalf("Close did not return within %v", limit)
+	}
+}
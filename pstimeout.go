@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+
+	"netlimiter/netlimiter"
+)
+
+const (
+	psTimeoutPrefKey  = "powerShellTimeoutSec"
+	psAttemptsPrefKey = "powerShellAttempts"
+
+	// A PowerShell run is killed after this long unless Settings says otherwise
+	defaultPSTimeout = 30 * time.Second
+	minPSTimeout     = 5 * time.Second
+
+	// How long a killed run's output pipes may stay open before Wait gives up
+	psWaitDelay = 2 * time.Second
+
+	// Limiter scripts failing transiently are run up to this many times in
+	// all, waiting psRetryDelay before the second and twice as long each time after
+	defaultPSAttempts = 3
+	maxPSAttempts     = 10
+	psRetryDelay      = time.Second
+)
+
+// Deadline, retries and in-flight runs of PowerShell scripts. Package-level
+// like the hooks, since every limiter operation goes through it.
+type psRunControl struct {
+	mu       sync.Mutex
+	timeout  time.Duration
+	attempts int
+	next     int
+	running  map[int]context.CancelFunc
+}
+
+var psRuns = &psRunControl{timeout: defaultPSTimeout, attempts: defaultPSAttempts, running: make(map[int]context.CancelFunc)}
+
+// Whether a failed limiter script is worth running again. Replaceable like
+// scriptLimiter.Run; access-denied, validation and timeout failures never are by default.
+var retryPowerShell = func(err error) bool { return errors.Is(err, errTransientWMI) }
+
+// Load the timeout and attempt count from preferences
+func (c *psRunControl) configure(prefs fyne.Preferences) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeout = psTimeout(prefs)
+	c.attempts = psAttempts(prefs)
+}
+
+// Stored attempt count, from 1 (no retries) to maxPSAttempts
+func psAttempts(prefs fyne.Preferences) int {
+	return min(max(prefs.IntWithFallback(psAttemptsPrefKey, defaultPSAttempts), 1), maxPSAttempts)
+}
+
+// Run a limiter script through executePowerShell, running it again with exponential
+// backoff while it fails in a way retryPowerShell accepts. Retries are
+// noted ahead of the output, so they reach the log with it.
+func runWithRetry(script string) (string, error) {
+	psRuns.mu.Lock()
+	attempts := psRuns.attempts
+	psRuns.mu.Unlock()
+
+	var notes strings.Builder
+	delay := psRetryDelay
+	for attempt := 1; ; attempt++ {
+		out, err := executePowerShell(script)
+		if err == nil || attempt >= attempts || !retryPowerShell(err) {
+			if attempt > 1 {
+				outcome := "succeeded"
+				if err != nil {
+					outcome = "failed, giving up"
+				}
+				fmt.Fprintf(&notes, "Attempt %d of %d %s\n", attempt, attempts, outcome)
+			}
+			return notes.String() + out, err
+		}
+		fmt.Fprintf(&notes, "Attempt %d of %d failed: %s Retrying in %v\n", attempt, attempts, interpretPowerShellError(out, err), delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Stored timeout, never below minPSTimeout
+func psTimeout(prefs fyne.Preferences) time.Duration {
+	d := time.Duration(prefs.IntWithFallback(psTimeoutPrefKey, int(defaultPSTimeout/time.Second))) * time.Second
+	return max(d, minPSTimeout)
+}
+
+// Context for one run, ended by the timeout or cancelAll; call done when the run finishes
+func (c *psRunControl) start() (ctx context.Context, timeout time.Duration, done func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timeout = c.timeout
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	id := c.next
+	c.next++
+	c.running[id] = cancel
+	return ctx, timeout, func() {
+		c.mu.Lock()
+		delete(c.running, id)
+		c.mu.Unlock()
+		cancel()
+	}
+}
+
+// Kill every PowerShell run in flight; returns how many there were
+func (c *psRunControl) cancelAll() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cancel := range c.running {
+		cancel()
+	}
+	return len(c.running)
+}
+
+// PowerShell command for script, killed when ctx ends. Replaceable, so a
+// stand-in command can check the timeout and cancellation.
+var powerShellCommand = func(ctx context.Context, script string) *exec.Cmd {
+	cmd := netlimiter.PowerShellCommand(ctx, script)
+	cmd.WaitDelay = psWaitDelay
+	return cmd
+}
+
+// Run script through powerShellCommand, stopping it on timeout or cancelAll
+func runPowerShellCommand(script string) ([]byte, error) {
+	ctx, timeout, done := psRuns.start()
+	defer done()
+	out, err := powerShellCommand(ctx, script).CombinedOutput()
+	return out, psContextError(ctx, timeout, err)
+}
+
+// Explain a run that failed because its context ended
+func psContextError(ctx context.Context, timeout time.Duration, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("PowerShell did not finish within %v and was stopped: %w", timeout, errPowerShellTimeout)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("PowerShell run cancelled: %w", context.Canceled)
+	}
+	return err
+}
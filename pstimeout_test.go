@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// Set in the environment of the stand-in PowerShell started by slowPowerShell
+const slowHelperEnv = "NETLIMITER_TEST_SLOW_POWERSHELL"
+
+// Not a real test: the stand-in for a hung powershell.exe, run as a child
+// process of the test binary
+func TestSlowPowerShellHelper(t *testing.T) {
+	if os.Getenv(slowHelperEnv) == "" {
+		t.Skip("only runs as the stand-in command")
+	}
+	time.Sleep(time.Minute)
+	os.Exit(0)
+}
+
+// Point powerShellCommand at a command that hangs for a minute, with psRuns
+// timing runs out after timeout, for the rest of the test
+func slowPowerShell(t *testing.T, timeout time.Duration) {
+	t.Helper()
+	saved := powerShellCommand
+	powerShellCommand = func(ctx context.Context, script string) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=^TestSlowPowerShellHelper$")
+		cmd.Env = append(os.Environ(), slowHelperEnv+"=1")
+		cmd.WaitDelay = psWaitDelay
+		return cmd
+	}
+	psRuns.mu.Lock()
+	savedTimeout := psRuns.timeout
+	psRuns.timeout = timeout
+	psRuns.mu.Unlock()
+	t.Cleanup(func() {
+		powerShellCommand = saved
+		psRuns.mu.Lock()
+		psRuns.timeout = savedTimeout
+		psRuns.mu.Unlock()
+	})
+}
+
+func TestPowerShellTimeoutKillsRun(t *testing.T) {
+	slowPowerShell(t, 500*time.Millisecond)
+	start := time.Now()
+	_, err := runPowerShellCommand("Start-Sleep 60")
+	if !errors.Is(err, errPowerShellTimeout) {
+		t.Fatalf("err = %v, want errPowerShellTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond+psWaitDelay+5*time.Second {
+		t.Errorf("run took %v after timing out; the command was not killed", elapsed)
+	}
+}
+
+func TestPowerShellCancelAllKillsRun(t *testing.T) {
+	slowPowerShell(t, time.Minute)
+	errc := make(chan error, 1)
+	go func() {
+		_, err := runPowerShellCommand("Start-Sleep 60")
+		errc <- err
+	}()
+
+	// Wait for the run to register before cancelling it
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		psRuns.mu.Lock()
+		n := len(psRuns.running)
+		psRuns.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("run never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := psRuns.cancelAll(); n != 1 {
+		t.Errorf("cancelAll() = %d, want 1 run cancelled", n)
+	}
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+		if errors.Is(err, errPowerShellTimeout) {
+			t.Errorf("cancelled run reported as a timeout: %v", err)
+		}
+	case <-time.After(psWaitDelay + 10*time.Second):
+		t.Fatal("cancelled run did not return; the command was not killed")
+	}
+
+	psRuns.mu.Lock()
+	defer psRuns.mu.Unlock()
+	if len(psRuns.running) != 0 {
+		t.Errorf("%d run(s) still registered after finishing", len(psRuns.running))
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+const (
+	quickLimitPrefKey     = "quickLimitKbps"
+	defaultQuickLimitKbps = 1000
+
+	// How often the foreground window is sampled
+	foregroundPollInterval = time.Second
+)
+
+// Last foreground process other than this app.
+// Opening the tray menu or the confirmation dialog moves focus away from the
+// app the user meant, so the target is remembered from before that happened.
+type foregroundTracker struct {
+	mu  sync.Mutex
+	pid int32
+}
+
+var lastForeground = &foregroundTracker{}
+
+// Sample the foreground window until the app exits
+func (t *foregroundTracker) watch() {
+	self := int32(os.Getpid())
+	ticker := time.NewTicker(foregroundPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		pid, err := foregroundProcessID()
+		if err != nil || pid == self {
+			continue
+		}
+		t.mu.Lock()
+		t.pid = pid
+		t.mu.Unlock()
+	}
+}
+
+// PID of the most recent foreground app, 0 if none was seen yet
+func (t *foregroundTracker) last() int32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pid
+}
+
+// Default rate for quick-limit, both directions
+func quickLimitKbps(prefs fyne.Preferences) int {
+	// 0/0 would block instead of limit
+	if kbps := prefs.IntWithFallback(quickLimitPrefKey, defaultQuickLimitKbps); kbps > 0 {
+		return kbps
+	}
+	return defaultQuickLimitKbps
+}
+
+// Resolve the app the user was last using to a quick-limit rule
+func quickLimitRule(src ProcessSource, kbps int) (LimitRule, error) {
+	pid := lastForeground.last()
+	if pid == 0 {
+		if _, err := foregroundProcessID(); err != nil {
+			return LimitRule{}, fmt.Errorf("no foreground app detected: %w", err)
+		}
+		return LimitRule{}, errors.New("no foreground app detected yet")
+	}
+	exePath, err := src.ExePath(pid)
+	if err != nil {
+		return LimitRule{}, fmt.Errorf("could not get executable path for PID %d: %w", pid, err)
+	}
+	return LimitRule{
+		Process: filepath.Base(exePath), ExePath: exePath,
+		InKbps: kbps, OutKbps: kbps,
+	}, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// How often watched processes are checked for a restart
+const restartCheckInterval = 5 * time.Second
+
+// Whether a process restarted between two PID snapshots: it came back after
+// not running, or none of the previously seen PIDs survived. Helper processes
+// coming and going alongside a surviving PID don't count.
+func processRestarted(prev, cur map[int32]bool) bool {
+	if len(cur) == 0 {
+		return false
+	}
+	if len(prev) == 0 {
+		return true
+	}
+	for pid := range prev {
+		if cur[pid] {
+			return false
+		}
+	}
+	return true
+}
+
+// Set of PIDs
+func pidSet(pids []int32) map[int32]bool {
+	set := make(map[int32]bool, len(pids))
+	for _, pid := range pids {
+		set[pid] = true
+	}
+	return set
+}
+
+// Reapply rules flagged WatchRestart whenever their process restarts.
+// The first snapshot of each rule only establishes a baseline.
+func watchRestarts(log func(string)) {
+	seen := make(map[string]map[int32]bool)
+	ticker := time.NewTicker(restartCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		procCache.invalidate()
+		for _, r := range tracked.list() {
+			if !r.WatchRestart || r.Package != "" || r.status() != statusActive {
+				delete(seen, r.key())
+				continue
+			}
+			pids, err := processes.FindPIDsByName(r.Process)
+			if err != nil {
+				continue
+			}
+			cur := pidSet(pids)
+			prev, known := seen[r.key()]
+			seen[r.key()] = cur
+			if !known || !processRestarted(prev, cur) {
+				continue
+			}
+
+			log(fmt.Sprintf("%s restarted (%d PID(s) now running), reapplying %s", r.Process, len(cur), r.describe()))
+			applyLog, err := applyRule(r).result()
+			log(applyLog)
+			if err != nil {
+				log("Reapply error: " + err.Error())
+			}
+			if saveErr := tracked.recordResult(r, err); saveErr != nil {
+				log("Could not save tracked rules: " + saveErr.Error())
+			}
+			auditOrLog(log, "reapply", r.Process, r.ExePath, map[string]any{"trigger": "restart"}, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Difference between the applied rules and a rule set about to replace them
+type ruleDiff struct {
+	Added   []LimitRule
+	Removed []LimitRule
+	Changed []ruleChange
+	Same    int
+}
+
+type ruleChange struct {
+	Old, New LimitRule
+}
+
+// Whether two rules put the same QoS/firewall state in place. Notes and
+// timestamps don't count; neither does anything the backend never sees.
+func sameRuleSettings(a, b LimitRule) bool {
+	return strings.EqualFold(a.Process, b.Process) && strings.EqualFold(a.ExePath, b.ExePath) &&
+		slices.EqualFunc(a.ExtraPaths, b.ExtraPaths, strings.EqualFold) &&
+		strings.EqualFold(a.Package, b.Package) &&
+		a.InKbps == b.InKbps && a.OutKbps == b.OutKbps && a.Blocked == b.Blocked &&
+		a.Priority == b.Priority && a.MeteredOnly == b.MeteredOnly && a.WatchRestart == b.WatchRestart &&
+		a.NameOnly == b.NameOnly && a.RemoteAddr == b.RemoteAddr && a.Protocol == b.Protocol
+}
+
+// Compare rules by key. A current rule that failed or is paused counts as
+// changed even with identical settings, so switching fixes it up.
+func diffRules(current, target []LimitRule) ruleDiff {
+	var d ruleDiff
+	byKey := make(map[string]LimitRule, len(current))
+	for _, r := range current {
+		byKey[r.key()] = r
+	}
+	for _, r := range target {
+		old, ok := byKey[r.key()]
+		delete(byKey, r.key())
+		switch {
+		case !ok:
+			d.Added = append(d.Added, r)
+		case sameRuleSettings(old, r) && (old.status() == statusActive || old.status() == statusPending):
+			d.Same++
+		default:
+			d.Changed = append(d.Changed, ruleChange{Old: old, New: r})
+		}
+	}
+	// Keep the removal order stable for display
+	for _, r := range current {
+		if _, ok := byKey[r.key()]; ok {
+			d.Removed = append(d.Removed, r)
+		}
+	}
+	return d
+}
+
+func (d ruleDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Readable summary for the log and the confirm dialog
+func (d ruleDiff) format() string {
+	if d.empty() {
+		return fmt.Sprintf("No changes (%d rule(s) already in place)", d.Same)
+	}
+	var b strings.Builder
+	for _, r := range d.Added {
+		fmt.Fprintf(&b, "+ %s\n", r.describe())
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(&b, "~ %s\n    was %s", c.New.describe(), c.Old.describe())
+		if c.Old.status() != statusActive {
+			fmt.Fprintf(&b, " [%s]", c.Old.status())
+		}
+		b.WriteString("\n")
+	}
+	for _, r := range d.Removed {
+		fmt.Fprintf(&b, "- %s\n", r.describe())
+	}
+	if d.Same > 0 {
+		fmt.Fprintf(&b, "(%d rule(s) unchanged)\n", d.Same)
+	}
+	return b.String()
+}
+
+// Apply only the delta: clear removed rules, replace changed ones, add new ones.
+// Metered-only rules start pending; the metered watcher applies them.
+func applyRuleDiff(d ruleDiff, params map[string]any, log func(string)) {
+	remove := func(r LimitRule) bool {
+		clearLog, err := clearRule(r).result()
+		log("Clearing " + r.describe())
+		log(clearLog)
+		auditOrLog(log, "clear", r.Process, r.ExePath, params, err)
+		if err != nil {
+			log("Clear error: " + err.Error())
+			return false
+		}
+		if err := tracked.remove(r.key()); err != nil {
+			log("Could not save tracked rules: " + err.Error())
+		}
+		return true
+	}
+	apply := func(r LimitRule) {
+		if r.MeteredOnly {
+			if err := tracked.markPending(r); err != nil {
+				log("Could not save tracked rules: " + err.Error())
+			}
+			return
+		}
+		applyLog, err := applyRule(r).result()
+		log("Applying " + r.describe())
+		log(applyLog)
+		if err != nil {
+			log("Apply error: " + err.Error())
+		}
+		if saveErr := tracked.recordResult(r, err); saveErr != nil {
+			log("Could not save tracked rules: " + saveErr.Error())
+		}
+		auditOrLog(log, "limit", r.Process, r.ExePath, params, err)
+	}
+
+	for _, r := range d.Removed {
+		remove(r)
+	}
+	for _, c := range d.Changed {
+		if remove(c.Old) {
+			apply(c.New)
+		}
+	}
+	for _, r := range d.Added {
+		apply(r)
+	}
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"netlimiter/netlimiter"
+)
+
+const (
+	rulesFileName = "rules.json"
+
+	// Clear every rule before applying a new one instead of only the target's own
+	resetBeforeApplyPrefKey = "resetBeforeApply"
+)
+
+// Lifecycle state of a tracked rule, shown as a colored dot in the rules table
+type ruleStatus string
+
+const (
+	statusActive  ruleStatus = "active"
+	statusPaused  ruleStatus = "paused"
+	statusFailed  ruleStatus = "failed"
+	statusPending ruleStatus = "pending"
+	statusBoosted ruleStatus = "boosted" // limit lifted until BoostUntil
+)
+
+// Rule applied by this tool, tracked so it can be checked and reapplied later
+type LimitRule struct {
+	Process      string      `json:"process"`
+	ExePath      string      `json:"exePath,omitempty"`
+	ExtraPaths   []string    `json:"extraPaths,omitempty"` // other paths the matched instances (and their children) run from
+	Package      string      `json:"package,omitempty"`
+	InKbps       int         `json:"inKbps"`
+	OutKbps      int         `json:"outKbps"`
+	Blocked      bool        `json:"blocked"`
+	Priority     qosPriority `json:"priority,omitempty"`
+	MeteredOnly  bool        `json:"meteredOnly,omitempty"`   // only in effect on a metered connection
+	WatchRestart bool        `json:"watchRestart,omitempty"`  // reapply when the process restarts
+	NameOnly     bool        `json:"nameOnly,omitempty"`      // QoS matches the image name, not the full path
+	RemoteAddr   string      `json:"remoteAddress,omitempty"` // block only traffic to these addresses (see blockScope)
+	Protocol     string      `json:"protocol,omitempty"`      // block only this protocol
+	PolicyStore  string      `json:"policyStore,omitempty"`   // QoS store the policies went to; "" is ActiveStore
+	Note         string      `json:"note,omitempty"`          // free text, never affects QoS/firewall state
+	AppliedAt    time.Time   `json:"appliedAt"`
+	Status       ruleStatus  `json:"status,omitempty"`
+	BoostUntil   time.Time   `json:"boostUntil,omitempty"`
+	LastError    string      `json:"lastError,omitempty"`
+}
+
+// Rule status; rules saved before statuses existed count as active
+func (r LimitRule) status() ruleStatus {
+	if r.Status == "" {
+		return statusActive
+	}
+	return r.Status
+}
+
+// Identity of a rule: the package family name or the lower-cased process name
+func (r LimitRule) key() string {
+	if r.Package != "" {
+		return packagePolicyKey(r.Package)
+	}
+	return strings.ToLower(r.Process)
+}
+
+// Key the rule's QoS policy and firewall rules are named after.
+// Process rules use the executable's file name, which is what the backend sees.
+func (r LimitRule) policyKey() string {
+	if r.Package != "" || r.ExePath == "" {
+		return r.key()
+	}
+	return netlimiter.ExePolicyKey(r.ExePath)
+}
+
+// Executable a process rule puts a policy in place for
+type exeTarget struct {
+	Key  string
+	Path string
+}
+
+// Every executable a rule covers: the main path under the rule's policy key,
+// then each extra path under a key of its own. A name-only QoS rule matches
+// the bare image name, which covers every path with one policy.
+func (r LimitRule) exeTargets() []exeTarget {
+	if r.nameOnlyQoS() {
+		// The name policy covers extra paths with the same file name;
+		// others (e.g. child processes) still need one of their own
+		name := netlimiter.ExeFileName(r.ExePath)
+		targets := []exeTarget{{Key: r.policyKey(), Path: name}}
+		for _, p := range r.ExtraPaths {
+			if !strings.EqualFold(netlimiter.ExeFileName(p), name) {
+				targets = append(targets, exeTarget{Key: extraPathPolicyKey(p), Path: p})
+			}
+		}
+		return targets
+	}
+	targets := []exeTarget{{Key: r.policyKey(), Path: r.ExePath}}
+	if r.Package != "" {
+		return targets
+	}
+	for _, p := range r.ExtraPaths {
+		targets = append(targets, exeTarget{Key: extraPathPolicyKey(p), Path: p})
+	}
+	return targets
+}
+
+// Whether the rule's QoS policy matches by image name. Firewall rules need
+// a full path, so blocks always use one.
+func (r LimitRule) nameOnlyQoS() bool {
+	return r.NameOnly && r.Package == "" && r.ExePath != "" && !r.Blocked
+}
+
+// Log line explaining what name-only matching means for r, "" when it doesn't apply
+func nameOnlyNote(r LimitRule) string {
+	switch {
+	case !r.NameOnly || r.Package != "":
+		return ""
+	case r.Blocked:
+		return "Note: firewall rules match a full path, so name-only matching doesn't apply to blocking"
+	}
+	return fmt.Sprintf("Note: matching by name only; the QoS policy covers every %s on this machine, whatever folder it runs from (e.g. under svchost or an Electron wrapper), not just %s",
+		netlimiter.ExeFileName(r.ExePath), r.ExePath)
+}
+
+// Policy key for an extra path. Extra paths usually share the main path's
+// file name, so a hash of the full path keeps their policies apart.
+func extraPathPolicyKey(exePath string) string {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(exePath)))
+	return fmt.Sprintf("%s_%08x", netlimiter.ExePolicyKey(exePath), h.Sum32())
+}
+
+// Remote addresses and protocol a block is narrowed to
+func (r LimitRule) scope() blockScope {
+	return blockScope{RemoteAddress: r.RemoteAddr, Protocol: r.Protocol}
+}
+
+// Short human-readable description used in logs and dialogs
+func (r LimitRule) describe() string {
+	target := r.Process
+	if r.Package != "" {
+		target = r.Package
+	}
+	desc := fmt.Sprintf("%s: in %d / out %d kbps", target, r.InKbps, r.OutKbps)
+	if r.Blocked {
+		desc = target + ": blocked" + r.scope().describe()
+	}
+	if r.Priority.active() {
+		desc += ", " + strings.ToLower(string(r.Priority)) + " priority"
+	}
+	if r.nameOnlyQoS() {
+		desc += " (name only)"
+	} else if len(r.ExtraPaths) > 0 {
+		desc += fmt.Sprintf(" (%d paths)", len(r.ExtraPaths)+1)
+	}
+	if r.MeteredOnly {
+		desc += " (metered only)"
+	}
+	if r.PolicyStore == netlimiter.PersistentStore {
+		desc += " (persistent)"
+	}
+	return desc
+}
+
+// Rules currently applied by this tool, persisted to rules.json
+type ruleStore struct {
+	mu    sync.Mutex
+	path  string
+	rules []LimitRule
+
+	// Called after every change, outside the lock (used to refresh the table)
+	onChange func()
+}
+
+var tracked = &ruleStore{}
+
+// Load tracked rules from disk; a missing file means no rules
+func (s *ruleStore) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir, err := appDataDir()
+	if err != nil {
+		return err
+	}
+	s.path = filepath.Join(dir, rulesFileName)
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &s.rules); err != nil {
+		// Start empty, keeping the unreadable file aside rather than overwriting it
+		s.rules = nil
+		if renameErr := os.Rename(s.path, s.path+".corrupt"); renameErr == nil {
+			return fmt.Errorf("could not parse %s, moved it to %s.corrupt: %w", s.path, rulesFileName, err)
+		}
+		return fmt.Errorf("could not parse %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// Write tracked rules to disk; caller holds s.mu
+func (s *ruleStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.rules, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o600)
+}
+
+// Copy of the tracked rules
+func (s *ruleStore) list() []LimitRule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]LimitRule(nil), s.rules...)
+}
+
+// Look up the tracked rule with the same key
+func (s *ruleStore) get(key string) (LimitRule, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rules {
+		if r.key() == key {
+			return r, true
+		}
+	}
+	return LimitRule{}, false
+}
+
+// Run the change hook, if any
+func (s *ruleStore) changed() {
+	if s.onChange != nil {
+		s.onChange()
+	}
+}
+
+// Add a rule, replacing any tracked rule with the same key
+func (s *ruleStore) put(r LimitRule) error {
+	if dryRun.Load() {
+		return nil
+	}
+	defer s.changed()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.rules {
+		if s.rules[i].key() == r.key() {
+			s.rules[i] = r
+			return s.saveLocked()
+		}
+	}
+	s.rules = append(s.rules, r)
+	return s.saveLocked()
+}
+
+// Change a rule's note; the applied QoS/firewall state is untouched
+func (s *ruleStore) setNote(key, note string) error {
+	defer s.changed()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.rules {
+		if s.rules[i].key() == key {
+			s.rules[i].Note = note
+			return s.saveLocked()
+		}
+	}
+	return fmt.Errorf("no tracked rule for %s", key)
+}
+
+// Stop tracking the rule with the given key
+func (s *ruleStore) remove(key string) error {
+	if dryRun.Load() {
+		return nil
+	}
+	defer s.changed()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.rules {
+		if s.rules[i].key() == key {
+			s.rules = append(s.rules[:i], s.rules[i+1:]...)
+			break
+		}
+	}
+	return s.saveLocked()
+}
+
+// Forget every tracked rule
+func (s *ruleStore) clear() error {
+	if dryRun.Load() {
+		return nil
+	}
+	defer s.changed()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = nil
+	return s.saveLocked()
+}
+
+// Record the outcome of applying r: active on success, failed with the error otherwise
+func (s *ruleStore) recordResult(r LimitRule, opErr error) error {
+	r.AppliedAt = time.Now()
+	r.BoostUntil = time.Time{}
+	r.Status, r.LastError = statusActive, ""
+	r.PolicyStore = ""
+	if !r.Blocked && r.Package == "" && qosStore() != netlimiter.ActiveStore {
+		r.PolicyStore = qosStore()
+	}
+	if opErr != nil {
+		r.Status, r.LastError = statusFailed, opErr.Error()
+	}
+	return s.put(r)
+}
+
+// Record that r is about to be applied
+func (s *ruleStore) markPending(r LimitRule) error {
+	r.Status, r.LastError = statusPending, ""
+	r.BoostUntil = time.Time{}
+	return s.put(r)
+}
+
+// Apply a tracked rule through the active backend. When the rule covers
+// several executable paths every one is attempted and the log reports
+// which succeeded; the failures are joined into the returned error.
+func applyRule(r LimitRule) *OpResult {
+	switch {
+	case r.Package != "" && r.Blocked:
+		return backend.BlockInternetForPackage(r.Package)
+	case r.Package != "":
+		return backend.ApplyLimitForPackage(r.Package, r.InKbps, r.OutKbps)
+	}
+
+	res := &OpResult{}
+	if note := nameOnlyNote(r); note != "" {
+		res.warn("%s", note)
+	}
+	targets := r.exeTargets()
+	if len(targets) == 1 {
+		applied := applyExeTarget(r, targets[0])
+		res.merge(applied).Err = applied.Err
+		return res
+	}
+	var errs []error
+	for _, t := range targets {
+		applied := applyExeTarget(r, t)
+		res.merge(applied)
+		if applied.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.Path, applied.Err))
+		}
+	}
+	summary := res.succeed
+	if len(errs) > 0 {
+		summary = res.warn
+	}
+	summary("%d of %d path(s) applied", len(targets)-len(errs), len(targets))
+	for _, err := range errs {
+		res.fail("  failed: %s", err)
+	}
+	res.Err = errors.Join(errs...)
+	return res
+}
+
+// Apply a process rule's limit, block or priority to one executable
+func applyExeTarget(r LimitRule, t exeTarget) *OpResult {
+	switch {
+	case r.Blocked:
+		return backend.BlockInternet(t.Key, t.Path, r.scope())
+	case r.Priority.active():
+		return backend.ApplyPriority(t.Key, t.Path, r.Priority, r.InKbps, r.OutKbps)
+	default:
+		return backend.ApplyLimit(t.Key, t.Path, r.InKbps, r.OutKbps)
+	}
+}
+
+// Remove a single rule's QoS policies / firewall rules, for every path it covers
+func clearRule(r LimitRule) *OpResult {
+	res := &OpResult{}
+	var errs []error
+	for _, t := range r.exeTargets() {
+		cleared := backend.ClearTarget(t.Key)
+		res.merge(cleared)
+		if cleared.Err != nil {
+			errs = append(errs, cleared.Err)
+		}
+	}
+	res.Err = errors.Join(errs...)
+	return res
+}
+
+// Tracked rule whose process now runs from a different executable path
+type pathChange struct {
+	Rule    LimitRule
+	NewPath string
+}
+
+// Find tracked rules whose recorded path no longer matches the running process.
+// Processes that aren't running are skipped; there is nothing to compare against,
+// and name-only rules don't depend on the path.
+func findStaleRules(rules []LimitRule, src ProcessSource) []pathChange {
+	procCache.invalidate()
+	var changes []pathChange
+	for _, r := range rules {
+		if r.Package != "" || r.ExePath == "" || r.nameOnlyQoS() {
+			continue
+		}
+		pids, err := src.FindPIDsByName(r.Process)
+		if err != nil || len(pids) == 0 {
+			continue
+		}
+
+		newPath := ""
+		stillMatches := false
+		for _, pid := range pids {
+			exe, err := src.ExePath(pid)
+			if err != nil {
+				continue
+			}
+			if strings.EqualFold(exe, r.ExePath) {
+				stillMatches = true
+				break
+			}
+			if newPath == "" {
+				newPath = exe
+			}
+		}
+		if !stillMatches && newPath != "" {
+			changes = append(changes, pathChange{Rule: r, NewPath: newPath})
+		}
+	}
+	return changes
+}
+
+// Move a rule to its process's new executable path: clear the old one, apply the new
+func migrateRule(c pathChange) (string, error) {
+	log := fmt.Sprintf("Migrating rule for %s\n  old path: %s\n  new path: %s\n", c.Rule.Process, c.Rule.ExePath, c.NewPath)
+
+	clearLog, err := clearRule(c.Rule).result()
+	log += clearLog
+	if err != nil {
+		return log, err
+	}
+
+	r := c.Rule
+	r.ExePath = c.NewPath
+	applyLog, err := applyRule(r).result()
+	log += applyLog
+	if saveErr := tracked.recordResult(r, err); saveErr != nil {
+		log += "Could not update tracked rules: " + saveErr.Error() + "\n"
+	}
+	return log, err
+}
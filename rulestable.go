@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Colors of the status dot in the rules table
+var statusColors = map[ruleStatus]color.Color{
+	statusActive:  color.NRGBA{R: 0x2e, G: 0xb8, B: 0x4b, A: 0xff}, // green
+	statusPaused:  color.NRGBA{R: 0x9e, G: 0x9e, B: 0x9e, A: 0xff}, // grey
+	statusFailed:  color.NRGBA{R: 0xe0, G: 0x3c, B: 0x31, A: 0xff}, // red
+	statusPending: color.NRGBA{R: 0xf2, G: 0xa9, B: 0x00, A: 0xff}, // amber
+	statusBoosted: color.NRGBA{R: 0x2f, G: 0x80, B: 0xed, A: 0xff}, // blue
+}
+
+var rulesTableHeaders = []string{"", "Target", "Limit", "Status", "Applied", "Used since applied", "Note"}
+
+// Text shown in a rules table cell (column 0 is the status dot)
+func rulesTableCell(r LimitRule, col int) string {
+	switch col {
+	case 1:
+		if r.Package != "" {
+			return r.Package
+		}
+		if r.ExePath != "" {
+			return r.ExePath
+		}
+		return r.Process
+	case 2:
+		if r.Blocked {
+			return "blocked"
+		}
+		return formatLimit(r.InKbps, r.OutKbps)
+	case 3:
+		if r.status() == statusBoosted {
+			return "boosted " + boostRemaining(r, time.Now())
+		}
+		if r.LastError != "" {
+			return string(r.status()) + ": " + r.LastError
+		}
+		return string(r.status())
+	case 4:
+		if r.AppliedAt.IsZero() {
+			return ""
+		}
+		return r.AppliedAt.Format("2006-01-02 15:04")
+	case 5:
+		if used, ok := ruleUsage.used(r); ok {
+			return formatBytes(used)
+		}
+		return ""
+	case 6:
+		return r.Note
+	}
+	return ""
+}
+
+// Format IN/OUT limits for display
+func formatLimit(inKbps, outKbps int) string {
+	return "in " + itoaOrDash(inKbps) + " / out " + itoaOrDash(outKbps) + " kbps"
+}
+
+// Integer as text, with "-" for unset (0) values
+func itoaOrDash(v int) string {
+	if v <= 0 {
+		return "-"
+	}
+	return strconv.Itoa(v)
+}
+
+// Build the table of tracked rules; call the returned func to refresh it.
+// Selecting a row edits that rule's note and offers a boost, a pause or
+// resume, a clear and a connectivity test; boost is called with the chosen
+// duration, or 0 to end a running boost, and pause with false to resume.
+func newRulesTable(window fyne.Window, remove func(LimitRule), boost func(LimitRule, time.Duration), pause func(LimitRule, bool), test func(LimitRule)) (*widget.Table, func()) {
+	var rows []LimitRule
+
+	table := widget.NewTableWithHeaders(
+		func() (int, int) { return len(rows), len(rulesTableHeaders) },
+		func() fyne.CanvasObject {
+			dot := canvas.NewCircle(color.Transparent)
+			return container.NewStack(
+				container.NewCenter(container.NewGridWrap(fyne.NewSize(12, 12), dot)),
+				widget.NewLabel(""),
+			)
+		},
+		func(id widget.TableCellID, obj fyne.CanvasObject) {
+			stack := obj.(*fyne.Container)
+			dotHolder := stack.Objects[0].(*fyne.Container)
+			label := stack.Objects[1].(*widget.Label)
+			r := rows[id.Row]
+
+			if id.Col == 0 {
+				dot := dotHolder.Objects[0].(*fyne.Container).Objects[0].(*canvas.Circle)
+				dot.FillColor = statusColors[r.status()]
+				dot.Refresh()
+				dotHolder.Show()
+				label.Hide()
+				return
+			}
+			dotHolder.Hide()
+			label.SetText(rulesTableCell(r, id.Col))
+			label.Show()
+		},
+	)
+	table.CreateHeader = func() fyne.CanvasObject { return widget.NewLabel("") }
+	table.UpdateHeader = func(id widget.TableCellID, obj fyne.CanvasObject) {
+		label := obj.(*widget.Label)
+		if id.Row < 0 && id.Col >= 0 {
+			label.SetText(rulesTableHeaders[id.Col])
+		} else {
+			label.SetText("")
+		}
+	}
+	table.ShowHeaderColumn = false
+	table.SetColumnWidth(0, 28)
+	table.SetColumnWidth(1, 260)
+	table.SetColumnWidth(2, 150)
+	table.SetColumnWidth(3, 160)
+	table.SetColumnWidth(4, 130)
+	table.SetColumnWidth(5, 130)
+	table.SetColumnWidth(6, 220)
+
+	table.OnSelected = func(id widget.TableCellID) {
+		table.UnselectAll()
+		if id.Row < 0 || id.Row >= len(rows) {
+			return
+		}
+		r := rows[id.Row]
+		noteEntry := widget.NewEntry()
+		noteEntry.SetText(r.Note)
+		noteEntry.SetPlaceHolder("e.g. throttle dev server upload")
+		items := []*widget.FormItem{widget.NewFormItem("Note", noteEntry)}
+
+		// Boosting or pausing lifts an applied limit; pending/failed rules have nothing to lift
+		var form dialog.Dialog
+		switch r.status() {
+		case statusActive:
+			var names []string
+			for _, d := range boostDurations {
+				names = append(names, strconv.Itoa(int(d/time.Minute))+" min")
+			}
+			boostSelect := widget.NewSelect(names, nil)
+			boostSelect.SetSelected(names[1])
+			boostButton := widget.NewButton("Boost", func() {
+				form.Hide()
+				boost(r, boostDurations[boostSelect.SelectedIndex()])
+			})
+			item := widget.NewFormItem("Remove limit for", container.NewHBox(boostSelect, boostButton))
+			item.HintText = "The original limit is restored automatically"
+			items = append(items, item)
+			pauseButton := widget.NewButton("Pause", func() {
+				form.Hide()
+				pause(r, true)
+			})
+			pauseItem := widget.NewFormItem("Pause rule", pauseButton)
+			pauseItem.HintText = "Removes its policies until you resume it"
+			items = append(items, pauseItem)
+		case statusPaused:
+			resumeButton := widget.NewButton("Resume", func() {
+				form.Hide()
+				pause(r, false)
+			})
+			items = append(items, widget.NewFormItem("Paused", resumeButton))
+		case statusBoosted:
+			endButton := widget.NewButton("End Boost Now", func() {
+				form.Hide()
+				boost(r, 0)
+			})
+			items = append(items, widget.NewFormItem("Boosted "+boostRemaining(r, time.Now()), endButton))
+		}
+
+		clearButton := widget.NewButton("Clear This Rule", func() {
+			form.Hide()
+			remove(r)
+		})
+		clearItem := widget.NewFormItem("Policies", clearButton)
+		clearItem.HintText = "Removes only this rule's QoS policies and firewall rules"
+		items = append(items, clearItem)
+
+		testButton := widget.NewButton("Test Connectivity", func() {
+			form.Hide()
+			test(r)
+		})
+		testItem := widget.NewFormItem("Check", testButton)
+		testItem.HintText = fmt.Sprintf("Lists its live policies, probes the internet and measures the app for %v", connectivitySampleTime)
+		items = append(items, testItem)
+
+		form = dialog.NewForm("Rule: "+r.describe(), "Save Note", "Cancel", items,
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				if err := tracked.setNote(r.key(), strings.TrimSpace(noteEntry.Text)); err != nil {
+					dialog.ShowError(err, window)
+				}
+			}, window)
+		form.Show()
+	}
+
+	refresh := func() {
+		rows = tracked.list()
+		table.Refresh()
+	}
+	refresh()
+	return table, refresh
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	selfTestDonePrefKey = "selfTestDone"
+
+	// Dummy policy and rule created by the self-test. They target an executable
+	// that doesn't exist, and the firewall rule is created disabled.
+	selfTestPolicyName = "GoNetLimitSelfTest"
+	selfTestRuleName   = "GoNetBlockSelfTest"
+	selfTestExe        = `C:\netlimiter-selftest.exe`
+)
+
+// Outcome of the self-test; each field holds a failure reason, "" on success
+type selfTestResult struct {
+	Elevated bool
+	Qos      string
+	Firewall string
+	Cleanup  string
+	Services string
+}
+
+// Failure reasons, empty when the environment is fully functional
+func (r selfTestResult) failures() []string {
+	var failures []string
+	if !r.Elevated {
+		failures = append(failures, "Not running as Administrator")
+	}
+	for _, f := range []struct{ label, reason string }{
+		{"Services", r.Services},
+		{"QoS policy", r.Qos},
+		{"Firewall rule", r.Firewall},
+		{"Cleanup", r.Cleanup},
+	} {
+		if f.reason != "" {
+			failures = append(failures, f.label+": "+f.reason)
+		}
+	}
+	return failures
+}
+
+// Create, verify and remove a dummy QoS policy and firewall rule
+func runSelfTest() (selfTestResult, error) {
+	script := fmt.Sprintf(`
+$r = [ordered]@{ Elevated = $false; Qos = ""; Firewall = ""; Cleanup = "" }
+$id = [Security.Principal.WindowsIdentity]::GetCurrent()
+$r.Elevated = (New-Object Security.Principal.WindowsPrincipal $id).IsInRole([Security.Principal.WindowsBuiltInRole]::Administrator)
+
+try {
+  New-NetQosPolicy -Name "%[1]s" -AppPathNameMatchCondition "%[3]s" -ThrottleRateActionBitsPerSecond 1000000 -PolicyStore ActiveStore -ErrorAction Stop | Out-Null
+  if (-not (Get-NetQosPolicy -Name "%[1]s" -PolicyStore ActiveStore -ErrorAction SilentlyContinue)) { $r.Qos = "policy was not found after creating it" }
+} catch { $r.Qos = $_.Exception.Message }
+
+try {
+  New-NetFirewallRule -DisplayName "%[2]s" -Program "%[3]s" -Direction Outbound -Action Block -Enabled False -ErrorAction Stop | Out-Null
+  if (-not (Get-NetFirewallRule -DisplayName "%[2]s" -ErrorAction SilentlyContinue)) { $r.Firewall = "rule was not found after creating it" }
+} catch { $r.Firewall = $_.Exception.Message }
+
+Remove-NetQosPolicy -Name "%[1]s" -PolicyStore ActiveStore -Confirm:$false -ErrorAction SilentlyContinue
+Remove-NetFirewallRule -DisplayName "%[2]s" -ErrorAction SilentlyContinue
+if ((Get-NetQosPolicy -Name "%[1]s" -PolicyStore ActiveStore -ErrorAction SilentlyContinue) -or (Get-NetFirewallRule -DisplayName "%[2]s" -ErrorAction SilentlyContinue)) {
+  $r.Cleanup = "the test policy or rule could not be removed"
+}
+ConvertTo-Json -InputObject ([pscustomobject]$r) -Compress
+`, selfTestPolicyName, selfTestRuleName, selfTestExe)
+
+	var result selfTestResult
+	if err := queryPowerShellJSON(script, &result); err != nil {
+		return result, fmt.Errorf("self-test error: %w", err)
+	}
+	return result, nil
+}
+
+// Run the backend self-test plus the service check and show a green/red result
+func showSelfTest(window fyne.Window, log func(string)) {
+	go func() {
+		log("----------------------------------------------------")
+		log("Running self-test...")
+		result, err := backend.SelfTest()
+		if err == nil {
+			if report, svcErr := backend.CheckServices(); svcErr == nil {
+				result.Services = serviceProblems(report)
+			}
+		}
+
+		failures := result.failures()
+		if err != nil {
+			failures = []string{err.Error()}
+		}
+		for _, f := range failures {
+			log("Self-test: " + f)
+		}
+		if len(failures) == 0 {
+			log("Self-test passed: QoS policies and firewall rules can be created and removed")
+		}
+
+		fyne.Do(func() {
+			dotColor, summary := statusColors[statusActive], "Ready: QoS policies and firewall rules can be created and removed."
+			if len(failures) > 0 {
+				dotColor, summary = statusColors[statusFailed], "Not ready: limits and blocks will fail until this is fixed."
+			}
+			dot := canvas.NewCircle(dotColor)
+			rows := container.NewVBox(container.NewHBox(
+				container.NewGridWrap(fyne.NewSize(16, 16), dot),
+				widget.NewLabel(summary),
+			))
+			for _, f := range failures {
+				rows.Add(widget.NewLabel("- " + f))
+			}
+			d := dialog.NewCustom("Self-test", "Close", rows, window)
+			d.Show()
+		})
+	}()
+}
+
+// Stopped services and a missing packet scheduler, or "" when all is well
+func serviceProblems(report serviceReport) string {
+	var problems []string
+	for _, s := range report.stopped(serviceBFE, serviceFirewall) {
+		problems = append(problems, fmt.Sprintf("%s (%s) is %s", s.DisplayName, s.Name, strings.ToLower(s.Status)))
+	}
+	if !report.PacketScheduler {
+		problems = append(problems, "QoS Packet Scheduler is not enabled on any network adapter")
+	}
+	return strings.Join(problems, "; ")
+}
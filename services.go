@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"netlimiter/netlimiter"
+)
+
+// Windows services the QoS and firewall cmdlets depend on
+const (
+	serviceBFE      = "BFE"    // Base Filtering Engine
+	serviceFirewall = "MpsSvc" // Windows Defender Firewall
+)
+
+// State of one Windows service as reported by Get-Service
+type serviceState struct {
+	Name        string
+	DisplayName string
+	Status      string
+}
+
+// Result of checking the services and drivers needed for limiting
+type serviceReport struct {
+	Services        []serviceState
+	PacketScheduler bool // QoS Packet Scheduler (ms_pacer) bound to an adapter
+	QosCmdlets      bool // New-NetQosPolicy exists; filled in from qosCmdletsAvailable
+}
+
+// Look up a service in the report
+func (r serviceReport) service(name string) (serviceState, bool) {
+	for _, s := range r.Services {
+		if strings.EqualFold(s.Name, name) {
+			return s, true
+		}
+	}
+	return serviceState{}, false
+}
+
+// Services from names that are missing or not running
+func (r serviceReport) stopped(names ...string) []serviceState {
+	var stopped []serviceState
+	for _, name := range names {
+		s, ok := r.service(name)
+		if !ok {
+			stopped = append(stopped, serviceState{Name: name, DisplayName: name, Status: "Missing"})
+		} else if !strings.EqualFold(s.Status, "Running") {
+			stopped = append(stopped, s)
+		}
+	}
+	return stopped
+}
+
+// Explain why QoS limiting is unavailable, or "" when it's ready
+func (r serviceReport) qosProblem() string {
+	var problems []string
+	for _, s := range r.stopped(serviceBFE) {
+		problems = append(problems, fmt.Sprintf("%s (%s) is %s", s.DisplayName, s.Name, strings.ToLower(s.Status)))
+	}
+	if !r.PacketScheduler {
+		problems = append(problems, "QoS Packet Scheduler is not enabled on any network adapter")
+	}
+	if !r.QosCmdlets {
+		problems = append(problems, "New-NetQosPolicy is not available on this Windows edition (Home editions lack the NetQos module)")
+	}
+	return strings.Join(problems, "; ")
+}
+
+// Explain why firewall blocking is unavailable, or "" when it's ready
+func (r serviceReport) firewallProblem() string {
+	var problems []string
+	for _, s := range r.stopped(serviceBFE, serviceFirewall) {
+		problems = append(problems, fmt.Sprintf("%s (%s) is %s", s.DisplayName, s.Name, strings.ToLower(s.Status)))
+	}
+	return strings.Join(problems, "; ")
+}
+
+// Query service states and the packet scheduler binding in one PowerShell call
+func checkServices() (serviceReport, error) {
+	script := fmt.Sprintf(`
+$svc = @(Get-Service -Name %s, %s -ErrorAction SilentlyContinue | Select-Object Name, DisplayName, @{n='Status';e={"$($_.Status)"}})
+$pacer = @(Get-NetAdapterBinding -ComponentID ms_pacer -ErrorAction SilentlyContinue | Where-Object { $_.Enabled }).Count -gt 0
+ConvertTo-Json -InputObject @{ Services = $svc; PacketScheduler = $pacer } -Compress -Depth 3
+`, serviceBFE, serviceFirewall)
+
+	var report serviceReport
+	if err := queryPowerShellJSON(script, &report); err != nil {
+		return report, fmt.Errorf("service check error: %w", err)
+	}
+	report.QosCmdlets = qosCmdletsAvailable()
+	return report, nil
+}
+
+// Whether the NetQos cmdlets exist; detected once per run. Windows Home
+// editions lack them, which leaves blocking as the only thing that works.
+var qosCmdlets struct {
+	once      sync.Once
+	available bool
+}
+
+func qosCmdletsAvailable() bool {
+	qosCmdlets.once.Do(func() {
+		out, err := runPowerShell(`if (Get-Command New-NetQosPolicy -ErrorAction SilentlyContinue) { "yes" } else { "no" }`)
+		// If detection itself fails, assume the cmdlets and let the real call report errors
+		qosCmdlets.available = err != nil || strings.TrimSpace(string(out)) != "no"
+	})
+	return qosCmdlets.available
+}
+
+// Error for a limit or priority on a system without the NetQos cmdlets
+func errNoQosCmdlets() error {
+	return fmt.Errorf("New-NetQosPolicy is not available on this Windows edition, so only blocking works: %w", errCmdletMissing)
+}
+
+// Start a stopped service (requires elevation)
+func startWindowsService(name string) (string, error) {
+	log := "Starting service: " + name + "\n"
+
+	script := fmt.Sprintf(`Start-Service -Name %s -ErrorAction Stop`, netlimiter.QuotePowerShell(name))
+	out, err := runPowerShell(script)
+	if len(out) > 0 {
+		log += "Service output:\n" + string(out) + "\n"
+	}
+	if err != nil {
+		return log, fmt.Errorf("start service error: %w", err)
+	}
+
+	log += "StartService: success\n"
+	return log, nil
+}
+
+// Offer to start the given stopped services; returns true if all started
+func offerServiceStart(stopped []serviceState, log func(string), confirm func(title, message, confirmText string) bool) bool {
+	var names []string
+	for _, s := range stopped {
+		if s.Status == "Missing" {
+			return false
+		}
+		names = append(names, s.DisplayName)
+	}
+	msg := "These services are required but not running:\n\n" + strings.Join(names, "\n") +
+		"\n\nStart them now? This needs Administrator rights."
+	if !confirm("Required services stopped", msg, "Start") {
+		return false
+	}
+
+	ok := true
+	for _, s := range stopped {
+		startLog, err := backend.StartService(s.Name)
+		log(startLog)
+		if err != nil {
+			log("StartService error: " + err.Error())
+			ok = false
+		}
+	}
+	return ok
+}
+
+// Make sure the services a block (firewall) or limit (QoS) needs are running.
+// Returns false, after explaining why, when the operation can't work.
+// If the check itself fails the operation goes ahead and reports its own errors.
+func ensureServices(block bool, log func(string), confirm func(title, message, confirmText string) bool) bool {
+	report, err := backend.CheckServices()
+	if err != nil {
+		log("Could not check required services: " + err.Error())
+		return true
+	}
+
+	problem, what, needed := report.qosProblem(), "QoS limiting", []string{serviceBFE}
+	if block {
+		problem, what, needed = report.firewallProblem(), "Firewall blocking", []string{serviceBFE, serviceFirewall}
+	}
+	if problem == "" {
+		return true
+	}
+	log(what + " is unavailable: " + problem)
+
+	if stopped := report.stopped(needed...); len(stopped) > 0 && offerServiceStart(stopped, log, confirm) {
+		if report, err = backend.CheckServices(); err == nil {
+			problem = report.qosProblem()
+			if block {
+				problem = report.firewallProblem()
+			}
+		}
+	}
+	if problem != "" {
+		switch {
+		case !block && !report.QosCmdlets:
+			log("This Windows edition can only block; set both limits to 0 to block the app instead")
+			return false
+		case !block && !report.PacketScheduler:
+			log("Enable \"QoS Packet Scheduler\" in the network adapter's properties to use limits")
+		}
+		log(what + " stays unavailable until the services above are enabled")
+		return false
+	}
+	return true
+}
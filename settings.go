@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"netlimiter/netlimiter"
+)
+
+// Show the settings dialog; onSaved runs after valid settings are stored
+func showSettings(window fyne.Window, prefs fyne.Preferences, appendLog func(string), onSaved func()) {
+	presetsEntry := widget.NewMultiLineEntry()
+	presetsEntry.SetText(formatPresets(loadPresets(prefs)))
+	presetsEntry.SetMinRowsVisible(5)
+
+	reapplyCheck := widget.NewCheck("Reapply saved rules at logon", nil)
+	reapplyCheck.SetChecked(prefs.Bool(reapplyAtLogonPrefKey))
+
+	verifyClearCheck := widget.NewCheck("Check connectivity after Clear All", nil)
+	verifyClearCheck.SetChecked(prefs.Bool(verifyAfterClearPrefKey))
+
+	closeToTrayCheck := widget.NewCheck("Closing the window keeps running in the tray", nil)
+	closeToTrayCheck.SetChecked(prefs.Bool(closeToTrayPrefKey))
+
+	persistCheck := widget.NewCheck("Persist across reboots", nil)
+	persistCheck.SetChecked(prefs.Bool(persistPoliciesPrefKey))
+
+	resetCheck := widget.NewCheck("Clear all rules before applying a new one", nil)
+	resetCheck.SetChecked(prefs.Bool(resetBeforeApplyPrefKey))
+
+	quickLimitEntry := widget.NewEntry()
+	quickLimitEntry.SetText(strconv.Itoa(quickLimitKbps(prefs)))
+
+	busyAlertCheck := widget.NewCheck("Notify when an unlimited process is busy", nil)
+	busyAlertCheck.SetChecked(prefs.Bool(busyAlertPrefKey))
+	busyThresholdEntry := widget.NewEntry()
+	busyThresholdEntry.SetText(strconv.Itoa(busyThresholdKbps(prefs)))
+
+	hookURLEntry := widget.NewEntry()
+	hookURLEntry.SetText(prefs.String(hookURLPrefKey))
+	hookURLEntry.SetPlaceHolder("Empty = off, e.g. https://example.com/netlimiter")
+	hookCommandEntry := widget.NewEntry()
+	hookCommandEntry.SetText(prefs.String(hookCommandPrefKey))
+	hookCommandEntry.SetPlaceHolder(`Empty = off, e.g. C:\scripts\on-rule.cmd`)
+	hookEventsGroup := widget.NewCheckGroup(hookEventNames, nil)
+	hookEventsGroup.SetSelected(parseHookEvents(prefs.String(hookEventsPrefKey)))
+
+	statusPathEntry := widget.NewEntry()
+	statusPathEntry.SetText(prefs.String(statusFilePrefKey))
+	statusPathEntry.SetPlaceHolder(`Empty = off, e.g. C:\Users\me\netlimiter-status.json`)
+	statusIntervalEntry := widget.NewEntry()
+	statusIntervalEntry.SetText(strconv.Itoa(int(statusInterval(prefs) / time.Second)))
+
+	psTimeoutEntry := widget.NewEntry()
+	psTimeoutEntry.SetText(strconv.Itoa(int(psTimeout(prefs) / time.Second)))
+	psAttemptsEntry := widget.NewEntry()
+	psAttemptsEntry.SetText(strconv.Itoa(psAttempts(prefs)))
+
+	presetsItem := widget.NewFormItem("Presets", presetsEntry)
+	presetsItem.HintText = "One per line: Name = IN/OUT (kbps)"
+	startupItem := widget.NewFormItem("Startup", reapplyCheck)
+	startupItem.HintText = "Creates a Scheduled Task running this app with -reapply"
+	windowItem := widget.NewFormItem("Window", closeToTrayCheck)
+	windowItem.HintText = "Quit from the tray menu"
+	applyItem := widget.NewFormItem("Apply", resetCheck)
+	persistItem := widget.NewFormItem("QoS policies", persistCheck)
+	persistItem.HintText = "Uses the persistent policy store for new limits; firewall blocks always persist"
+	applyItem.HintText = "Off: only the target's previous rule is replaced"
+	clearItem := widget.NewFormItem("Clear", verifyClearCheck)
+	clearItem.HintText = "Looks for leftover rules and probes internet reachability"
+
+	logDirEntry := widget.NewEntry()
+	logDirEntry.SetText(prefs.String(logDirPrefKey))
+	logDirEntry.SetPlaceHolder(`Empty = logs folder under %APPDATA%\net-limiter`)
+
+	psTimeoutItem := widget.NewFormItem("PowerShell timeout (s)", psTimeoutEntry)
+	psTimeoutItem.HintText = "A run taking longer is stopped and reported as failed"
+	psAttemptsItem := widget.NewFormItem("PowerShell attempts", psAttemptsEntry)
+	psAttemptsItem.HintText = "Tries per change on transient WMI errors, with growing pauses; 1 = no retry"
+
+	quickLimitItem := widget.NewFormItem("Quick-limit (kbps)", quickLimitEntry)
+	quickLimitItem.HintText = "IN/OUT rate of the tray's \"Limit foreground app\""
+
+	busyAlertItem := widget.NewFormItem("Activity alerts", busyAlertCheck)
+	busyAlertItem.HintText = "Offers a tray shortcut to throttle the process"
+	busyThresholdItem := widget.NewFormItem("Alert above (kbps)", busyThresholdEntry)
+	busyThresholdItem.HintText = fmt.Sprintf("Sustained for %v", busySampleInterval*busySustainSamples)
+
+	statusPathItem := widget.NewFormItem("Status file", statusPathEntry)
+	statusPathItem.HintText = "JSON summary of the rules for external monitors"
+	statusIntervalItem := widget.NewFormItem("Status interval (s)", statusIntervalEntry)
+	logDirItem := widget.NewFormItem("Log folder", logDirEntry)
+	logDirItem.HintText = fmt.Sprintf("Every log line and script, kept in up to %d files of %d MB", logFilesKept, logFileMaxSize>>20)
+
+	hookURLItem := widget.NewFormItem("Webhook URL", hookURLEntry)
+	hookURLItem.HintText = "Receives each event as a JSON POST"
+	hookCommandItem := widget.NewFormItem("Hook command", hookCommandEntry)
+	hookCommandItem.HintText = "Gets the JSON on stdin and NETLIMITER_EVENT etc. in its environment"
+	hookEventsItem := widget.NewFormItem("Hook events", hookEventsGroup)
+
+	items := []*widget.FormItem{presetsItem, startupItem, windowItem, applyItem, persistItem, clearItem, psTimeoutItem, psAttemptsItem, quickLimitItem, busyAlertItem, busyThresholdItem, statusPathItem, statusIntervalItem, logDirItem, hookURLItem, hookCommandItem, hookEventsItem}
+
+	d := dialog.NewForm("Settings", "Save", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		presets, err := parsePresets(presetsEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		quickKbps, err := strconv.Atoi(strings.TrimSpace(quickLimitEntry.Text))
+		if err != nil || quickKbps <= 0 || quickKbps > netlimiter.MaxLimitKbps {
+			dialog.ShowError(fmt.Errorf("quick-limit rate must be a whole number from 1 to %d kbps", netlimiter.MaxLimitKbps), window)
+			return
+		}
+		busyKbps, err := strconv.Atoi(strings.TrimSpace(busyThresholdEntry.Text))
+		if err != nil || busyKbps <= 0 {
+			dialog.ShowError(errors.New("alert threshold must be a positive integer"), window)
+			return
+		}
+		statusSecs, err := strconv.Atoi(strings.TrimSpace(statusIntervalEntry.Text))
+		if err != nil || statusSecs < int(minStatusInterval/time.Second) {
+			dialog.ShowError(errors.New("status interval must be at least 1 second"), window)
+			return
+		}
+		timeoutSecs, err := strconv.Atoi(strings.TrimSpace(psTimeoutEntry.Text))
+		if err != nil || timeoutSecs < int(minPSTimeout/time.Second) {
+			dialog.ShowError(fmt.Errorf("PowerShell timeout must be at least %d seconds", int(minPSTimeout/time.Second)), window)
+			return
+		}
+		attempts, err := strconv.Atoi(strings.TrimSpace(psAttemptsEntry.Text))
+		if err != nil || attempts < 1 || attempts > maxPSAttempts {
+			dialog.ShowError(fmt.Errorf("PowerShell attempts must be from 1 to %d", maxPSAttempts), window)
+			return
+		}
+		if len(hookEventsGroup.Selected) == 0 {
+			dialog.ShowError(errors.New("select at least one hook event"), window)
+			return
+		}
+		savePresets(prefs, presets)
+		prefs.SetString(hookURLPrefKey, strings.TrimSpace(hookURLEntry.Text))
+		prefs.SetString(hookCommandPrefKey, strings.TrimSpace(hookCommandEntry.Text))
+		prefs.SetString(hookEventsPrefKey, strings.Join(hookEventsGroup.Selected, ","))
+		hooks.configure(prefs)
+		prefs.SetString(statusFilePrefKey, strings.TrimSpace(statusPathEntry.Text))
+		prefs.SetInt(statusIntervalPrefKey, statusSecs)
+		prefs.SetString(logDirPrefKey, strings.TrimSpace(logDirEntry.Text))
+		if err := configureFileLog(prefs); err != nil {
+			appendLog("Log file error: " + err.Error())
+		}
+		prefs.SetInt(psTimeoutPrefKey, timeoutSecs)
+		prefs.SetInt(psAttemptsPrefKey, attempts)
+		psRuns.configure(prefs)
+		prefs.SetInt(quickLimitPrefKey, quickKbps)
+		prefs.SetBool(busyAlertPrefKey, busyAlertCheck.Checked)
+		prefs.SetInt(busyThresholdPrefKey, busyKbps)
+		prefs.SetBool(closeToTrayPrefKey, closeToTrayCheck.Checked)
+		prefs.SetBool(resetBeforeApplyPrefKey, resetCheck.Checked)
+		prefs.SetBool(persistPoliciesPrefKey, persistCheck.Checked)
+		configurePolicyStore(prefs)
+		prefs.SetBool(verifyAfterClearPrefKey, verifyClearCheck.Checked)
+
+		if reapplyCheck.Checked != prefs.Bool(reapplyAtLogonPrefKey) {
+			enable := reapplyCheck.Checked
+			go func() {
+				var taskLog string
+				var err error
+				if enable {
+					taskLog, err = registerReapplyTask()
+				} else {
+					taskLog, err = unregisterReapplyTask()
+				}
+				appendLog(taskLog)
+				if err != nil {
+					appendLog("Logon task error: " + err.Error())
+					return
+				}
+				prefs.SetBool(reapplyAtLogonPrefKey, enable)
+			}()
+		}
+		onSaved()
+	}, window)
+	d.Resize(fyne.NewSize(520, 520))
+	d.Show()
+}
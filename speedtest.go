@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	speedTestDownURLPrefKey = "speedTestDownURL"
+	speedTestUpURLPrefKey   = "speedTestUpURL"
+	speedTestSizePrefKey    = "speedTestSizeMB"
+
+	// Measured link capacity, used as 100% by percentage limits
+	linkDownKbpsPrefKey     = "linkDownKbps"
+	linkUpKbpsPrefKey       = "linkUpKbps"
+	linkCalibratedAtPrefKey = "linkCalibratedAt"
+
+	// {bytes} is replaced with the test size
+	defaultSpeedTestDownURL = "https://speed.cloudflare.com/__down?bytes={bytes}"
+	defaultSpeedTestUpURL   = "https://speed.cloudflare.com/__up"
+	defaultSpeedTestSizeMB  = 10
+
+	speedTestTimeout = 60 * time.Second
+)
+
+// Result of one calibration run
+type linkCapacity struct {
+	DownKbps int
+	UpKbps   int
+	At       time.Time
+}
+
+// Last stored calibration; false if none has run
+func loadLinkCapacity(prefs fyne.Preferences) (linkCapacity, bool) {
+	at, err := time.Parse(time.RFC3339, prefs.String(linkCalibratedAtPrefKey))
+	if err != nil {
+		return linkCapacity{}, false
+	}
+	return linkCapacity{DownKbps: prefs.Int(linkDownKbpsPrefKey), UpKbps: prefs.Int(linkUpKbpsPrefKey), At: at}, true
+}
+
+func saveLinkCapacity(prefs fyne.Preferences, c linkCapacity) {
+	prefs.SetInt(linkDownKbpsPrefKey, c.DownKbps)
+	prefs.SetInt(linkUpKbpsPrefKey, c.UpKbps)
+	prefs.SetString(linkCalibratedAtPrefKey, c.At.Format(time.RFC3339))
+}
+
+func (c linkCapacity) describe() string {
+	return fmt.Sprintf("down %d / up %d kbps, measured %s", c.DownKbps, c.UpKbps, c.At.Local().Format("2006-01-02 15:04"))
+}
+
+// Throughput in kbps for n bytes moved in d
+func throughputKbps(n int64, d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(float64(n) * 8 / 1000 / d.Seconds())
+}
+
+// Download sizeBytes from downURL and upload as much to upURL, timing each.
+// Existing limits on this app would skew the figures; it is not normally limited.
+func runSpeedTest(ctx context.Context, downURL, upURL string, sizeBytes int64) (linkCapacity, error) {
+	ctx, cancel := context.WithTimeout(ctx, speedTestTimeout)
+	defer cancel()
+	client := &http.Client{}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.ReplaceAll(downURL, "{bytes}", strconv.FormatInt(sizeBytes, 10)), nil)
+	if err != nil {
+		return linkCapacity{}, fmt.Errorf("download URL: %w", err)
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return linkCapacity{}, fmt.Errorf("download: %w", err)
+	}
+	n, err := io.Copy(io.Discard, io.LimitReader(resp.Body, sizeBytes))
+	resp.Body.Close()
+	if err != nil {
+		return linkCapacity{}, fmt.Errorf("download: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return linkCapacity{}, fmt.Errorf("download: %s", resp.Status)
+	}
+	if n < sizeBytes/2 {
+		return linkCapacity{}, fmt.Errorf("download: endpoint sent only %d of %d bytes", n, sizeBytes)
+	}
+	down := throughputKbps(n, time.Since(start))
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, upURL, bytes.NewReader(make([]byte, sizeBytes)))
+	if err != nil {
+		return linkCapacity{}, fmt.Errorf("upload URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	start = time.Now()
+	resp, err = client.Do(req)
+	if err != nil {
+		return linkCapacity{}, fmt.Errorf("upload: %w", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return linkCapacity{}, fmt.Errorf("upload: %s", resp.Status)
+	}
+	up := throughputKbps(sizeBytes, time.Since(start))
+
+	return linkCapacity{DownKbps: down, UpKbps: up, At: time.Now()}, nil
+}
+
+// Advanced panel to measure the real link speed instead of the adapter's LinkSpeed
+func speedTestPanel(prefs fyne.Preferences, appendLog func(string)) fyne.CanvasObject {
+	downEntry := widget.NewEntry()
+	downEntry.SetText(prefs.StringWithFallback(speedTestDownURLPrefKey, defaultSpeedTestDownURL))
+	upEntry := widget.NewEntry()
+	upEntry.SetText(prefs.StringWithFallback(speedTestUpURLPrefKey, defaultSpeedTestUpURL))
+	sizeEntry := widget.NewEntry()
+	sizeEntry.SetText(strconv.Itoa(prefs.IntWithFallback(speedTestSizePrefKey, defaultSpeedTestSizeMB)))
+
+	// Adapter percentage limits are taken of; the first option means automatic
+	const autoAdapter = "Automatic (measured, else default route)"
+	adapterSelect := widget.NewSelect([]string{autoAdapter}, func(name string) {
+		if name == autoAdapter {
+			name = ""
+		}
+		prefs.SetString(percentAdapterPrefKey, name)
+	})
+	if name := prefs.String(percentAdapterPrefKey); name != "" {
+		adapterSelect.SetOptions([]string{autoAdapter, name})
+		adapterSelect.SetSelected(name)
+	} else {
+		adapterSelect.SetSelected(autoAdapter)
+	}
+	findAdapters := widget.NewButton("Find Adapters", func() {
+		go func() {
+			adapters, err := listNetAdapters()
+			if err != nil {
+				appendLog("Error: " + err.Error())
+				return
+			}
+			options := []string{autoAdapter}
+			for _, a := range adapters {
+				options = append(options, a.Name)
+				note := ""
+				if a.DefaultRoute {
+					note = " (default route)"
+				}
+				appendLog(fmt.Sprintf("Adapter %s: link speed %d kbps%s", a.Name, a.Speed/1000, note))
+			}
+			fyne.Do(func() { adapterSelect.SetOptions(options) })
+		}()
+	})
+
+	lastLabel := widget.NewLabel("Last calibration: never")
+	if c, ok := loadLinkCapacity(prefs); ok {
+		lastLabel.SetText("Last calibration: " + c.describe())
+	}
+
+	var run *widget.Button
+	run = widget.NewButton("Run Speed Test", func() {
+		sizeMB, err := strconv.Atoi(strings.TrimSpace(sizeEntry.Text))
+		if err != nil || sizeMB <= 0 {
+			appendLog("Error: test size must be a positive number of MB")
+			return
+		}
+		downURL, upURL := strings.TrimSpace(downEntry.Text), strings.TrimSpace(upEntry.Text)
+		prefs.SetString(speedTestDownURLPrefKey, downURL)
+		prefs.SetString(speedTestUpURLPrefKey, upURL)
+		prefs.SetInt(speedTestSizePrefKey, sizeMB)
+
+		run.Disable()
+		appendLog(fmt.Sprintf("Speed test: %d MB down from %s, up to %s", sizeMB, downURL, upURL))
+		go func() {
+			c, err := runSpeedTest(context.Background(), downURL, upURL, int64(sizeMB)*1000*1000)
+			fyne.Do(func() {
+				run.Enable()
+				if err != nil {
+					appendLog("Speed test error: " + err.Error())
+					return
+				}
+				saveLinkCapacity(prefs, c)
+				lastLabel.SetText("Last calibration: " + c.describe())
+				appendLog("Speed test: " + c.describe())
+			})
+		}()
+	})
+
+	return widget.NewForm(
+		widget.NewFormItem("Download URL", downEntry),
+		widget.NewFormItem("Upload URL", upEntry),
+		widget.NewFormItem("Size (MB)", sizeEntry),
+		widget.NewFormItem("", run),
+		widget.NewFormItem("", lastLabel),
+		widget.NewFormItem("Percentages of", container.NewBorder(nil, nil, nil, findAdapters, adapterSelect)),
+	)
+}
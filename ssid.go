@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	ssidProfilesPrefKey = "ssidProfiles"
+
+	// How often the connected Wi-Fi network is checked
+	ssidCheckInterval = 15 * time.Second
+)
+
+// "    SSID                   : HomeWifi" in netsh output (BSSID lines don't match)
+var ssidLinePattern = regexp.MustCompile(`(?m)^\s*SSID\s*:\s*(.+?)\s*$`)
+
+// Rule set applied automatically while connected to a Wi-Fi network
+type ssidProfile struct {
+	SSID  string      `json:"ssid"`
+	Rules []LimitRule `json:"rules"`
+}
+
+// Saved SSID profiles, sorted by SSID
+func loadSSIDProfiles(prefs fyne.Preferences) []ssidProfile {
+	var profiles []ssidProfile
+	if raw := prefs.String(ssidProfilesPrefKey); raw != "" {
+		if json.Unmarshal([]byte(raw), &profiles) != nil {
+			return nil
+		}
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].SSID < profiles[j].SSID })
+	return profiles
+}
+
+func saveSSIDProfiles(prefs fyne.Preferences, profiles []ssidProfile) {
+	data, _ := json.Marshal(profiles)
+	prefs.SetString(ssidProfilesPrefKey, string(data))
+}
+
+// Profile for an SSID; SSIDs are case-sensitive
+func findSSIDProfile(profiles []ssidProfile, ssid string) (ssidProfile, bool) {
+	for _, p := range profiles {
+		if p.SSID == ssid {
+			return p, true
+		}
+	}
+	return ssidProfile{}, false
+}
+
+// Store rules as the profile for ssid, replacing any existing one; no rules removes it
+func setSSIDProfile(prefs fyne.Preferences, ssid string, rules []LimitRule) {
+	var kept []ssidProfile
+	for _, p := range loadSSIDProfiles(prefs) {
+		if p.SSID != ssid {
+			kept = append(kept, p)
+		}
+	}
+	if len(rules) > 0 {
+		kept = append(kept, ssidProfile{SSID: ssid, Rules: rules})
+	}
+	saveSSIDProfiles(prefs, kept)
+}
+
+// SSID of the connected Wi-Fi network, "" when not on Wi-Fi
+func currentSSID() (string, error) {
+	out, err := runPowerShell("netsh wlan show interfaces")
+	if err != nil {
+		// No wireless adapter or WLAN service: not on Wi-Fi
+		if strings.Contains(string(out), "wlansvc") || strings.Contains(string(out), "no wireless interface") {
+			return "", nil
+		}
+		return "", fmt.Errorf("netsh error: %w", err)
+	}
+	m := ssidLinePattern.FindStringSubmatch(string(out))
+	if m == nil {
+		return "", nil
+	}
+	return m[1], nil
+}
+
+// Replace the applied rules with a profile's rule set, touching only the
+// rules that differ; identical rules stay in place
+func switchToSSIDProfile(p ssidProfile, log func(string)) {
+	d := diffRules(tracked.list(), p.Rules)
+	log(d.format())
+	applyRuleDiff(d, map[string]any{"trigger": "ssid", "ssid": p.SSID}, log)
+}
+
+// Poll the connected SSID and switch rule sets when it changes to one with a profile.
+// Networks without a profile leave the current rules alone.
+func watchSSID(prefs fyne.Preferences, log func(string)) {
+	last := ""
+	known := false
+	ticker := time.NewTicker(ssidCheckInterval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		profiles := loadSSIDProfiles(prefs)
+		if len(profiles) == 0 {
+			known = false
+			continue
+		}
+		ssid, err := currentSSID()
+		if err != nil || (known && ssid == last) {
+			continue
+		}
+		wasKnown := known
+		last, known = ssid, true
+
+		// At startup the saved rules already reflect the current network
+		if !wasKnown {
+			continue
+		}
+		p, ok := findSSIDProfile(profiles, ssid)
+		if !ok {
+			continue
+		}
+		log("----------------------------------------------------")
+		log(fmt.Sprintf("Wi-Fi changed to %q, switching to its rule set (%d rule(s))", ssid, len(p.Rules)))
+		switchToSSIDProfile(p, log)
+	}
+}
+
+// Dialog to save the current rules as the rule set of a Wi-Fi network
+func showSSIDProfiles(window fyne.Window, prefs fyne.Preferences, appendLog func(string)) {
+	ssidEntry := widget.NewSelectEntry(nil)
+	ssidEntry.SetPlaceHolder("Wi-Fi network name (SSID)")
+	summary := widget.NewLabel("")
+
+	refresh := func() {
+		profiles := loadSSIDProfiles(prefs)
+		names := make([]string, len(profiles))
+		var b strings.Builder
+		for i, p := range profiles {
+			names[i] = p.SSID
+			fmt.Fprintf(&b, "%s:\n", p.SSID)
+			for _, r := range p.Rules {
+				fmt.Fprintf(&b, "  - %s\n", r.describe())
+			}
+		}
+		if len(profiles) == 0 {
+			b.WriteString("No Wi-Fi rule sets saved.")
+		}
+		ssidEntry.SetOptions(names)
+		summary.SetText(b.String())
+	}
+	refresh()
+
+	saveButton := widget.NewButton("Save Current Rules for This SSID", func() {
+		ssid := strings.TrimSpace(ssidEntry.Text)
+		rules := tracked.list()
+		if ssid == "" || len(rules) == 0 {
+			dialog.ShowInformation("Wi-Fi rule sets", "Enter an SSID and apply at least one rule first.", window)
+			return
+		}
+		setSSIDProfile(prefs, ssid, rules)
+		appendLog(fmt.Sprintf("Saved %d rule(s) for Wi-Fi %q", len(rules), ssid))
+		refresh()
+	})
+	applyButton := widget.NewButton("Apply Now", func() {
+		p, ok := findSSIDProfile(loadSSIDProfiles(prefs), strings.TrimSpace(ssidEntry.Text))
+		if !ok {
+			return
+		}
+		d := diffRules(tracked.list(), p.Rules)
+		if d.empty() {
+			dialog.ShowInformation("Wi-Fi rule sets", d.format(), window)
+			return
+		}
+		dialog.ShowConfirm("Switch to "+p.SSID+"'s rule set?", d.format(), func(ok bool) {
+			if !ok {
+				return
+			}
+			go func() {
+				appendLog("----------------------------------------------------")
+				appendLog(fmt.Sprintf("Switching to the rule set of Wi-Fi %q:", p.SSID))
+				applyRuleDiff(d, map[string]any{"trigger": "ssid", "ssid": p.SSID}, appendLog)
+			}()
+		}, window)
+	})
+	deleteButton := widget.NewButton("Delete", func() {
+		ssid := strings.TrimSpace(ssidEntry.Text)
+		if _, ok := findSSIDProfile(loadSSIDProfiles(prefs), ssid); !ok {
+			return
+		}
+		setSSIDProfile(prefs, ssid, nil)
+		appendLog(fmt.Sprintf("Deleted rule set for Wi-Fi %q", ssid))
+		refresh()
+	})
+
+	content := container.NewBorder(
+		container.NewVBox(ssidEntry, container.NewHBox(saveButton, applyButton, deleteButton)),
+		nil, nil, nil,
+		container.NewVScroll(summary),
+	)
+	d := dialog.NewCustom("Wi-Fi rule sets", "Close", content, window)
+	d.Resize(fyne.NewSize(520, 400))
+	d.Show()
+
+	// Prefill with the connected network
+	go func() {
+		ssid, err := currentSSID()
+		if err != nil || ssid == "" {
+			return
+		}
+		fyne.Do(func() {
+			if ssidEntry.Text == "" {
+				ssidEntry.SetText(ssid)
+			}
+		})
+	}()
+}
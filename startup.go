@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"netlimiter/netlimiter"
+)
+
+// Scheduled Task that runs "-reapply" at logon
+const (
+	reapplyTaskName       = "NetLimiterReapply"
+	reapplyAtLogonPrefKey = "reapplyAtLogon"
+)
+
+// Register a logon Scheduled Task that relaunches this executable with -reapply.
+// It runs with highest privileges so the QoS/firewall cmdlets work without a UAC prompt.
+func registerReapplyTask() (string, error) {
+	log := "Registering logon task: " + reapplyTaskName + "\n"
+
+	exe, err := os.Executable()
+	if err != nil {
+		return log, fmt.Errorf("could not find own executable: %w", err)
+	}
+
+	script := fmt.Sprintf(`
+$action    = New-ScheduledTaskAction -Execute %s -Argument "-reapply"
+$trigger   = New-ScheduledTaskTrigger -AtLogOn -User "$env:USERDOMAIN\$env:USERNAME"
+$principal = New-ScheduledTaskPrincipal -UserId "$env:USERDOMAIN\$env:USERNAME" -LogonType Interactive -RunLevel Highest
+Register-ScheduledTask -TaskName "%s" -Action $action -Trigger $trigger -Principal $principal -Force | Out-Null
+`,
+		netlimiter.QuotePowerShell(exe),
+		reapplyTaskName,
+	)
+
+	out, err := runPowerShell(script)
+	if len(out) > 0 {
+		log += "Task output:\n" + string(out) + "\n"
+	}
+	if err != nil {
+		return log, fmt.Errorf("scheduled task error: %w", err)
+	}
+
+	log += "RegisterTask: success\n"
+	return log, nil
+}
+
+// Remove the logon task if it exists
+func unregisterReapplyTask() (string, error) {
+	log := "Removing logon task: " + reapplyTaskName + "\n"
+
+	script := fmt.Sprintf(`
+Unregister-ScheduledTask -TaskName "%s" -Confirm:$false -ErrorAction SilentlyContinue
+`, reapplyTaskName)
+
+	out, err := runPowerShell(script)
+	if len(out) > 0 {
+		log += "Task output:\n" + string(out) + "\n"
+	}
+	if err != nil {
+		return log, fmt.Errorf("scheduled task error: %w", err)
+	}
+
+	log += "UnregisterTask: success\n"
+	return log, nil
+}
+
+// Saved rules that should be in effect: active ones, and failed ones worth
+// another try. Paused and boosted rules stay as they are, and pending
+// metered-only rules are left to the metered watcher.
+func reapplicableRules(rules []LimitRule) []LimitRule {
+	var out []LimitRule
+	for _, r := range rules {
+		if s := r.status(); s == statusActive || s == statusFailed {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Headless "-reapply" mode: recreate the saved rules, print the log and
+// return the process exit code
+func runReapply() int {
+	if err := tracked.load(); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load tracked rules:", err)
+		return 1
+	}
+	return reapplyHeadless(reapplicableRules(tracked.list()))
+}
+
+// Recreate rules for -reapply. Each rule's own policies are cleared first,
+// so anything else (other rules, allowlist mode) is left as it is.
+func reapplyHeadless(rules []LimitRule) int {
+	if len(rules) == 0 {
+		fmt.Println("No saved rules to reapply")
+		return 0
+	}
+
+	code := 0
+	for _, r := range rules {
+		fmt.Println("Reapplying", r.describe())
+		if clearLog, err := clearRule(r).result(); err != nil {
+			fmt.Print(clearLog)
+			fmt.Fprintln(os.Stderr, "Clear error:", err)
+		}
+		// No GUI preferences here; each rule goes back to the store it was in
+		persistPolicies.Store(r.qosStore() == netlimiter.PersistentStore)
+		applyLog, err := applyRule(r).result()
+		fmt.Print(applyLog)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Reapply error:", err)
+			code = 1
+		}
+		if saveErr := tracked.recordResult(r, err); saveErr != nil {
+			fmt.Fprintln(os.Stderr, "Could not save tracked rules:", saveErr)
+		}
+		if auditErr := audit.record("reapply", r.Process, r.ExePath, map[string]any{"mode": "logon"}, err); auditErr != nil {
+			fmt.Fprintln(os.Stderr, "Audit log error:", auditErr)
+		}
+	}
+	return code
+}
+
+// Recreate saved rules from the GUI, e.g. after a reboot wiped the ActiveStore
+// policies; see reapplicableRules for which
+func reapplySavedRules(log func(string)) {
+	rules := reapplicableRules(tracked.list())
+	if len(rules) == 0 {
+		log("No saved rules to reapply")
+		return
+	}
+
+	failed := 0
+	for _, r := range rules {
+		applyLog, err := applyRule(r).result()
+		log("Reapplying " + r.describe())
+		log(applyLog)
+		if err != nil {
+			log("Reapply error: " + err.Error())
+			failed++
+		}
+		if saveErr := tracked.recordResult(r, err); saveErr != nil {
+			log("Could not save tracked rules: " + saveErr.Error())
+		}
+		auditOrLog(log, "reapply", r.Process, r.ExePath, map[string]any{"trigger": "manual"}, err)
+	}
+	log(fmt.Sprintf("Reapplied %d of %d saved rule(s)", len(rules)-failed, len(rules)))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReapplicableRules(t *testing.T) {
+	rules := []LimitRule{
+		{Process: "legacy.exe"}, // saved before statuses existed, so active
+		{Process: "active.exe", Status: statusActive},
+		{Process: "failed.exe", Status: statusFailed},
+		{Process: "paused.exe", Status: statusPaused},
+		{Process: "pending.exe", Status: statusPending, MeteredOnly: true},
+		{Process: "boosted.exe", Status: statusBoosted, BoostUntil: time.Now().Add(time.Hour)},
+	}
+	var got []string
+	for _, r := range reapplicableRules(rules) {
+		got = append(got, r.Process)
+	}
+	if want := []string{"legacy.exe", "active.exe", "failed.exe"}; !slices.Equal(got, want) {
+		t.Errorf("reapplicable rules %q, want %q", got, want)
+	}
+}
+
+// -reapply clears only the keys it reapplies: keyRecordingLimiter has no
+// ClearAll, so clearing everything (and allowlist mode with it) would panic
+func TestReapplyHeadlessClearsOnlyItsKeys(t *testing.T) {
+	l, _ := recordingTestEnv(t)
+	rules := []LimitRule{
+		{Process: "chrome.exe", ExePath: `C:\Chrome\chrome.exe`, OutKbps: 500},
+		{Process: "steam.exe", ExePath: `C:\Steam\steam.exe`, ExtraPaths: []string{`D:\Steam\steam.exe`}, OutKbps: 800},
+	}
+	if code := reapplyHeadless(rules); code != 0 {
+		t.Errorf("reapplyHeadless = %d, want 0", code)
+	}
+	var want []string
+	for _, r := range rules {
+		for _, target := range r.exeTargets() {
+			want = append(want, target.Key)
+		}
+	}
+	if !slices.Equal(l.cleared, want) || !slices.Equal(l.applied, want) {
+		t.Errorf("cleared %q and applied %q, want %q for both", l.cleared, l.applied, want)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// How often the status bar re-reads the live QoS policies and firewall rules
+const liveStatusInterval = 5 * time.Second
+
+// What a live policy or rule targets, for the status bar
+func liveTarget(p livePolicy) string {
+	switch {
+	case p.AppPath != "" && p.AppPath != "Any":
+		return filepath.Base(strings.ReplaceAll(p.AppPath, `\`, "/"))
+	case p.Package != "" && p.Package != "Any":
+		return "package " + p.Package
+	}
+	return p.Name
+}
+
+// One-line summary of what is in force, e.g. "Active: throttled 1000 kbps on chrome.exe"
+func liveStatusText(live []livePolicy) string {
+	var parts []string
+	blocked := make(map[string]bool)
+	for _, p := range live {
+		target := liveTarget(p)
+		switch {
+		case p.Kind == "qos" && p.BitsPerSecond > 0:
+			parts = append(parts, fmt.Sprintf("throttled %d kbps on %s", p.BitsPerSecond/1000, target))
+		case p.Kind == "qos":
+			parts = append(parts, "priority on "+target)
+		case !blocked[strings.ToLower(target)]:
+			// The IN and OUT rules of one block count once
+			blocked[strings.ToLower(target)] = true
+			parts = append(parts, "blocked "+target)
+		}
+	}
+	if len(parts) == 0 {
+		return "No limits active"
+	}
+	return "Active: " + strings.Join(parts, "; ")
+}
+
+// Status bar showing the limits actually in place, re-read every
+// liveStatusInterval and whenever the returned function is called
+func liveStatusBar() (fyne.CanvasObject, func()) {
+	label := widget.NewLabel("Checking live limits...")
+	label.Truncation = fyne.TextTruncateEllipsis
+	refresh := make(chan struct{}, 1)
+
+	go func() {
+		ticker := time.NewTicker(liveStatusInterval)
+		defer ticker.Stop()
+		for {
+			live, err := backend.LiveRules()
+			text := liveStatusText(live)
+			if err != nil {
+				text = "Live state unavailable: " + err.Error()
+			}
+			fyne.Do(func() { label.SetText(text) })
+
+			select {
+			case <-ticker.C:
+			case <-refresh:
+			}
+		}
+	}()
+
+	return label, func() {
+		select {
+		case refresh <- struct{}{}:
+		default:
+		}
+	}
+}
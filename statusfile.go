@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+const (
+	statusFilePrefKey     = "statusFilePath"
+	statusIntervalPrefKey = "statusFileIntervalSec"
+
+	defaultStatusInterval = 10 * time.Second
+	minStatusInterval     = time.Second
+)
+
+// Summary of the active rules written for external read-only consumers
+type statusSnapshot struct {
+	UpdatedAt time.Time         `json:"updatedAt"`
+	Backend   string            `json:"backend"`
+	Rules     []ruleStatusEntry `json:"rules"`
+}
+
+// One tracked rule in the status file
+type ruleStatusEntry struct {
+	Target    string     `json:"target"`
+	Process   string     `json:"process"`
+	ExePath   string     `json:"exePath,omitempty"`
+	Package   string     `json:"package,omitempty"`
+	InKbps    int        `json:"inKbps"`
+	OutKbps   int        `json:"outKbps"`
+	Blocked   bool       `json:"blocked"`
+	Status    ruleStatus `json:"status"`
+	AppliedAt time.Time  `json:"appliedAt"`
+	UsedBytes uint64     `json:"usedBytes"` // I/O since the rule was applied
+}
+
+// Build the status summary from the tracked rules and usage counters
+func buildStatusSnapshot(rules []LimitRule) statusSnapshot {
+	snap := statusSnapshot{UpdatedAt: time.Now().UTC(), Backend: backend.Name(), Rules: []ruleStatusEntry{}}
+	for _, r := range rules {
+		used, _ := ruleUsage.used(r)
+		snap.Rules = append(snap.Rules, ruleStatusEntry{
+			Target: r.key(), Process: r.Process, ExePath: r.ExePath, Package: r.Package,
+			InKbps: r.InKbps, OutKbps: r.OutKbps, Blocked: r.Blocked,
+			Status: r.status(), AppliedAt: r.AppliedAt, UsedBytes: used,
+		})
+	}
+	return snap
+}
+
+// Write data to path through a temp file in the same directory and a rename,
+// so readers never see a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Update interval from the settings, never below minStatusInterval
+func statusInterval(prefs fyne.Preferences) time.Duration {
+	d := time.Duration(prefs.IntWithFallback(statusIntervalPrefKey, int(defaultStatusInterval/time.Second))) * time.Second
+	return max(d, minStatusInterval)
+}
+
+// Periodically write the status file while a path is configured.
+// The same error is only logged once in a row.
+func watchStatusFile(prefs fyne.Preferences, log func(string)) {
+	lastErr := ""
+	for {
+		time.Sleep(statusInterval(prefs))
+		path := prefs.String(statusFilePrefKey)
+		if path == "" {
+			continue
+		}
+
+		data, err := json.MarshalIndent(buildStatusSnapshot(tracked.list()), "", "  ")
+		if err == nil {
+			err = writeFileAtomic(path, data)
+		}
+		if err == nil {
+			lastErr = ""
+			continue
+		}
+		if err.Error() != lastErr {
+			log("Status file error: " + err.Error())
+		}
+		lastErr = err.Error()
+	}
+}
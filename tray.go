@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// Hide the window on close instead of quitting, leaving the tray menu
+const closeToTrayPrefKey = "closeToTray"
+
+// Make closing the window hide it while the close-to-tray setting is on.
+// Without a system tray there would be no way back, so the window closes.
+func closeToTray(window fyne.Window, application fyne.App) {
+	if _, ok := application.(desktop.App); !ok {
+		return
+	}
+	window.SetCloseIntercept(func() {
+		if application.Preferences().Bool(closeToTrayPrefKey) {
+			window.Hide()
+			return
+		}
+		window.Close()
+	})
+}
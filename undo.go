@@ -0,0 +1,105 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// Operations kept for undo; older ones are dropped
+const undoDepth = 20
+
+// Tracked rules before and after one Apply/Block/Clear
+type historyEntry struct {
+	Label         string
+	Before, After []LimitRule
+}
+
+// Undo and redo stacks of rule states. Kept in memory only, so nothing
+// outlives the session.
+type opHistory struct {
+	mu         sync.Mutex
+	undo, redo []historyEntry
+}
+
+var history = &opHistory{}
+
+// Whether two rule lists put the same state in place, whatever their order
+func sameRuleSet(a, b []LimitRule) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	byKey := make(map[string]LimitRule, len(a))
+	for _, r := range a {
+		byKey[r.key()] = r
+	}
+	for _, r := range b {
+		old, ok := byKey[r.key()]
+		if !ok || !sameRuleSettings(old, r) || old.status() != r.status() {
+			return false
+		}
+	}
+	return true
+}
+
+// Record an operation that started from before and left the tracked rules
+// as they are now; operations that changed nothing aren't recorded. Use as
+// defer history.record(label, tracked.list()) at the start of the operation.
+func (h *opHistory) record(label string, before []LimitRule) {
+	after := tracked.list()
+	if sameRuleSet(before, after) {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.undo = append(h.undo, historyEntry{Label: label, Before: before, After: after})
+	if len(h.undo) > undoDepth {
+		h.undo = h.undo[len(h.undo)-undoDepth:]
+	}
+	h.redo = nil
+}
+
+// Take the latest entry off from and put it on to
+func (h *opHistory) move(from, to *[]historyEntry) (historyEntry, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(*from) == 0 {
+		return historyEntry{}, false
+	}
+	e := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, e)
+	return e, true
+}
+
+// Go back to the rules before the latest operation: rules it added are
+// cleared and rules it changed or cleared are applied again
+func (h *opHistory) undoLast(log func(string)) {
+	e, ok := h.move(&h.undo, &h.redo)
+	if !ok {
+		log("Nothing to undo")
+		return
+	}
+	log("Undoing: " + e.Label)
+	restoreRules(e.Before, "undo", log)
+}
+
+// Apply the latest undone operation again
+func (h *opHistory) redoLast(log func(string)) {
+	e, ok := h.move(&h.redo, &h.undo)
+	if !ok {
+		log("Nothing to redo")
+		return
+	}
+	log("Redoing: " + e.Label)
+	restoreRules(e.After, "redo", log)
+}
+
+// Bring the live rules to target, changing only what differs
+func restoreRules(target []LimitRule, trigger string, log func(string)) {
+	d := diffRules(tracked.list(), target)
+	log(strings.TrimSuffix(d.format(), "\n"))
+	if d.empty() {
+		return
+	}
+	applyRuleDiff(d, map[string]any{"trigger": trigger}, log)
+}
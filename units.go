@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"netlimiter/netlimiter"
+)
+
+// Unit a limit is entered in; rules and presets always store kbps
+type rateUnit string
+
+const (
+	unitKbps rateUnit = "Kbps"
+	unitMbps rateUnit = "Mbps"
+)
+
+var rateUnits = []string{string(unitKbps), string(unitMbps)}
+
+// kbps in one of unit. Network rates are decimal (1 kbps = 1000 bits per
+// second, 1 Mbps = 1000 kbps), as in convertToBitsPerSecond.
+func (u rateUnit) kbps() int {
+	if u == unitMbps {
+		return 1000
+	}
+	return 1
+}
+
+// Bits per second of value in unit, for ThrottleRateActionBitsPerSecond,
+// through netlimiter.KbpsToBitsPerSecond. 0 or less (no limit, or a block
+// when IN and OUT both are) converts to 0; values whose kbps don't fit in
+// an int saturate rather than wrap around to a negative rate.
+func convertToBitsPerSecond(value int, unit rateUnit) int64 {
+	if value > math.MaxInt/unit.kbps() {
+		return math.MaxInt64
+	}
+	return netlimiter.KbpsToBitsPerSecond(value * unit.kbps())
+}
+
+// Parse a limit field in unit into kbps; an empty field is 0
+func parseLimit(text string, unit rateUnit) (int, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(text)
+	// Check the range before converting so a huge value can't overflow on the way
+	if errors.Is(err, strconv.ErrRange) || (err == nil && v > netlimiter.MaxLimitKbps/unit.kbps()) {
+		return 0, fmt.Errorf("limit of %s %s is above the %d kbps (100 Gbps) maximum", text, unit, netlimiter.MaxLimitKbps)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a whole number", text)
+	}
+	kbps := v * unit.kbps()
+	if err := netlimiter.ValidateLimitKbps(kbps); err != nil {
+		return 0, err
+	}
+	return kbps, nil
+}
+
+// Parse a percentage limit such as "50%"; ok is false when text isn't one
+func parsePercent(text string) (pct int, ok bool, err error) {
+	num, isPercent := strings.CutSuffix(strings.TrimSpace(text), "%")
+	if !isPercent {
+		return 0, false, nil
+	}
+	pct, err = strconv.Atoi(strings.TrimSpace(num))
+	if err != nil || pct < 1 || pct > 100 {
+		return 0, true, fmt.Errorf("percentage must be a whole number from 1 to 100, got %q", text)
+	}
+	return pct, true, nil
+}
+
+// pct percent of capacityKbps, never rounded down to 0
+func percentOfKbps(pct, capacityKbps int) int {
+	return max(int(int64(capacityKbps)*int64(pct)/100), 1)
+}
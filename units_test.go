@@ -0,0 +1,86 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"netlimiter/netlimiter"
+)
+
+func TestConvertToBitsPerSecond(t *testing.T) {
+	type test struct {
+		value int64 // int64 so the 64-bit cases below compile on 32-bit targets
+		unit  rateUnit
+		want  int64
+	}
+	tests := []test{
+		{0, unitKbps, 0}, // no limit, or a block when IN and OUT both are 0
+		{0, unitMbps, 0},
+		{-1, unitKbps, 0},
+		{-1, unitMbps, 0},
+		{1, unitKbps, 1_000},
+		{1, unitMbps, 1_000_000},
+		{500, unitKbps, 500_000},
+		{25, unitMbps, 25_000_000},
+		{100_000, unitMbps, 100_000_000_000},
+		{math.MaxInt32, unitKbps, math.MaxInt32 * 1000},
+		{math.MaxInt32 / 1000, unitMbps, math.MaxInt32 / 1000 * 1_000_000},
+		{math.MaxInt/1000 + 1, unitMbps, math.MaxInt64}, // kbps would overflow int
+		{math.MaxInt, unitMbps, math.MaxInt64},
+	}
+	if strconv.IntSize == 64 {
+		tests = append(tests,
+			test{math.MaxInt64 / 1000, unitKbps, math.MaxInt64 / 1000 * 1000},
+			test{math.MaxInt64/1000 + 1, unitKbps, math.MaxInt64},
+		)
+	}
+	for _, tt := range tests {
+		if got := convertToBitsPerSecond(int(tt.value), tt.unit); got != tt.want {
+			t.Errorf("convertToBitsPerSecond(%d, %s) = %d, want %d", tt.value, tt.unit, got, tt.want)
+		}
+	}
+}
+
+func TestConvertToBitsPerSecondMatchesParsedKbps(t *testing.T) {
+	// A limit entered in Mbps and the kbps parseLimit stores for it throttle alike
+	kbps, err := parseLimit("12", unitMbps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := convertToBitsPerSecond(kbps, unitKbps), convertToBitsPerSecond(12, unitMbps); got != want {
+		t.Errorf("12 Mbps stored as %d kbps converts to %d bits per second, want %d", kbps, got, want)
+	}
+}
+
+func TestParseLimitBounds(t *testing.T) {
+	tests := []struct {
+		text string
+		unit rateUnit
+		want int
+		ok   bool
+	}{
+		{"", unitKbps, 0, true},
+		{"0", unitKbps, 0, true},
+		{"0", unitMbps, 0, true},
+		{"-1", unitKbps, 0, false},
+		{"-1", unitMbps, 0, false},
+		{" 250 ", unitKbps, 250, true},
+		{"100000000", unitKbps, netlimiter.MaxLimitKbps, true},
+		{"100000001", unitKbps, 0, false},
+		{"100000", unitMbps, netlimiter.MaxLimitKbps, true},
+		{"100001", unitMbps, 0, false},
+		// Would overflow int when scaled to kbps; rejected before converting
+		{"9223372036854775", unitMbps, 0, false},
+		{"9223372036854775807", unitKbps, 0, false},
+		{"9223372036854775808", unitMbps, 0, false},
+		{"1.5", unitMbps, 0, false},
+		{"fast", unitKbps, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseLimit(tt.text, tt.unit)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseLimit(%q, %s) = %d, %v; want %d, ok %v", tt.text, tt.unit, got, err, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// How often per-process I/O counters are sampled for the rules table
+const usageSampleInterval = 5 * time.Second
+
+// Bytes transferred under one rule since it was applied
+type usageCounter struct {
+	appliedAt time.Time
+	bytes     uint64
+	last      map[int32]uint64 // last I/O total seen per PID
+}
+
+// Running byte totals per tracked rule, kept in memory only.
+// Windows reports all I/O per process (not only network), so this is an upper bound.
+type usageTracker struct {
+	mu       sync.Mutex
+	counters map[string]*usageCounter
+}
+
+var ruleUsage = &usageTracker{counters: make(map[string]*usageCounter)}
+
+// Add the I/O since the previous sample to every active rule.
+// A PID's first sample is only a baseline, and reapplying a rule resets its total.
+func (u *usageTracker) sample(rules []LimitRule, src ProcessSource) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, r := range rules {
+		if r.Package != "" || r.status() != statusActive {
+			continue
+		}
+		key := r.key()
+		seen[key] = true
+		c := u.counters[key]
+		if c == nil || !c.appliedAt.Equal(r.AppliedAt) {
+			c = &usageCounter{appliedAt: r.AppliedAt, last: make(map[int32]uint64)}
+			u.counters[key] = c
+		}
+
+		pids, err := src.FindPIDsByName(r.Process)
+		if err != nil {
+			continue
+		}
+		cur := make(map[int32]uint64, len(pids))
+		for _, pid := range pids {
+			total, err := src.IOBytes(pid)
+			if err != nil {
+				continue
+			}
+			cur[pid] = total
+			if prev, ok := c.last[pid]; ok && total >= prev {
+				c.bytes += total - prev
+			}
+		}
+		c.last = cur
+	}
+
+	for key := range u.counters {
+		if !seen[key] {
+			delete(u.counters, key)
+		}
+	}
+}
+
+// Bytes used under r since it was applied; false when nothing was measured yet
+func (u *usageTracker) used(r LimitRule) (uint64, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	c := u.counters[r.key()]
+	if c == nil || !c.appliedAt.Equal(r.AppliedAt) {
+		return 0, false
+	}
+	return c.bytes, true
+}
+
+// Sample usage forever, calling onUpdate after each pass
+func watchUsage(onUpdate func()) {
+	ticker := time.NewTicker(usageSampleInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ruleUsage.sample(tracked.list(), processes)
+		onUpdate()
+	}
+}
+
+// Byte count in human units, e.g. "1.3 GB"
+func formatBytes(n uint64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
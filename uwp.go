@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"netlimiter/netlimiter"
+)
+
+// Registry key mapping AppContainer SIDs to package family names (Moniker)
+const appContainerMappingsKey = `HKCU:\Software\Classes\Local Settings\Software\Microsoft\Windows\CurrentVersion\AppContainer\Mappings`
+
+// Package family names look like "Microsoft.WindowsCalculator_8wekyb3d8bbwe"
+var packageFamilyNamePattern = regexp.MustCompile(`^[A-Za-z0-9.\-]+_[A-Za-z0-9]+$`)
+
+// Installed UWP/Store package as reported by Get-AppxPackage
+type appxPackage struct {
+	Name              string
+	PackageFamilyName string
+	InstallLocation   string
+}
+
+// Check that a package family name is well-formed before it reaches a script
+func validatePackageFamilyName(pfn string) error {
+	if !packageFamilyNamePattern.MatchString(pfn) {
+		return fmt.Errorf("invalid package family name: %q", pfn)
+	}
+	return nil
+}
+
+// Package family name of an executable installed under WindowsApps, e.g.
+// ...\WindowsApps\Microsoft.ZuneMusic_11.2.40.0_x64__8wekyb3d8bbwe\Music.UI.exe
+// is Microsoft.ZuneMusic_8wekyb3d8bbwe. The folder is the package full name
+// (name_version_arch_resource_publisher); false for ordinary programs.
+func packageFamilyFromPath(exePath string) (string, bool) {
+	parts := strings.FieldsFunc(exePath, func(r rune) bool { return r == '\\' || r == '/' })
+	for i := 0; i+1 < len(parts); i++ {
+		if !strings.EqualFold(parts[i], "WindowsApps") {
+			continue
+		}
+		fields := strings.Split(parts[i+1], "_")
+		if len(fields) != 5 {
+			return "", false
+		}
+		pfn := fields[0] + "_" + fields[4]
+		return pfn, validatePackageFamilyName(pfn) == nil
+	}
+	return "", false
+}
+
+// List installed UWP/Store packages for the current user, sorted by name
+func listAppxPackages() ([]appxPackage, error) {
+	script := `
+$pkgs = @(Get-AppxPackage -ErrorAction SilentlyContinue | Select-Object Name, PackageFamilyName, InstallLocation)
+ConvertTo-Json -InputObject $pkgs -Compress
+`
+	var pkgs []appxPackage
+	if err := queryPowerShellJSON(script, &pkgs); err != nil {
+		return nil, fmt.Errorf("Get-AppxPackage error: %w", err)
+	}
+
+	// Several package versions can share a family name; keep one entry each
+	seen := make(map[string]bool)
+	unique := pkgs[:0]
+	for _, p := range pkgs {
+		if p.PackageFamilyName == "" || seen[p.PackageFamilyName] {
+			continue
+		}
+		seen[p.PackageFamilyName] = true
+		unique = append(unique, p)
+	}
+
+	sort.Slice(unique, func(i, j int) bool {
+		return strings.ToLower(unique[i].Name) < strings.ToLower(unique[j].Name)
+	})
+	return unique, nil
+}
+
+// Resolve the executables declared in a package's manifest to full paths
+func resolvePackageExecutables(pfn string) ([]string, error) {
+	if err := validatePackageFamilyName(pfn); err != nil {
+		return nil, err
+	}
+
+	script := fmt.Sprintf(`
+$pkg = Get-AppxPackage -ErrorAction SilentlyContinue | Where-Object { $_.PackageFamilyName -eq %s } | Select-Object -First 1
+if (-not $pkg) { ConvertTo-Json -InputObject @() -Compress; exit }
+$manifest = Get-AppxPackageManifest -Package $pkg.PackageFullName
+$exes = @($manifest.Package.Applications.Application | Where-Object { $_.Executable } | ForEach-Object { Join-Path $pkg.InstallLocation $_.Executable })
+ConvertTo-Json -InputObject $exes -Compress
+`,
+		netlimiter.QuotePowerShell(pfn),
+	)
+
+	var exes []string
+	if err := queryPowerShellJSON(script, &exes); err != nil {
+		return nil, fmt.Errorf("package manifest error: %w", err)
+	}
+	if len(exes) == 0 {
+		return nil, fmt.Errorf("package %s not installed or has no executables: %w", pfn, errProcessNotFound)
+	}
+	return exes, nil
+}
+
+// Block all internet (inbound + outbound) for a UWP package via its AppContainer SID
+func blockInternetForPackage(pfn string) *OpResult {
+	res := &OpResult{}
+	res.step("Blocking internet for package: %s", pfn)
+
+	if res.Err = validatePackageFamilyName(pfn); res.Err != nil {
+		return res
+	}
+	// netsh can't target an AppContainer, so there is no fallback here
+	if !firewallCmdletsAvailable() {
+		res.Err = fmt.Errorf("blocking a package needs the NetSecurity firewall cmdlets: %w", errCmdletMissing)
+		return res
+	}
+
+	res.Script = packageBlockScript(pfn)
+	if res.run("Firewall output", "firewall error").Err == nil {
+		res.succeed("BlockInternet (package): success")
+	}
+	return res
+}
+
+// Build the script that blocks a package by its AppContainer SID
+func packageBlockScript(pfn string) string {
+	key := packagePolicyKey(pfn)
+	return fmt.Sprintf(`
+$pfn = %s
+$sid = Get-ChildItem "%s" -ErrorAction SilentlyContinue |
+  Where-Object { (Get-ItemProperty $_.PSPath).Moniker -eq $pfn } |
+  Select-Object -First 1 -ExpandProperty PSChildName
+if (-not $sid) {
+  Write-Output "No AppContainer SID found for package $pfn"
+  exit 1
+}
+Write-Output "AppContainer SID: $sid"
+`,
+		netlimiter.QuotePowerShell(pfn),
+		appContainerMappingsKey,
+	) +
+		netlimiter.FirewallRuleScript(netlimiter.PolicyName(netlimiter.FirewallRuleOut, key), "-Package $sid", "Outbound") +
+		netlimiter.FirewallRuleScript(netlimiter.PolicyName(netlimiter.FirewallRuleIn, key), "-Package $sid", "Inbound")
+}
+
+// Policy key for a package, matching its rule key
+func packagePolicyKey(pfn string) string {
+	return "package:" + strings.ToLower(pfn)
+}
+
+// Apply QoS throttling for a UWP package.
+// QoS has no package condition, so the policy matches the package's main executable.
+func applyLimitForPackage(pfn string, inKbps, outKbps int) *OpResult {
+	res := &OpResult{}
+	res.step("Applying speed limit for package: %s", pfn)
+
+	exes, err := resolvePackageExecutables(pfn)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	if len(exes) > 1 {
+		res.step("Package declares %d executables, limiting the first: %s", len(exes), exes[0])
+	}
+
+	limited := applyQosLimit(packagePolicyKey(pfn), exes[0], inKbps, outKbps)
+	res.merge(limited).Err = limited.Err
+	return res
+}
+
+// Show a searchable list of installed packages; the chosen one is passed to onSelect
+func showPackagePicker(window fyne.Window, onSelect func(appxPackage)) {
+	var all, filtered []appxPackage
+
+	list := widget.NewList(
+		func() int { return len(filtered) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			p := filtered[id]
+			obj.(*widget.Label).SetText(p.Name + "  (" + p.PackageFamilyName + ")")
+		},
+	)
+
+	search := widget.NewEntry()
+	search.SetPlaceHolder("Filter packages...")
+	search.OnChanged = func(text string) {
+		text = strings.ToLower(strings.TrimSpace(text))
+		filtered = filtered[:0]
+		for _, p := range all {
+			if text == "" || strings.Contains(strings.ToLower(p.PackageFamilyName), text) {
+				filtered = append(filtered, p)
+			}
+		}
+		list.Refresh()
+	}
+
+	status := widget.NewLabel("Loading installed packages...")
+	content := container.NewBorder(container.NewVBox(search, status), nil, nil, nil, list)
+	d := dialog.NewCustom("Select UWP Package", "Cancel", content, window)
+	d.Resize(fyne.NewSize(520, 420))
+
+	list.OnSelected = func(id widget.ListItemID) {
+		onSelect(filtered[id])
+		d.Hide()
+	}
+
+	d.Show()
+
+	// Get-AppxPackage takes a moment, load in the background
+	go func() {
+		pkgs, err := listAppxPackages()
+		fyne.Do(func() {
+			if err != nil {
+				status.SetText("Error: " + err.Error())
+				return
+			}
+			all = pkgs
+			filtered = append([]appxPackage(nil), pkgs...)
+			status.SetText(fmt.Sprintf("%d packages", len(pkgs)))
+			list.Refresh()
+		})
+	}()
+}
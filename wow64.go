@@ -0,0 +1,76 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Directory pairs that WOW64 redirection maps onto each other on 64-bit Windows.
+// A 32-bit process runs from the second directory even when started via the first.
+var redirectedDirs = [][2]string{
+	{`\Windows\System32\`, `\Windows\SysWOW64\`},
+	{`\Program Files\`, `\Program Files (x86)\`},
+}
+
+// Sysnative is an alias only 32-bit processes can see; images never run from it
+var sysnativePattern = regexp.MustCompile(`(?i)\\Windows\\Sysnative\\`)
+
+// Strip aliases that never appear as a running image's path: the \\?\ prefix
+// and Sysnative. QoS compares against the real image path, so an alias never matches.
+func normalizeRedirectedPath(path string) string {
+	path = strings.TrimPrefix(path, `\\?\`)
+	path = sysnativePattern.ReplaceAllString(path, `\Windows\System32\`)
+	return filepath.Clean(path)
+}
+
+// Path's counterpart on the other side of WOW64 redirection, if it has one
+func redirectedTwin(path string) (string, bool) {
+	lower := strings.ToLower(path)
+	for _, pair := range redirectedDirs {
+		for i, dir := range pair {
+			if j := strings.Index(lower, strings.ToLower(dir)); j >= 0 {
+				other := pair[1-i]
+				return path[:j] + other + path[j+len(dir):], true
+			}
+		}
+	}
+	return "", false
+}
+
+// Canonical path to key a rule on, plus a log note when it differs from path.
+// If the process runs only from the redirected twin (e.g. a 32-bit copy in
+// SysWOW64), the twin is used, since a rule on the other path would be silently ineffective.
+func canonicalExePath(src ProcessSource, path string) (string, string) {
+	normalized := normalizeRedirectedPath(path)
+	note := ""
+	if !strings.EqualFold(normalized, filepath.Clean(path)) {
+		note = "Normalized redirected path: " + path + " -> " + normalized
+	}
+
+	twin, ok := redirectedTwin(normalized)
+	if !ok {
+		return normalized, note
+	}
+	pids, err := src.FindPIDsByName(filepath.Base(normalized))
+	if err != nil {
+		return normalized, note
+	}
+	runsAsTwin := false
+	for _, pid := range pids {
+		exe, err := src.ExePath(pid)
+		if err != nil {
+			continue
+		}
+		if strings.EqualFold(exe, normalized) {
+			return normalized, note
+		}
+		if strings.EqualFold(exe, twin) {
+			runsAsTwin = true
+		}
+	}
+	if runsAsTwin {
+		return twin, "Running process uses the redirected path: " + normalized + " -> " + twin
+	}
+	return normalized, note
+}